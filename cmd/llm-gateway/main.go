@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+	oauth2 "golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/Billy-Davies-2/llm-test/config"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/cluster"
+	"github.com/Billy-Davies-2/llm-test/pkg/gateway"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load config:", err)
+		os.Exit(1)
+	}
+
+	addr := flag.String("addr", cfg.GatewayAddr, "HTTP listen address")
+	backendAddr := flag.String("backend-addr", cfg.ChatGRPCAddr, "Chat gRPC server to forward to, or llmcluster:///seed:port to spread requests over the cluster")
+	requireAuth := flag.Bool("auth", true, "Require a bearer token from the OIDC issuer")
+	flag.Parse()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  "llm-gateway",
+		OTLPEndpoint: cfg.OTLPEndpoint,
+		File:         cfg.TraceFile,
+	})
+	if err != nil {
+		logger.Warn("tracing disabled", "err", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	var verifier *auth.Verifier
+	if *requireAuth {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+		provider, err := oidc.NewProvider(ctx, cfg.OIDCIssuerURL)
+		cancel()
+		if err != nil {
+			logger.Error("OIDC discovery failed", "issuer", cfg.OIDCIssuerURL, "err", err)
+			fmt.Fprintln(os.Stderr, "OIDC discovery failed (use --auth=false for local testing):", err)
+			os.Exit(1)
+		}
+		verifier = auth.NewVerifier(provider, cfg.OIDCClientID)
+	}
+
+	dialOpts := append(tracing.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// requests carry their caller's token, but an llmcluster target also
+	// asks its seeds for the members in the background; those lookups use
+	// the token cached by "tui-client login"
+	seedOpts := dialOpts
+	tokens, err := seedTokens(cfg)
+	switch {
+	case err == nil:
+		seedOpts = append(slices.Clone(dialOpts), grpc.WithPerRPCCredentials(auth.TokenSourceCredentials(tokens)))
+	case strings.HasPrefix(*backendAddr, cluster.Scheme+":"):
+		logger.Warn("cluster lookups will be sent without a token", "err", err)
+	}
+	dialOpts = append(dialOpts, grpc.WithResolvers(cluster.NewBuilder(cluster.WithSeedDialOptions(seedOpts...), cluster.WithLogger(logger))))
+	conn, err := grpc.NewClient(*backendAddr, dialOpts...)
+	if err != nil {
+		logger.Error("failed to create grpc client", "addr", *backendAddr, "err", err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           gateway.New(conn, verifier, logger).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	logger.Info("starting OpenAI gateway", "addr", *addr, "backend", *backendAddr, "auth", *requireAuth)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("gateway stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+// seedTokens serves the token cached for cfg's issuer
+func seedTokens(cfg *config.Config) (oauth2.TokenSource, error) {
+	store, err := auth.OpenTokenStore(cfg.TokenFile, cfg.TokenPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	return auth.TokenSource(context.Background(), auth.OIDCConfig{IssuerURL: cfg.OIDCIssuerURL, ClientID: cfg.OIDCClientID}, store)
+}
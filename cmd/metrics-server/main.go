@@ -2,8 +2,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	oidc "github.com/coreos/go-oidc"
+
+	"github.com/Billy-Davies-2/llm-test/config"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	"github.com/Billy-Davies-2/llm-test/pkg/models"
+	"github.com/Billy-Davies-2/llm-test/pkg/routing"
+	"github.com/Billy-Davies-2/llm-test/pkg/scheduler"
+	"github.com/Billy-Davies-2/llm-test/pkg/server"
+	"github.com/Billy-Davies-2/llm-test/pkg/shard"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
 )
 
 func initLogger() *slog.Logger {
@@ -23,8 +46,221 @@ func initLogger() *slog.Logger {
 	return slog.New(handler)
 }
 
+// newBackend builds the inference backend selected by name. Given a
+// llama-server binary but no fixed model, it launches models from the
+// registry on demand instead. The pipeline backend runs a toy model split
+// across the cluster, or on this host alone without one; only it serves
+// other hosts' pipeline stages.
+func newBackend(name, hostID string, cfg *config.Config, registry *models.Registry, cluster *gossip.Node, logger *slog.Logger) ([]server.Option, func() error, error) {
+	switch name {
+	case "echo":
+		return []server.Option{server.WithBackend(backend.NewEcho())}, func() error { return nil }, nil
+	case "pipeline":
+		spec := shard.ToySpec("toy", cfg.PipelineLayers)
+		plan := shard.LocalPlanner(hostID, spec)
+		var peers []shard.ServiceOption
+		if cluster != nil {
+			plan = shard.ClusterPlanner(cluster, spec, float64(cfg.PipelineLayerMB))
+			peers = append(peers, shard.WithPeers(shard.GossipPeers(cluster)))
+		}
+		shards := shard.NewService(hostID, spec, logger, peers...)
+		return []server.Option{server.WithBackend(shard.NewPipeline(shards, plan)), server.WithShards(shards)}, shards.Close, nil
+	case "llamacpp":
+		if cfg.LlamaServerBin != "" && cfg.LlamaModel == "" {
+			pool := backend.NewPool(cfg.MaxLoadedModels, backend.LlamaCPPOpener(cfg.LlamaServerBin, nil, func(model string) (string, error) {
+				m, err := registry.Get(model)
+				return m.Path, err
+			}))
+			return []server.Option{server.WithModelPool(pool, cfg.DefaultModel)}, pool.Close, nil
+		}
+		l := backend.NewLlamaCPP(backend.LlamaCPPConfig{
+			URL:       cfg.LlamaServerURL,
+			Binary:    cfg.LlamaServerBin,
+			ModelPath: cfg.LlamaModel,
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := l.Start(ctx); err != nil {
+			l.Close()
+			return nil, nil, err
+		}
+		return []server.Option{server.WithBackend(l)}, l.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown backend %q", name)
+}
+
+// splitList returns the non-empty items of a comma-separated list
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// joinCluster starts gossip membership for this host. The cluster is an
+// optimisation, so failing to join is logged rather than fatal.
+func joinCluster(cfg *config.Config, hostID string, logger *slog.Logger) *gossip.Node {
+	seeds := splitList(cfg.GossipSeeds)
+	node, err := gossip.Start(gossip.Config{
+		Name:          hostID,
+		BindAddr:      cfg.GossipBindAddr,
+		AdvertiseAddr: cfg.GossipAdvertiseAddr,
+		Seeds:         seeds,
+		Logger:        logger,
+		OnChange: func(m gossip.Member) {
+			logger.Info("cluster membership changed", "member", m.Name, "addr", m.Addr, "state", m.State)
+		},
+	})
+	if err != nil {
+		logger.Warn("gossip disabled", "bind", cfg.GossipBindAddr, "err", err)
+		return nil
+	}
+	return node
+}
+
+// setupTracing starts exporting spans where cfg says. Tracing is a
+// diagnostic aid, so failing to set it up is logged rather than fatal.
+func setupTracing(cfg *config.Config, logger *slog.Logger) func() {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  "metrics-server",
+		OTLPEndpoint: cfg.OTLPEndpoint,
+		File:         cfg.TraceFile,
+	})
+	if err != nil {
+		logger.Warn("tracing disabled", "err", err)
+		return func() {}
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Warn("flushing spans failed", "err", err)
+		}
+	}
+}
+
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load config:", err)
+		os.Exit(1)
+	}
+
+	hostname, _ := os.Hostname()
+	hostID := flag.String("host-id", hostname, "Unique host identifier")
+	port := flag.Int("port", 50051, "The server port")
+	backendName := flag.String("backend", cfg.Backend, "Inference backend (echo, llamacpp, pipeline)")
+	promAddr := flag.String("prometheus-addr", cfg.PrometheusAddr, "Address to serve Prometheus metrics on at /metrics; empty to disable")
+	routingPolicy := flag.String("routing", cfg.RoutingPolicy, "Chat routing policy (local, least-loaded, model-affinity, round-robin)")
+	requireAuth := flag.Bool("auth", true, "Require a bearer token from the OIDC issuer")
+	flag.Parse()
+
+	if *hostID == "" {
+		fmt.Fprintln(os.Stderr, "--host-id must be set to a non-empty value")
+		os.Exit(2)
+	}
+	policy, err := routing.ParsePolicy(*routingPolicy)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	logger := initLogger()
-	// pass logger into your server package, e.g.:
-	server.Run(logger, hostID, port)
+	stopTracing := setupTracing(cfg, logger)
+	defer stopTracing()
+	registry := models.NewRegistry(cfg.ModelDir, logger)
+	if err := registry.Scan(); err != nil {
+		logger.Warn("model directory scan failed", "dir", cfg.ModelDir, "err", err)
+	}
+	scanCtx, stopScan := context.WithCancel(context.Background())
+	defer stopScan()
+	go registry.Watch(scanCtx, cfg.ModelScan)
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		logger.Error("listen failed", "port", *port, "err", err)
+		os.Exit(1)
+	}
+	node := joinCluster(cfg, *hostID, logger)
+	opts, closeBackend, err := newBackend(*backendName, *hostID, cfg, registry, node, logger)
+	if err != nil {
+		logger.Error("backend setup failed", "backend", *backendName, "err", err)
+		fmt.Fprintln(os.Stderr, "backend setup failed:", err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+	if node != nil {
+		// chat, metrics and shards share this server's port, on the host
+		// peers already reach us at for gossip
+		host, _, _ := net.SplitHostPort(node.Addr())
+		addr := net.JoinHostPort(host, strconv.Itoa(lis.Addr().(*net.TCPAddr).Port))
+		opts = append(opts, server.WithCluster(node, addr, addr), server.WithRouting(policy))
+	}
+	if *requireAuth {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+		provider, err := oidc.NewProvider(ctx, cfg.OIDCIssuerURL)
+		cancel()
+		if err != nil {
+			logger.Error("OIDC discovery failed", "issuer", cfg.OIDCIssuerURL, "err", err)
+			fmt.Fprintln(os.Stderr, "OIDC discovery failed (use --auth=false for local testing):", err)
+			os.Exit(1)
+		}
+		opts = append(opts, server.WithAuth(provider, cfg.OIDCClientID), server.WithAdmins(splitList(cfg.AdminSubjects)...))
+	}
+	sessions, err := server.OpenSessionStore(cfg.SessionDir)
+	if err != nil {
+		logger.Error("session store setup failed", "dir", cfg.SessionDir, "err", err)
+		fmt.Fprintln(os.Stderr, "session store setup failed:", err)
+		os.Exit(1)
+	}
+	opts = append(opts,
+		server.WithSessions(sessions),
+		server.WithModelRegistry(registry),
+		server.WithScheduler(scheduler.New(cfg.MaxConcurrentGenerations, cfg.MaxQueueDepth)),
+		server.WithWatchInterval(cfg.PollInterval),
+		server.WithSampling(cfg.SampleInterval, cfg.MetricsHistory),
+	)
+	srv := server.NewServer(logger, *hostID, *port, opts...)
+	var prom *http.Server
+	if *promAddr != "" {
+		promLis, err := net.Listen("tcp", *promAddr)
+		if err != nil {
+			logger.Error("listen failed", "addr", *promAddr, "err", err)
+			fmt.Fprintln(os.Stderr, "prometheus listen failed:", err)
+			os.Exit(1)
+		}
+		prom = &http.Server{Handler: srv.MetricsHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := prom.Serve(promLis); !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("prometheus endpoint stopped", "err", err)
+			}
+		}()
+		logger.Info("serving prometheus metrics", "addr", promLis.Addr().String())
+	}
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		if prom != nil {
+			prom.Close()
+		}
+		if node != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			node.Leave(ctx)
+			cancel()
+			node.Shutdown()
+		}
+		srv.Stop()
+	}()
+
+	// the integration tests read the bound address from stdout
+	fmt.Printf("Listening on %s\n", lis.Addr())
+	logger.Info("starting metrics server", "addr", lis.Addr().String(), "host", *hostID)
+
+	if err := srv.Serve(lis); err != nil {
+		closeBackend()
+		os.Exit(1)
+	}
 }
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc"
 
+	"github.com/Billy-Davies-2/llm-test/config"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/client"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
 	"github.com/Billy-Davies-2/llm-test/pkg/tui"
 	"github.com/Billy-Davies-2/llm-test/pkg/tui/clipboard"
 )
@@ -29,9 +37,86 @@ func main() {
 	clipboard.Init()
 	slog.Info("Copied clipboard into in-memory clipboard")
 
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	// trace chat calls from here through the backends that answer them
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  "tui-client",
+		OTLPEndpoint: cfg.OTLPEndpoint,
+		File:         cfg.TraceFile,
+	})
+	if err != nil {
+		logger.Warn("tracing disabled", "error", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	oidcCfg := auth.OIDCConfig{IssuerURL: cfg.OIDCIssuerURL, ClientID: cfg.OIDCClientID}
+	store, err := auth.OpenTokenStore(cfg.TokenFile, cfg.TokenPassphrase)
+	if err != nil {
+		logger.Error("no token cache", "error", err)
+		os.Exit(1)
+	}
+
+	// "login" and "logout" manage the cached tokens instead of starting the TUI
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "login":
+			err = login(context.Background(), oidcCfg, store)
+		case "logout":
+			err = auth.Logout(context.Background(), oidcCfg, store)
+		default:
+			err = fmt.Errorf("unknown command %q; want login or logout", os.Args[1])
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// send the cached tokens with each call, refreshing them as they expire
+	var dialOpts []grpc.DialOption
+	tokens, err := auth.TokenSource(context.Background(), oidcCfg, store)
+	switch {
+	case err == nil:
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.TokenSourceCredentials(tokens)))
+	case errors.Is(err, auth.ErrNotLoggedIn):
+		logger.Info("not logged in, calling the backend without a token")
+	default:
+		logger.Warn("cached tokens unusable, calling the backend without a token", "error", err)
+	}
+
+	// connect to the chat backend; the connection is established lazily
+	m := tui.InitialModel().WithMetricsInterval(cfg.PollInterval).WithDialOptions(dialOpts...)
+	chat, err := client.NewClient(context.Background(), cfg.ChatGRPCAddr, logger, dialOpts...)
+	if err != nil {
+		logger.Warn("chat backend unavailable, using offline replies", "addr", cfg.ChatGRPCAddr, "error", err)
+	} else {
+		defer chat.Close()
+		m = m.WithChat(chat)
+	}
+
 	// run the TUI
-	if _, err := tea.NewProgram(tui.InitialModel(), tea.WithAltScreen(), tea.WithMouseAllMotion()).Run(); err != nil {
+	if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseAllMotion()).Run(); err != nil {
 		logger.Error("TUI exited with error", "error", err)
 		os.Exit(1)
 	}
 }
+
+// login signs the user in on another device and caches the tokens
+func login(ctx context.Context, cfg auth.OIDCConfig, store *auth.TokenStore) error {
+	res, err := auth.RunDeviceFlow(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(res.Token()); err != nil {
+		return fmt.Errorf("saving tokens: %w", err)
+	}
+	fmt.Println("Logged in.")
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	_ "github.com/joho/godotenv/autoload"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -10,16 +11,46 @@ import (
 type Config struct {
 	OIDCIssuerURL string // OIDC issuer URL
 	OIDCClientID  string // OIDC client ID
+	AdminSubjects string // comma-separated token subjects allowed to load and unload models
+
+	TokenFile       string // where the TUI caches its tokens; empty for the user config directory
+	TokenPassphrase string // encrypts the token cache; empty leaves it readable by the user only
 
 	ChatGRPCAddr    string // address for chat gRPC (e.g. ":50051")
 	MetricsGRPCAddr string // address for metrics gRPC (e.g. ":50052")
+	GatewayAddr     string // listen address for the OpenAI-compatible HTTP gateway
+	PrometheusAddr  string // listen address for a backend's Prometheus /metrics; empty to disable
+
+	GossipSeeds         string // comma-separated gossip seed addresses
+	GossipBindAddr      string // UDP address the gossip protocol listens on
+	GossipAdvertiseAddr string // address peers reach this host's gossip at; guessed if empty
+	RoutingPolicy       string // how chat requests are spread over the cluster
+
+	ModelDir        string // local model directory path
+	DefaultModel    string // model used when a request names none
+	MaxLoadedModels int    // models kept resident at once before evicting
+
+	Backend        string // inference backend: "echo" or "llamacpp"
+	LlamaServerURL string // llama-server base URL
+	LlamaServerBin string // llama-server binary to launch; empty to use a running server
+	LlamaModel     string // model file passed to a launched llama-server
+
+	PipelineLayers  int // layers of the toy model the pipeline backend runs
+	PipelineLayerMB int // memory each layer is planned as needing; 0 uses its real size
 
-	GossipSeeds string // comma-separated gossip seed addresses
+	SessionDir string // directory chat sessions are saved in; empty keeps them in memory
 
-	ModelDir string // local model directory path
+	MaxConcurrentGenerations int // generations run at once per host
+	MaxQueueDepth            int // requests allowed to wait for a generation slot
 
-	PollInterval time.Duration // poll interval for metrics
-	DialTimeout  time.Duration // timeout for gRPC dialing
+	OTLPEndpoint string // OTLP/gRPC collector URL spans are sent to; empty to send none
+	TraceFile    string // file spans are appended to as OTLP/JSON lines; empty to write none
+
+	PollInterval   time.Duration // how often metrics are streamed; servers send them no more often
+	SampleInterval time.Duration // how often a server measures its host
+	MetricsHistory time.Duration // how long a server keeps its samples
+	ModelScan      time.Duration // how often a server rescans ModelDir; 0 scans only at startup
+	DialTimeout    time.Duration // timeout for gRPC dialing
 }
 
 // Load reads configuration from environment, applying defaults where unset.
@@ -27,16 +58,46 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		OIDCIssuerURL: getEnv("OIDC_ISSUER_URL", "https://keycloak.example.com/auth/realms/llm"),
 		OIDCClientID:  getEnv("OIDC_CLIENT_ID", "llm-client"),
+		AdminSubjects: getEnv("ADMIN_SUBJECTS", ""),
+
+		TokenFile:       getEnv("TOKEN_FILE", ""),
+		TokenPassphrase: getEnv("TOKEN_PASSPHRASE", ""),
 
 		ChatGRPCAddr:    getEnv("CHAT_GRPC_ADDR", ":50051"),
 		MetricsGRPCAddr: getEnv("METRICS_GRPC_ADDR", ":50052"),
+		GatewayAddr:     getEnv("GATEWAY_ADDR", ":8000"),
+		PrometheusAddr:  getEnv("PROMETHEUS_ADDR", ""),
+
+		GossipSeeds:         getEnv("GOSSIP_SEEDS", "llm-backend-headless.llm.svc.cluster.local:7946"),
+		GossipBindAddr:      getEnv("GOSSIP_BIND_ADDR", ":7946"),
+		GossipAdvertiseAddr: getEnv("GOSSIP_ADVERTISE_ADDR", ""),
+		RoutingPolicy:       getEnv("ROUTING_POLICY", "model-affinity"),
+
+		ModelDir:        getEnv("MODEL_DIR", "/models"),
+		DefaultModel:    getEnv("DEFAULT_MODEL", ""),
+		MaxLoadedModels: getEnvInt("MAX_LOADED_MODELS", 1),
 
-		GossipSeeds: getEnv("GOSSIP_SEEDS", "llm-backend-headless.llm.svc.cluster.local:7946"),
+		Backend:        getEnv("BACKEND", "echo"),
+		LlamaServerURL: getEnv("LLAMA_SERVER_URL", "http://127.0.0.1:8080"),
+		LlamaServerBin: getEnv("LLAMA_SERVER_BIN", ""),
+		LlamaModel:     getEnv("LLAMA_MODEL", ""),
 
-		ModelDir: getEnv("MODEL_DIR", "/models"),
+		PipelineLayers:  getEnvInt("PIPELINE_LAYERS", 8),
+		PipelineLayerMB: getEnvInt("PIPELINE_LAYER_MB", 0),
 
-		PollInterval: getEnvDuration("POLL_INTERVAL", 5*time.Second),
-		DialTimeout:  getEnvDuration("DIAL_TIMEOUT", 5*time.Second),
+		SessionDir: getEnv("SESSION_DIR", ""),
+
+		MaxConcurrentGenerations: getEnvInt("MAX_CONCURRENT_GENERATIONS", 4),
+		MaxQueueDepth:            getEnvInt("MAX_QUEUE_DEPTH", 64),
+
+		OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TraceFile:    getEnv("TRACE_FILE", ""),
+
+		PollInterval:   getEnvDuration("POLL_INTERVAL", 2*time.Second),
+		SampleInterval: getEnvDuration("SAMPLE_INTERVAL", time.Second),
+		MetricsHistory: getEnvDuration("METRICS_HISTORY", 5*time.Minute),
+		ModelScan:      getEnvDuration("MODEL_SCAN_INTERVAL", 30*time.Second),
+		DialTimeout:    getEnvDuration("DIAL_TIMEOUT", 5*time.Second),
 	}
 	return cfg, nil
 }
@@ -58,3 +119,13 @@ func getEnvDuration(key string, defaultDur time.Duration) time.Duration {
 	}
 	return defaultDur
 }
+
+// getEnvInt parses an int from env or returns defaultVal on error/empty.
+func getEnvInt(key string, defaultVal int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
@@ -3,6 +3,7 @@ package main_test
 import (
 	"bufio"
 	"context"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"testing"
@@ -13,12 +14,12 @@ import (
 
 func TestServerClientEndToEnd(t *testing.T) {
 	// 1) build and launch the server binary on an ephemeral port
-	if out, err := exec.Command("go", "build", "-o", "metrics-server", "./cmd/metrics-server").CombinedOutput(); err != nil {
+	if out, err := exec.Command("go", "build", "-o", "metrics-server", "../cmd/metrics-server").CombinedOutput(); err != nil {
 		t.Fatalf("build server failed: %v\n%s", err, out)
 	}
 	defer exec.Command("rm", "metrics-server").Run()
 
-	cmd := exec.Command("./metrics-server", "--host-id", "inttest", "--port", "0")
+	cmd := exec.Command("./metrics-server", "--host-id", "inttest", "--port", "0", "--auth=false")
 	stdout, _ := cmd.StdoutPipe()
 	if err := cmd.Start(); err != nil {
 		t.Fatalf("start server failed: %v", err)
@@ -52,7 +53,7 @@ func TestServerClientEndToEnd(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	cli, err := client.NewClient(ctx, addr)
+	cli, err := client.NewClient(ctx, addr, slog.New(slog.DiscardHandler))
 	if err != nil {
 		t.Fatalf("NewClient() error: %v", err)
 	}
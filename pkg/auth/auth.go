@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,9 +11,7 @@ import (
 	"strings"
 	"time"
 
-	oidc "github.com/coreos/go-oidc"
 	oauth2 "golang.org/x/oauth2"
-	"golang.org/x/oauth2/clientcredentials"
 )
 
 // OIDCConfig holds Keycloak endpoints and client info
@@ -28,85 +27,193 @@ type DeviceFlowResult struct {
 	Expiry       time.Time // Expiry time of AccessToken
 }
 
-// RunDeviceFlow starts the OAuth2 Device Code Flow with Keycloak.
+// Errors ending a device login that polling again cannot fix
+var (
+	ErrAccessDenied = errors.New("device login was denied")
+	ErrExpiredToken = errors.New("device code expired before the login was approved")
+)
+
+// deviceGrantType is the grant_type of the device access token request,
+// RFC 8628 section 3.4
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// intervalUnit is what the provider's polling intervals count in; tests
+// shorten it
+var intervalUnit = time.Second
+
+// RunDeviceFlow runs the OAuth2 Device Authorization Grant (RFC 8628): it
+// asks the provider for a user code, prints where to enter it, and polls
+// for tokens until the user approves or denies the login or the code
+// expires.
 func RunDeviceFlow(ctx context.Context, cfg OIDCConfig) (*DeviceFlowResult, error) {
-	// Discover endpoints
-	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	// Find the provider's device and token endpoints
+	_, ep, err := discover(ctx, cfg.IssuerURL)
 	if err != nil {
 		return nil, err
 	}
+	if ep.DeviceAuth == "" {
+		return nil, ErrDeviceAuthUnsupported
+	}
 
 	// Request device/user codes
-	deviceResp, err := requestDeviceCode(ctx, cfg.IssuerURL, cfg.ClientID)
+	deviceResp, err := requestDeviceCode(ctx, ep.DeviceAuth, cfg.ClientID)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("\nVisit %s and enter code: %s\n", deviceResp.VerificationURI, deviceResp.UserCode)
+	if deviceResp.VerificationURIComplete != "" {
+		fmt.Printf("\nVisit %s to log in, or visit %s and enter code: %s\n",
+			deviceResp.VerificationURIComplete, deviceResp.VerificationURI, deviceResp.UserCode)
+	} else {
+		fmt.Printf("\nVisit %s and enter code: %s\n", deviceResp.VerificationURI, deviceResp.UserCode)
+	}
 
 	// Poll for token
-	oauthCfg := clientcredentials.Config{
-		ClientID:  cfg.ClientID,
-		TokenURL:  deviceResp.TokenURL,
-		AuthStyle: oauth2.AuthStyleInParams,
+	interval := time.Duration(deviceResp.Interval) * intervalUnit
+	if deviceResp.Interval <= 0 {
+		interval = 5 * intervalUnit // the RFC's default
+	}
+	var expired <-chan time.Time // never, if the provider gave no lifetime
+	if deviceResp.ExpiresIn > 0 {
+		t := time.NewTimer(time.Duration(deviceResp.ExpiresIn) * intervalUnit)
+		defer t.Stop()
+		expired = t.C
 	}
-
-	ticker := time.NewTicker(time.Duration(deviceResp.Interval) * time.Second)
-	defer ticker.Stop()
-	timeout := time.After(time.Duration(deviceResp.ExpiresIn) * time.Second)
-
 	for {
+		wait := time.NewTimer(interval)
 		select {
-		case <-ticker.C:
-			tok, err := oauthCfg.Token(ctx)
-			if err == nil {
-				return &DeviceFlowResult{
-					AccessToken:  tok.AccessToken,
-					RefreshToken: tok.RefreshToken,
-					Expiry:       tok.Expiry,
-				}, nil
+		case <-ctx.Done():
+			wait.Stop()
+			return nil, ctx.Err()
+		case <-expired:
+			wait.Stop()
+			return nil, ErrExpiredToken
+		case <-wait.C:
+		}
+		tok, err := requestDeviceToken(ctx, ep.Token, cfg.ClientID, deviceResp.DeviceCode)
+		var oauthErr *tokenError
+		if !errors.As(err, &oauthErr) {
+			if err != nil {
+				return nil, err
 			}
-		case <-timeout:
-			return nil, fmt.Errorf("device flow timeout")
+			return &DeviceFlowResult{
+				AccessToken:  tok.AccessToken,
+				RefreshToken: tok.RefreshToken,
+				Expiry:       tok.Expiry,
+			}, nil
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			// section 3.5: every slow_down adds 5 seconds for good
+			interval += 5 * intervalUnit
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrExpiredToken
+		default:
+			return nil, err
 		}
 	}
 }
 
 // deviceCodeResponse holds device auth response fields
 type deviceCodeResponse struct {
-	DeviceCode      string `json:"device_code"`
-	UserCode        string `json:"user_code"`
-	VerificationURI string `json:"verification_uri"`
-	ExpiresIn       int    `json:"expires_in"`
-	Interval        int    `json:"interval"`
-	TokenURL        string // full token endpoint
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// tokenError is an OAuth2 error response, RFC 6749 section 5.2
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *tokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+	}
+	return "oauth2: " + e.Code
 }
 
 // requestDeviceCode does the HTTP call to get device code
-func requestDeviceCode(ctx context.Context, issuer, clientID string) (*deviceCodeResponse, error) {
-	discoURL := issuer + "/protocol/openid-connect/auth/device"
+func requestDeviceCode(ctx context.Context, deviceAuthURL, clientID string) (*deviceCodeResponse, error) {
 	values := url.Values{}
 	values.Set("client_id", clientID)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", discoURL, strings.NewReader(values.Encode()))
-	if err != nil {
+	var codeResp deviceCodeResponse
+	if err := postForm(ctx, deviceAuthURL, values, &codeResp); err != nil {
+		return nil, fmt.Errorf("device authorization request: %w", err)
+	}
+	if codeResp.DeviceCode == "" || codeResp.UserCode == "" || codeResp.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization request: response lacks device_code, user_code or verification_uri")
+	}
+	return &codeResp, nil
+}
+
+// requestDeviceToken asks once for the tokens of an approved device code.
+// While the user has not yet decided the error is a *tokenError.
+func requestDeviceToken(ctx context.Context, tokenURL, clientID, deviceCode string) (*oauth2.Token, error) {
+	values := url.Values{}
+	values.Set("grant_type", deviceGrantType)
+	values.Set("device_code", deviceCode)
+	values.Set("client_id", clientID)
+
+	var tokResp struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := postForm(ctx, tokenURL, values, &tokResp); err != nil {
 		return nil, err
 	}
+	if tokResp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	tok := &oauth2.Token{
+		AccessToken:  tokResp.AccessToken,
+		TokenType:    tokResp.TokenType,
+		RefreshToken: tokResp.RefreshToken,
+	}
+	if tokResp.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tokResp.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// postForm posts values to endpoint and decodes the JSON reply into out,
+// unless out is nil. An OAuth2 error reply is returned as a *tokenError.
+func postForm(ctx context.Context, endpoint string, values url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var codeResp deviceCodeResponse
-	if err := json.Unmarshal(body, &codeResp); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		var oauthErr tokenError
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
-	codeResp.TokenURL = issuer + "/protocol/openid-connect/token"
-	return &codeResp, nil
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
 }
@@ -2,9 +2,31 @@ package auth_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth/authtest"
+	oauth2 "golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 func TestPerRPCCredentials_GetRequestMetadata(t *testing.T) {
@@ -35,3 +57,542 @@ func TestUnaryServerInterceptor_MissingToken(t *testing.T) {
 		t.Error("expected error for missing metadata, got nil")
 	}
 }
+
+// fakeStream is a server stream with only a context
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context { return s.ctx }
+
+func TestServerInterceptors(t *testing.T) {
+	opts := []auth.InterceptorOption{
+		auth.AllowUnauthenticated(auth.PublicMethods...),
+		auth.AllowUnauthenticated("/proto.MetricsService/GetMetrics"),
+	}
+	unary, err := auth.UnaryServerInterceptor(nil, "client-id", opts...)
+	if err != nil {
+		t.Fatalf("UnaryServerInterceptor(): unexpected error: %v", err)
+	}
+	stream, err := auth.StreamServerInterceptor(nil, "client-id", opts...)
+	if err != nil {
+		t.Fatalf("StreamServerInterceptor(): unexpected error: %v", err)
+	}
+
+	noMetadata := context.Background()
+	noToken := metadata.NewIncomingContext(context.Background(), metadata.MD{})
+	badToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer not-a-jwt"))
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"no metadata", noMetadata, "/proto.ChatService/Chat", codes.Unauthenticated},
+		{"no token", noToken, "/proto.ChatService/ChatStream", codes.Unauthenticated},
+		{"bad token", badToken, "/proto.ChatService/Chat", codes.Unauthenticated},
+		{"public service", noToken, "/grpc.health.v1.Health/Watch", codes.OK},
+		{"reflection", noMetadata, "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", codes.OK},
+		{"public method", noToken, "/proto.MetricsService/GetMetrics", codes.OK},
+		{"other method of its service", noToken, "/proto.MetricsService/WatchMetrics", codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unary(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return "ok", nil
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("unary %s code = %v; want %v (%v)", tt.method, got, tt.want, err)
+			}
+			err = stream(nil, fakeStream{ctx: tt.ctx}, &grpc.StreamServerInfo{FullMethod: tt.method}, func(srv interface{}, ss grpc.ServerStream) error {
+				return nil
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("stream %s code = %v; want %v (%v)", tt.method, got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestServerInterceptorsIdentity(t *testing.T) {
+	iss := authtest.NewIssuer(t, "llm-client")
+	provider := iss.Provider(t)
+	unary, _ := auth.UnaryServerInterceptor(provider, "llm-client")
+	stream, _ := auth.StreamServerInterceptor(provider, "llm-client")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+iss.Token(t, "ada")))
+
+	var got []auth.Identity
+	_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/proto.ChatService/Chat"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		id, _ := auth.IdentityFromContext(ctx)
+		got = append(got, id)
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("unary: unexpected error: %v", err)
+	}
+	err = stream(nil, fakeStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/proto.ChatService/ChatStream"}, func(srv interface{}, ss grpc.ServerStream) error {
+		id, _ := auth.IdentityFromContext(ss.Context())
+		got = append(got, id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: unexpected error: %v", err)
+	}
+	for i, kind := range []string{"unary", "stream"} {
+		if got[i].Subject != "ada" || got[i].Username != "ada" {
+			t.Errorf("%s handler identity = %+v; want ada", kind, got[i])
+		}
+	}
+
+	// a token for another client is refused
+	other := authtest.NewIssuer(t, "other-client")
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+other.Token(t, "ada")))
+	err = stream(nil, fakeStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/proto.ChatService/ChatStream"}, func(interface{}, grpc.ServerStream) error {
+		return nil
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("stream with another issuer's token error = %v; want Unauthenticated", err)
+	}
+}
+
+func TestForwardCredentials(t *testing.T) {
+	var got []string
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		got = append(got, strings.Join(md["authorization"], ","))
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	lis := bufconn.Listen(1024 * 1024)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(auth.ForwardCredentials(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	cli := healthpb.NewHealthClient(conn)
+
+	incoming := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer caller"))
+	own := metadata.AppendToOutgoingContext(incoming, "authorization", "Bearer own")
+	for _, ctx := range []context.Context{context.Background(), incoming, own} {
+		if _, err := cli.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("Check(): unexpected error: %v", err)
+		}
+	}
+	want := []string{"", "Bearer caller", "Bearer own"}
+	if !slices.Equal(got, want) {
+		t.Errorf("authorization sent = %q; want %q", got, want)
+	}
+}
+
+// layout is where a fake provider serves its endpoints under the issuer
+// path, and whether its discovery document lists them. It has no endpoint
+// whose path is empty.
+type layout struct {
+	issuer                          string
+	device, token, userinfo, revoke string
+	listed                          bool
+}
+
+var (
+	// keycloak serves its own paths under a realm without listing them,
+	// which the client falls back to
+	keycloak = layout{"/realms/llm", "/protocol/openid-connect/auth/device", "/protocol/openid-connect/token", "/protocol/openid-connect/userinfo", "/protocol/openid-connect/revoke", false}
+	dex      = layout{"", "/device/code", "/token", "/userinfo", "/token/revoke", true}
+	// bare has neither device logins nor revocation
+	bare = layout{"", "", "/token", "/userinfo", "", true}
+)
+
+// fakeProvider is a local OIDC provider whose token endpoint answers
+// device code polls from a script. It swaps refresh-1 for access-2 and
+// records revocations.
+type fakeProvider struct {
+	*httptest.Server
+	Issuer    string
+	expiresIn int
+
+	mu      sync.Mutex
+	replies []string // error to answer each poll with; "" issues tokens
+	polls   []time.Time
+	forms   []url.Values
+	refresh []url.Values
+	revoked []url.Values
+}
+
+func newFakeProvider(t *testing.T, l layout, expiresIn int, replies ...string) *fakeProvider {
+	t.Helper()
+	p := &fakeProvider{expiresIn: expiresIn, replies: replies}
+	mux := http.NewServeMux()
+	handle := func(method, path string, h http.HandlerFunc) {
+		if path != "" {
+			mux.HandleFunc(method+" "+l.issuer+path, h)
+		}
+	}
+	handle("GET", "/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		doc := map[string]string{
+			"issuer":                 p.Issuer,
+			"authorization_endpoint": p.Issuer + "/auth",
+			"jwks_uri":               p.Issuer + "/certs",
+		}
+		if l.listed {
+			for key, path := range map[string]string{
+				"device_authorization_endpoint": l.device,
+				"token_endpoint":                l.token,
+				"userinfo_endpoint":             l.userinfo,
+				"revocation_endpoint":           l.revoke,
+			} {
+				if path != "" {
+					doc[key] = p.Issuer + path
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(doc)
+	})
+	handle("POST", l.device, func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "llm-client" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "dev-123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": p.URL + "/device",
+			"expires_in":       p.expiresIn,
+			"interval":         1,
+		})
+	})
+	handle("GET", l.userinfo, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"preferred_username": "ada", "email": "ada@example.com"})
+	})
+	handle("POST", l.revoke, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		p.revoked = append(p.revoked, r.PostForm)
+		p.mu.Unlock()
+	})
+	handle("POST", l.token, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("grant_type") == "refresh_token" {
+			p.mu.Lock()
+			p.refresh = append(p.refresh, r.PostForm)
+			p.mu.Unlock()
+			if r.PostForm.Get("refresh_token") != "refresh-1" || r.PostForm.Get("client_id") != "llm-client" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token":  "access-2",
+				"refresh_token": "refresh-2",
+				"token_type":    "Bearer",
+				"expires_in":    300,
+			})
+			return
+		}
+		p.mu.Lock()
+		p.polls = append(p.polls, time.Now())
+		p.forms = append(p.forms, r.PostForm)
+		reply := "authorization_pending"
+		if len(p.replies) > 0 {
+			reply, p.replies = p.replies[0], p.replies[1:]
+		}
+		p.mu.Unlock()
+		if reply != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": reply})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access-1",
+			"refresh_token": "refresh-1",
+			"token_type":    "Bearer",
+			"expires_in":    300,
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	p.Issuer = p.URL + l.issuer
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestRunDeviceFlow(t *testing.T) {
+	const unit = 10 * time.Millisecond
+	defer auth.SetIntervalUnit(unit)()
+	p := newFakeProvider(t, keycloak, 600, "authorization_pending", "slow_down", "authorization_pending", "")
+
+	res, err := auth.RunDeviceFlow(context.Background(), auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"})
+	if err != nil {
+		t.Fatalf("RunDeviceFlow(): unexpected error: %v", err)
+	}
+	if res.AccessToken != "access-1" || res.RefreshToken != "refresh-1" || time.Until(res.Expiry) < 4*time.Minute {
+		t.Errorf("RunDeviceFlow() = %+v; want the issued tokens, expiring in 5m", res)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.forms) != 4 {
+		t.Fatalf("token endpoint polled %d times; want 4", len(p.forms))
+	}
+	for i, form := range p.forms {
+		if form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" ||
+			form.Get("device_code") != "dev-123" || form.Get("client_id") != "llm-client" {
+			t.Errorf("poll %d form = %v; want the device_code grant for dev-123", i, form)
+		}
+	}
+	// slow_down adds 5 to the interval of 1 for every later poll
+	for i := 2; i < len(p.polls); i++ {
+		if gap := p.polls[i].Sub(p.polls[i-1]); gap < 6*unit {
+			t.Errorf("poll %d came %v after the last; want at least %v after slow_down", i, gap, 6*unit)
+		}
+	}
+}
+
+func TestRunDeviceFlowErrors(t *testing.T) {
+	defer auth.SetIntervalUnit(time.Millisecond)()
+	tests := []struct {
+		name      string
+		expiresIn int
+		replies   []string
+		want      error
+	}{
+		{"denied", 600, []string{"authorization_pending", "access_denied"}, auth.ErrAccessDenied},
+		{"expired", 600, []string{"expired_token"}, auth.ErrExpiredToken},
+		{"lifetime passes", 20, nil, auth.ErrExpiredToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProvider(t, keycloak, tt.expiresIn, tt.replies...)
+			_, err := auth.RunDeviceFlow(context.Background(), auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("RunDeviceFlow() error = %v; want %v", err, tt.want)
+			}
+		})
+	}
+
+	p := newFakeProvider(t, keycloak, 600, "invalid_grant")
+	_, err := auth.RunDeviceFlow(context.Background(), auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"})
+	if err == nil || errors.Is(err, auth.ErrAccessDenied) || errors.Is(err, auth.ErrExpiredToken) {
+		t.Errorf("RunDeviceFlow() on invalid_grant error = %v; want a plain failure", err)
+	}
+	if _, err := auth.RunDeviceFlow(context.Background(), auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "other"}); err == nil {
+		t.Error("RunDeviceFlow() for an unknown client succeeded; want the device request to fail")
+	}
+}
+
+func TestDiscoveredEndpoints(t *testing.T) {
+	defer auth.SetIntervalUnit(time.Millisecond)()
+	for name, l := range map[string]layout{"listed": dex, "keycloak fallback": keycloak} {
+		t.Run(name, func(t *testing.T) {
+			p := newFakeProvider(t, l, 600, "")
+			ctx := context.Background()
+			res, err := auth.RunDeviceFlow(ctx, auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"})
+			if err != nil {
+				t.Fatalf("RunDeviceFlow(): unexpected error: %v", err)
+			}
+			ui, err := auth.FetchUserInfo(ctx, p.Issuer, res.AccessToken)
+			if err != nil {
+				t.Fatalf("FetchUserInfo(): unexpected error: %v", err)
+			}
+			if ui.PreferredUsername != "ada" || ui.Email != "ada@example.com" {
+				t.Errorf("FetchUserInfo() = %+v; want ada's profile", ui)
+			}
+		})
+	}
+
+	// only a Keycloak realm is assumed to have endpoints it does not list
+	p := newFakeProvider(t, bare, 600, "")
+	if _, err := auth.RunDeviceFlow(context.Background(), auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"}); !errors.Is(err, auth.ErrDeviceAuthUnsupported) {
+		t.Errorf("RunDeviceFlow() without a device endpoint error = %v; want ErrDeviceAuthUnsupported", err)
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	tok := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour).Round(0)}
+	for _, passphrase := range []string{"", "hunter2"} {
+		t.Run("passphrase "+passphrase, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "llm-test", "tokens.json")
+			store := auth.NewTokenStore(path, passphrase)
+			if _, err := store.Load(); !errors.Is(err, auth.ErrNotLoggedIn) {
+				t.Fatalf("Load() before Save() error = %v; want ErrNotLoggedIn", err)
+			}
+			if err := store.Save(tok); err != nil {
+				t.Fatalf("Save(): unexpected error: %v", err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Stat(): unexpected error: %v", err)
+			}
+			if fi.Mode().Perm() != 0o600 {
+				t.Errorf("token cache mode = %v; want 0600", fi.Mode().Perm())
+			}
+			data, _ := os.ReadFile(path)
+			if encrypted := !strings.Contains(string(data), "refresh-1"); encrypted != (passphrase != "") {
+				t.Errorf("token cache encrypted = %v; want %v", encrypted, passphrase != "")
+			}
+
+			got, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load(): unexpected error: %v", err)
+			}
+			if got.AccessToken != tok.AccessToken || got.RefreshToken != tok.RefreshToken || !got.Expiry.Equal(tok.Expiry) {
+				t.Errorf("Load() = %+v; want %+v", got, tok)
+			}
+			if err := store.Delete(); err != nil {
+				t.Fatalf("Delete(): unexpected error: %v", err)
+			}
+			if _, err := store.Load(); !errors.Is(err, auth.ErrNotLoggedIn) {
+				t.Errorf("Load() after Delete() error = %v; want ErrNotLoggedIn", err)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := auth.NewTokenStore(path, "hunter2").Save(tok); err != nil {
+		t.Fatalf("Save(): unexpected error: %v", err)
+	}
+	if _, err := auth.NewTokenStore(path, "hunter3").Load(); !errors.Is(err, auth.ErrBadPassphrase) {
+		t.Errorf("Load() with the wrong passphrase error = %v; want ErrBadPassphrase", err)
+	}
+}
+
+func TestTokenSourceRefreshes(t *testing.T) {
+	p := newFakeProvider(t, dex, 600)
+	cfg := auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"}
+	store := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"), "")
+	if _, err := auth.TokenSource(context.Background(), cfg, store); !errors.Is(err, auth.ErrNotLoggedIn) {
+		t.Fatalf("TokenSource() with no cache error = %v; want ErrNotLoggedIn", err)
+	}
+
+	// a token expiring within the minute is replaced before use
+	if err := store.Save(&oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(30 * time.Second)}); err != nil {
+		t.Fatalf("Save(): unexpected error: %v", err)
+	}
+	src, err := auth.TokenSource(context.Background(), cfg, store)
+	if err != nil {
+		t.Fatalf("TokenSource(): unexpected error: %v", err)
+	}
+	md, err := auth.TokenSourceCredentials(src).GetRequestMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("GetRequestMetadata(): unexpected error: %v", err)
+	}
+	if md["authorization"] != "Bearer access-2" {
+		t.Errorf("authorization = %q; want the refreshed token", md["authorization"])
+	}
+	if _, err := src.Token(); err != nil {
+		t.Fatalf("Token(): unexpected error: %v", err)
+	}
+	p.mu.Lock()
+	if len(p.refresh) != 1 {
+		t.Errorf("token refreshed %d times; want once while it is fresh", len(p.refresh))
+	}
+	p.mu.Unlock()
+
+	cached, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load(): unexpected error: %v", err)
+	}
+	if cached.AccessToken != "access-2" || cached.RefreshToken != "refresh-2" {
+		t.Errorf("cached token = %+v; want the refreshed one", cached)
+	}
+
+	// a refresh the provider rejects means logging in again
+	if err := store.Save(&oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-2", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Save(): unexpected error: %v", err)
+	}
+	src, err = auth.TokenSource(context.Background(), cfg, store)
+	if err != nil {
+		t.Fatalf("TokenSource(): unexpected error: %v", err)
+	}
+	if _, err := src.Token(); err == nil {
+		t.Error("Token() with a rejected refresh token succeeded; want an error")
+	}
+}
+
+func TestTokenSourceOffline(t *testing.T) {
+	// nothing listens here, so discovery fails
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	cfg := auth.OIDCConfig{IssuerURL: "http://" + lis.Addr().String(), ClientID: "llm-client"}
+	lis.Close()
+	store := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"), "")
+
+	// a fresh cached token is used without asking the provider
+	if err := store.Save(&oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Save(): unexpected error: %v", err)
+	}
+	src, err := auth.TokenSource(context.Background(), cfg, store)
+	if err != nil {
+		t.Fatalf("TokenSource() with the issuer down: unexpected error: %v", err)
+	}
+	tok, err := src.Token()
+	if err != nil {
+		t.Fatalf("Token(): unexpected error: %v", err)
+	}
+	if tok.AccessToken != "access-1" {
+		t.Errorf("Token() = %q; want the cached access-1", tok.AccessToken)
+	}
+
+	// one that needs refreshing fails until the provider is back
+	if err := store.Save(&oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Save(): unexpected error: %v", err)
+	}
+	if src, err = auth.TokenSource(context.Background(), cfg, store); err != nil {
+		t.Fatalf("TokenSource(): unexpected error: %v", err)
+	}
+	if _, err := src.Token(); err == nil {
+		t.Error("Token() refreshing with the issuer down succeeded; want an error")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	for name, l := range map[string]layout{"listed": dex, "keycloak fallback": keycloak, "no revocation": bare} {
+		t.Run(name, func(t *testing.T) {
+			p := newFakeProvider(t, l, 600)
+			cfg := auth.OIDCConfig{IssuerURL: p.Issuer, ClientID: "llm-client"}
+			store := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"), "")
+			if err := store.Save(&oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"}); err != nil {
+				t.Fatalf("Save(): unexpected error: %v", err)
+			}
+			if err := auth.Logout(context.Background(), cfg, store); err != nil {
+				t.Fatalf("Logout(): unexpected error: %v", err)
+			}
+			if _, err := store.Load(); !errors.Is(err, auth.ErrNotLoggedIn) {
+				t.Errorf("Load() after Logout() error = %v; want ErrNotLoggedIn", err)
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			want := []struct{ token, hint string }{{"refresh-1", "refresh_token"}, {"access-1", "access_token"}}
+			if l.revoke == "" {
+				want = nil
+			}
+			if len(p.revoked) != len(want) {
+				t.Fatalf("%d tokens revoked; want %d", len(p.revoked), len(want))
+			}
+			for i, w := range want {
+				form := p.revoked[i]
+				if form.Get("token") != w.token || form.Get("token_type_hint") != w.hint || form.Get("client_id") != "llm-client" {
+					t.Errorf("revocation %d form = %v; want %s as %s", i, form, w.token, w.hint)
+				}
+			}
+		})
+	}
+
+	// logging out with nothing cached does nothing
+	store := auth.NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"), "")
+	if err := auth.Logout(context.Background(), auth.OIDCConfig{IssuerURL: "http://127.0.0.1:0", ClientID: "llm-client"}, store); err != nil {
+		t.Errorf("Logout() when logged out: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	oidc "github.com/coreos/go-oidc"
+)
+
+// ErrNoToken is returned by Verify when no bearer token was presented
+var ErrNoToken = errors.New("authorization token not provided")
+
+// Verifier checks bearer tokens issued by the OIDC provider. It is shared
+// by the gRPC interceptor and the HTTP gateway so both accept the same
+// Keycloak tokens.
+type Verifier struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewVerifier accepts tokens from provider issued to clientID. A nil
+// provider rejects every token.
+func NewVerifier(provider *oidc.Provider, clientID string) *Verifier {
+	v := &Verifier{}
+	if provider != nil {
+		v.verifier = provider.Verifier(&oidc.Config{ClientID: clientID})
+	}
+	return v
+}
+
+// Verify checks an Authorization header value ("Bearer <token>") and
+// returns the caller it identifies.
+func (v *Verifier) Verify(ctx context.Context, header string) (Identity, error) {
+	tok := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if tok == "" {
+		return Identity{}, ErrNoToken
+	}
+	if v.verifier == nil {
+		return Identity{}, fmt.Errorf("no OIDC provider configured")
+	}
+	idTok, err := v.verifier.Verify(ctx, tok)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid token: %w", err)
+	}
+	id, err := identityFromToken(idTok)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid token claims: %w", err)
+	}
+	return id, nil
+}
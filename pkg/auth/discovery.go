@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	oidc "github.com/coreos/go-oidc"
+)
+
+// ErrDeviceAuthUnsupported reports a provider with no device authorization
+// endpoint, which logging in from a terminal needs
+var ErrDeviceAuthUnsupported = errors.New("provider does not support device authorization")
+
+// endpoints are where the provider takes the requests this package makes
+// besides token verification. Revocation is empty if the provider offers
+// none.
+type endpoints struct {
+	DeviceAuth string `json:"device_authorization_endpoint"`
+	Token      string `json:"token_endpoint"`
+	UserInfo   string `json:"userinfo_endpoint"`
+	Revocation string `json:"revocation_endpoint"`
+}
+
+// keycloakRealm matches the path of a Keycloak realm's issuer URL, with or
+// without the /auth prefix of releases before 17
+var keycloakRealm = regexp.MustCompile(`^(/auth)?/realms/[^/]+/?$`)
+
+// discover reads the issuer's endpoints from its OpenID discovery
+// document. For a Keycloak realm, endpoints the document leaves out fall
+// back to Keycloak's fixed paths; other providers must list the ones they
+// have.
+func discover(ctx context.Context, issuer string) (*oidc.Provider, endpoints, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, endpoints{}, err
+	}
+	var ep endpoints
+	if err := provider.Claims(&ep); err != nil {
+		return nil, endpoints{}, fmt.Errorf("reading discovery document: %w", err)
+	}
+	if u, err := url.Parse(issuer); err == nil && keycloakRealm.MatchString(u.Path) {
+		keycloak := strings.TrimSuffix(issuer, "/") + "/protocol/openid-connect"
+		fill := func(endpoint *string, path string) {
+			if *endpoint == "" {
+				*endpoint = keycloak + path
+			}
+		}
+		fill(&ep.DeviceAuth, "/auth/device")
+		fill(&ep.Token, "/token")
+		fill(&ep.UserInfo, "/userinfo")
+		fill(&ep.Revocation, "/revoke")
+	}
+	if ep.Token == "" {
+		return nil, endpoints{}, errors.New("discovery document lists no token endpoint")
+	}
+	return provider, ep, nil
+}
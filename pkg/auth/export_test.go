@@ -0,0 +1,11 @@
+package auth
+
+import "time"
+
+// SetIntervalUnit makes the provider's polling intervals and code lifetimes
+// count in d rather than seconds, returning a func that restores them.
+func SetIntervalUnit(d time.Duration) (restore func()) {
+	prev := intervalUnit
+	intervalUnit = d
+	return func() { intervalUnit = prev }
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	oidc "github.com/coreos/go-oidc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Identity is the verified caller of an RPC
+type Identity struct {
+	Subject  string // stable user ID from the token's sub claim
+	Username string // preferred_username claim, if present
+	Email    string // email claim, if present
+}
+
+type identityKey struct{}
+
+// ContextWithIdentity returns a copy of ctx carrying id
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the caller attached by the server interceptor.
+// ok is false for unauthenticated requests.
+func IdentityFromContext(ctx context.Context) (id Identity, ok bool) {
+	id, ok = ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Authorize checks that the caller in ctx is one of subjects, matched
+// against the token's sub claim. It fails with Unauthenticated when there
+// is no verified caller and PermissionDenied for anyone else.
+func Authorize(ctx context.Context, subjects []string) error {
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "no verified caller")
+	}
+	if !slices.Contains(subjects, id.Subject) {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed to do this", id.Subject)
+	}
+	return nil
+}
+
+// identityFromToken reads the caller's identity from verified token claims
+func identityFromToken(tok *oidc.IDToken) (Identity, error) {
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+	}
+	if err := tok.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+	return Identity{Subject: tok.Subject, Username: claims.PreferredUsername, Email: claims.Email}, nil
+}
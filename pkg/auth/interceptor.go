@@ -2,50 +2,169 @@ package auth
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	oidc "github.com/coreos/go-oidc"
+	oauth2 "golang.org/x/oauth2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// PublicMethods are the gRPC services that answer without a token: health
+// checks, which probes call unauthenticated, and server reflection.
+var PublicMethods = []string{
+	"grpc.health.v1.Health",
+	"grpc.reflection.v1.ServerReflection",
+	"grpc.reflection.v1alpha.ServerReflection",
+}
+
+// InterceptorOption configures the server interceptors.
+type InterceptorOption func(*interceptor)
+
+// AllowUnauthenticated lets calls to methods through without a token.
+// Each is a full method ("/pkg.Service/Method") or a whole service
+// ("pkg.Service"), as in PublicMethods.
+func AllowUnauthenticated(methods ...string) InterceptorOption {
+	return func(i *interceptor) {
+		for _, m := range methods {
+			i.public[m] = true
+		}
+	}
+}
+
+// interceptor checks the bearer token of each call not on its allow-list
+type interceptor struct {
+	verifier *Verifier
+	public   map[string]bool
+}
+
+func newInterceptor(provider *oidc.Provider, clientID string, opts []InterceptorOption) *interceptor {
+	i := &interceptor{verifier: NewVerifier(provider, clientID), public: map[string]bool{}}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// authenticate returns ctx carrying the caller of fullMethod, or ctx as it
+// is for a public method. Failures are codes.Unauthenticated.
+func (i *interceptor) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if i.public[fullMethod] || i.public[service] {
+		return ctx, nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	authHeaders := md["authorization"]
+	if len(authHeaders) == 0 {
+		return nil, status.Error(codes.Unauthenticated, ErrNoToken.Error())
+	}
+	id, err := i.verifier.Verify(ctx, authHeaders[0])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return ContextWithIdentity(ctx, id), nil
+}
+
 // UnaryServerInterceptor returns a gRPC interceptor that validates JWTs from Keycloak.
-func UnaryServerInterceptor(provider *oidc.Provider, clientID string) (grpc.UnaryServerInterceptor, error) {
-	verifier := provider.Verifier(&oidc.Config{ClientID: clientID})
+// The verified caller is attached to the handler's context; see IdentityFromContext.
+func UnaryServerInterceptor(provider *oidc.Provider, clientID string, opts ...InterceptorOption) (grpc.UnaryServerInterceptor, error) {
+	i := newInterceptor(provider, clientID, opts)
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
-		hhandler grpc.UnaryHandler,
+		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, fmt.Errorf("missing metadata")
+		method := ""
+		if info != nil {
+			method = info.FullMethod
 		}
-
-		authHeaders := md["authorization"]
-		if len(authHeaders) == 0 {
-			return nil, fmt.Errorf("authorization token not provided")
-		}
-		tok := strings.TrimPrefix(authHeaders[0], "Bearer ")
-		if _, err := verifier.Verify(ctx, tok); err != nil {
-			return nil, fmt.Errorf("invalid token: %w", err)
+		ctx, err := i.authenticate(ctx, method)
+		if err != nil {
+			return nil, err
 		}
-
 		return handler(ctx, req)
 	}, nil
 }
 
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs.
+func StreamServerInterceptor(provider *oidc.Provider, clientID string, opts ...InterceptorOption) (grpc.StreamServerInterceptor, error) {
+	i := newInterceptor(provider, clientID, opts)
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}, nil
+}
+
+// identityStream is a server stream whose context carries the caller
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context { return s.ctx }
+
 // PerRPCCredentials attaches the Bearer token to outgoing RPCs.
-func PerRPCCredentials(token string) grpc.PerRPCCredentials {
-	return oauthToken{token: token}
+func PerRPCCredentials(token string) credentials.PerRPCCredentials {
+	return TokenSourceCredentials(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+}
+
+// TokenSourceCredentials attaches a Bearer token from src to each outgoing
+// RPC, so a refreshing source such as TokenSource keeps calls authorised
+// across token expiry.
+func TokenSourceCredentials(src oauth2.TokenSource) credentials.PerRPCCredentials {
+	return oauthToken{src: src}
 }
 
-type oauthToken struct{ token string }
+type oauthToken struct{ src oauth2.TokenSource }
 
 func (a oauthToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
-	return map[string]string{"authorization": "Bearer " + a.token}, nil
+	tok, err := a.src.Token()
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return map[string]string{"authorization": "Bearer " + tok.AccessToken}, nil
 }
 
 func (a oauthToken) RequireTransportSecurity() bool { return false }
+
+// ForwardCredentials passes the bearer token a call arrived with on to the
+// calls made on its behalf, so a host forwarding a request to a peer acts
+// as the original caller. Calls that set their own token keep it.
+func ForwardCredentials() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(forwardToken(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(forwardToken(ctx), desc, cc, method, opts...)
+		}),
+	}
+}
+
+// forwardToken copies the incoming authorization header of ctx into its
+// outgoing metadata
+func forwardToken(ctx context.Context) context.Context {
+	if out, ok := metadata.FromOutgoingContext(ctx); ok && len(out["authorization"]) > 0 {
+		return ctx
+	}
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(in["authorization"]) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", in["authorization"][0])
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+	oauth2 "golang.org/x/oauth2"
+)
+
+// Errors loading cached tokens
+var (
+	ErrNotLoggedIn   = errors.New("not logged in")
+	ErrBadPassphrase = errors.New("wrong passphrase for the token cache")
+)
+
+// TokenStore keeps the user's tokens in a file between runs, readable by
+// the user alone and, given a passphrase, encrypted.
+type TokenStore struct {
+	path       string
+	passphrase string
+}
+
+// DefaultTokenPath is the token cache in the user's config directory.
+func DefaultTokenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "llm-test", "tokens.json"), nil
+}
+
+// NewTokenStore keeps tokens at path, encrypted with passphrase unless it
+// is empty.
+func NewTokenStore(path, passphrase string) *TokenStore {
+	return &TokenStore{path: path, passphrase: passphrase}
+}
+
+// OpenTokenStore is NewTokenStore at path, or at DefaultTokenPath when
+// path is empty.
+func OpenTokenStore(path, passphrase string) (*TokenStore, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultTokenPath(); err != nil {
+			return nil, err
+		}
+	}
+	return NewTokenStore(path, passphrase), nil
+}
+
+// storedTokens is the file format: the token in the clear, or sealed with
+// AES-GCM under a key derived from the passphrase and salt
+type storedTokens struct {
+	Token  *oauth2.Token `json:"token,omitempty"`
+	Salt   []byte        `json:"salt,omitempty"`
+	Nonce  []byte        `json:"nonce,omitempty"`
+	Sealed []byte        `json:"sealed,omitempty"`
+}
+
+// Load returns the cached token, or ErrNotLoggedIn if there is none.
+func (s *TokenStore) Load() (*oauth2.Token, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st storedTokens
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("token cache %s: %w", s.path, err)
+	}
+	if st.Sealed == nil {
+		if st.Token == nil {
+			return nil, ErrNotLoggedIn
+		}
+		return st.Token, nil
+	}
+	if s.passphrase == "" {
+		return nil, fmt.Errorf("token cache %s is encrypted and no passphrase was given", s.path)
+	}
+	aead, err := newAEAD(s.passphrase, st.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, st.Nonce, st.Sealed, nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(plain, &tok); err != nil {
+		return nil, fmt.Errorf("token cache %s: %w", s.path, err)
+	}
+	return &tok, nil
+}
+
+// Save replaces the cached token.
+func (s *TokenStore) Save(tok *oauth2.Token) error {
+	st := storedTokens{Token: tok}
+	if s.passphrase != "" {
+		plain, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		st = storedTokens{Salt: make([]byte, 16)}
+		if _, err := rand.Read(st.Salt); err != nil {
+			return err
+		}
+		aead, err := newAEAD(s.passphrase, st.Salt)
+		if err != nil {
+			return err
+		}
+		st.Nonce = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(st.Nonce); err != nil {
+			return err
+		}
+		st.Sealed = aead.Seal(nil, st.Nonce, plain, nil)
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	// write a private temporary file and rename it over the cache, so a
+	// crash never leaves half a token behind
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), ".tokens-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+// Delete removes the cached token; there being none is not an error.
+func (s *TokenStore) Delete() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// newAEAD derives an AES-256-GCM cipher from passphrase and salt
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
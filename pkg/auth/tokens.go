@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	oauth2 "golang.org/x/oauth2"
+)
+
+// refreshEarly is how long before its expiry an access token is replaced,
+// so a call never sets off with one about to lapse
+const refreshEarly = time.Minute
+
+// Token returns the result as an oauth2 token, for TokenStore.Save.
+func (r *DeviceFlowResult) Token() *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  r.AccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: r.RefreshToken,
+		Expiry:       r.Expiry,
+	}
+}
+
+// TokenSource serves the token cached in store, refreshing it with the
+// provider shortly before it expires and caching each new one. ctx is used
+// for the refresh requests. The provider is only contacted once a refresh
+// is due, so a cached token still works while it is unreachable. It
+// returns ErrNotLoggedIn if nothing is cached.
+func TokenSource(ctx context.Context, cfg OIDCConfig, store *TokenStore) (oauth2.TokenSource, error) {
+	tok, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &refreshingSource{ctx: ctx, cfg: cfg, store: store, tok: tok}, nil
+}
+
+type refreshingSource struct {
+	ctx   context.Context
+	cfg   OIDCConfig
+	store *TokenStore
+
+	mu   sync.Mutex
+	tok  *oauth2.Token
+	conf *oauth2.Config // set by the first refresh
+}
+
+func (s *refreshingSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok.AccessToken != "" && (s.tok.Expiry.IsZero() || time.Until(s.tok.Expiry) > refreshEarly) {
+		return s.tok, nil
+	}
+	if s.tok.RefreshToken == "" {
+		return nil, fmt.Errorf("access token expired and there is no refresh token: %w", ErrNotLoggedIn)
+	}
+	if s.conf == nil {
+		_, ep, err := discover(s.ctx, s.cfg.IssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("refreshing access token: %w", err)
+		}
+		s.conf = &oauth2.Config{
+			ClientID: s.cfg.ClientID,
+			Endpoint: oauth2.Endpoint{TokenURL: ep.Token, AuthStyle: oauth2.AuthStyleInParams},
+		}
+	}
+	tok, err := s.conf.TokenSource(s.ctx, &oauth2.Token{RefreshToken: s.tok.RefreshToken}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("refreshing access token: %w", err)
+	}
+	s.tok = tok
+	// a token that cannot be cached is still good for this run; the next
+	// refresh tries again
+	_ = s.store.Save(tok)
+	return tok, nil
+}
+
+// Logout revokes the cached tokens with the provider (RFC 7009) and
+// deletes them. They are deleted even if revoking fails, in which case the
+// error says so.
+func Logout(ctx context.Context, cfg OIDCConfig, store *TokenStore) error {
+	tok, err := store.Load()
+	if errors.Is(err, ErrNotLoggedIn) {
+		return nil
+	}
+	if err != nil {
+		// an unreadable cache is removed all the same
+		return errors.Join(err, store.Delete())
+	}
+	revokeErr := revoke(ctx, cfg, tok)
+	if err := store.Delete(); err != nil {
+		return errors.Join(revokeErr, err)
+	}
+	if revokeErr != nil {
+		return fmt.Errorf("tokens deleted but not revoked: %w", revokeErr)
+	}
+	return nil
+}
+
+// revoke asks the provider to revoke the refresh token, which ends the
+// session and its access tokens, and then the access token itself. A
+// provider without a revocation endpoint is left to expire them.
+func revoke(ctx context.Context, cfg OIDCConfig, tok *oauth2.Token) error {
+	_, ep, err := discover(ctx, cfg.IssuerURL)
+	if err != nil || ep.Revocation == "" {
+		return err
+	}
+	hints := []struct{ token, hint string }{
+		{tok.RefreshToken, "refresh_token"},
+		{tok.AccessToken, "access_token"},
+	}
+	for _, h := range hints {
+		if h.token == "" {
+			continue
+		}
+		values := url.Values{}
+		values.Set("token", h.token)
+		values.Set("token_type_hint", h.hint)
+		values.Set("client_id", cfg.ClientID)
+		if err := postForm(ctx, ep.Revocation, values, nil); err != nil {
+			return fmt.Errorf("revoking %s: %w", h.hint, err)
+		}
+	}
+	return nil
+}
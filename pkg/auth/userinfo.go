@@ -3,21 +3,29 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
-// UserInfo holds Keycloak user profile fields
-// retrieved from the /userinfo endpoint
+// UserInfo holds user profile fields
+// retrieved from the provider's userinfo endpoint
 type UserInfo struct {
 	PreferredUsername string `json:"preferred_username"`
 	Email             string `json:"email"`
 }
 
-// FetchUserInfo retrieves the user info from Keycloak's userinfo endpoint
+// FetchUserInfo retrieves the user info from the userinfo endpoint the
+// issuer's discovery document names
 func FetchUserInfo(ctx context.Context, issuerURL, accessToken string) (UserInfo, error) {
-	userinfoURL := issuerURL + "/protocol/openid-connect/userinfo"
-	req, err := http.NewRequestWithContext(ctx, "GET", userinfoURL, nil)
+	_, ep, err := discover(ctx, issuerURL)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	if ep.UserInfo == "" {
+		return UserInfo{}, errors.New("provider has no userinfo endpoint")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", ep.UserInfo, nil)
 	if err != nil {
 		return UserInfo{}, err
 	}
@@ -0,0 +1,63 @@
+// Package backend defines the inference engines the chat server delegates
+// generation to, so the gRPC layer does not depend on any one engine.
+package backend
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable is wrapped by backends when the engine cannot be reached.
+var ErrUnavailable = errors.New("backend unavailable")
+
+// FinishReason says why a generation stopped.
+type FinishReason string
+
+const (
+	FinishStop   FinishReason = "stop"   // end of reply or stop sequence
+	FinishLength FinishReason = "length" // token limit reached
+)
+
+// Request is a single generation request.
+type Request struct {
+	Prompt    string
+	MaxTokens int       // 0 means the backend default
+	Stop      []string  // sequences that end generation when produced
+	Sampling  *Sampling // nil means the backend defaults
+}
+
+// Sampling controls how each next token is picked.
+type Sampling struct {
+	Temperature       float64 // 0 is greedy
+	TopP              float64
+	TopK              int // 0 disables top-k filtering
+	RepetitionPenalty float64
+	Seed              int64 // negative picks a random seed
+}
+
+// Result summarises a finished generation.
+type Result struct {
+	Text             string
+	FinishReason     FinishReason
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// ModelInfo describes a model a backend can serve.
+type ModelInfo struct {
+	ID      string
+	OwnedBy string
+}
+
+// Backend is an inference engine.
+type Backend interface {
+	// Generate runs req to completion and returns the whole reply.
+	Generate(ctx context.Context, req Request) (*Result, error)
+	// Stream runs req and calls emit with each token as it is produced.
+	// The returned Result carries the full text and usage.
+	Stream(ctx context.Context, req Request, emit func(token string) error) (*Result, error)
+	// Tokenize returns the token ids for text.
+	Tokenize(ctx context.Context, text string) ([]int, error)
+	// ListModels returns the models this backend can serve.
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
@@ -0,0 +1,92 @@
+package backend
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// DefaultEchoReply is what the echo backend answers when no reply is set.
+const DefaultEchoReply = "🤖 This is a canned AI response."
+
+// Echo is a backend that answers every prompt with a fixed reply. It needs
+// no model and is what the server runs with by default and in tests.
+type Echo struct {
+	Reply string
+}
+
+// NewEcho returns an echo backend using DefaultEchoReply.
+func NewEcho() *Echo {
+	return &Echo{Reply: DefaultEchoReply}
+}
+
+// Generate implements Backend.
+func (e *Echo) Generate(ctx context.Context, req Request) (*Result, error) {
+	return e.Stream(ctx, req, func(string) error { return nil })
+}
+
+// Stream implements Backend, emitting the reply one word at a time.
+func (e *Echo) Stream(ctx context.Context, req Request, emit func(token string) error) (*Result, error) {
+	res := &Result{
+		FinishReason: FinishStop,
+		PromptTokens: len(splitWords(req.Prompt)),
+	}
+	var b strings.Builder
+	for _, tok := range splitWords(e.Reply) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if req.MaxTokens > 0 && res.CompletionTokens == req.MaxTokens {
+			res.FinishReason = FinishLength
+			break
+		}
+		if err := emit(tok); err != nil {
+			return nil, err
+		}
+		b.WriteString(tok)
+		res.CompletionTokens++
+	}
+	res.Text = b.String()
+	return res, nil
+}
+
+// Tokenize implements Backend. Each word is one token whose id is a hash
+// of its text.
+func (e *Echo) Tokenize(_ context.Context, text string) ([]int, error) {
+	words := splitWords(text)
+	ids := make([]int, len(words))
+	for i, w := range words {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		ids[i] = int(h.Sum32() & 0x7fffffff)
+	}
+	return ids, nil
+}
+
+// ListModels implements Backend.
+func (e *Echo) ListModels(context.Context) ([]ModelInfo, error) {
+	return []ModelInfo{{ID: "echo", OwnedBy: "llm-test"}}, nil
+}
+
+// splitWords splits text into word-sized tokens, keeping the trailing
+// whitespace on each so that concatenating them restores the input.
+func splitWords(text string) []string {
+	var out []string
+	start, inSpace := 0, false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			inSpace = true
+			continue
+		}
+		if inSpace && strings.TrimSpace(text[start:i]) != "" {
+			out = append(out, text[start:i])
+			start = i
+		}
+		inSpace = false
+	}
+	if strings.TrimSpace(text[start:]) != "" {
+		out = append(out, text[start:])
+	}
+	return out
+}
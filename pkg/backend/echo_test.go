@@ -0,0 +1,65 @@
+package backend_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+)
+
+func TestEchoStream(t *testing.T) {
+	e := &backend.Echo{Reply: "one two  three"}
+	var toks []string
+	res, err := e.Stream(context.Background(), backend.Request{Prompt: "hi there"}, func(tok string) error {
+		toks = append(toks, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream(): unexpected error: %v", err)
+	}
+	if got, want := strings.Join(toks, ""), "one two  three"; got != want {
+		t.Errorf("tokens joined = %q; want %q", got, want)
+	}
+	if got, want := len(toks), 3; got != want {
+		t.Errorf("got %d tokens; want %d", got, want)
+	}
+	if res.Text != "one two  three" || res.FinishReason != backend.FinishStop {
+		t.Errorf("Result = %+v", res)
+	}
+	if res.PromptTokens != 2 || res.CompletionTokens != 3 {
+		t.Errorf("usage = %d/%d; want 2/3", res.PromptTokens, res.CompletionTokens)
+	}
+}
+
+func TestEchoMaxTokens(t *testing.T) {
+	e := &backend.Echo{Reply: "one two three"}
+	res, err := e.Generate(context.Background(), backend.Request{MaxTokens: 2})
+	if err != nil {
+		t.Fatalf("Generate(): unexpected error: %v", err)
+	}
+	if res.Text != "one two " || res.FinishReason != backend.FinishLength {
+		t.Errorf("Result = %+v; want truncated with length finish", res)
+	}
+}
+
+func TestEchoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := backend.NewEcho().Generate(ctx, backend.Request{}); err != context.Canceled {
+		t.Errorf("Generate() error = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestEchoTokenize(t *testing.T) {
+	ids, err := backend.NewEcho().Tokenize(context.Background(), "a b a")
+	if err != nil {
+		t.Fatalf("Tokenize(): unexpected error: %v", err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("got %d ids; want 3", len(ids))
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("distinct words got the same id %d", ids[0])
+	}
+}
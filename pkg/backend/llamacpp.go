@@ -0,0 +1,344 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// LlamaCPPConfig configures a backend that talks to llama.cpp's
+// llama-server over its HTTP API.
+type LlamaCPPConfig struct {
+	// URL of the server, e.g. "http://127.0.0.1:8080".
+	URL string
+	// Binary is the llama-server executable to launch on URL's host and
+	// port. Leave it empty to use a server that is already running.
+	Binary string
+	// ModelPath is passed to a launched server with -m.
+	ModelPath string
+	// Args are extra command line arguments for a launched server.
+	Args []string
+	// HTTPClient is used for all requests; http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// LlamaCPP is a Backend driving a llama-server process.
+type LlamaCPP struct {
+	cfg    LlamaCPPConfig
+	client *http.Client
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// NewLlamaCPP returns a backend for the server described by cfg. Call
+// Start before use if cfg.Binary is set.
+func NewLlamaCPP(cfg LlamaCPPConfig) *LlamaCPP {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	c := cfg.HTTPClient
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &LlamaCPP{cfg: cfg, client: c}
+}
+
+// Start launches llama-server if a binary is configured, then waits until
+// the server reports healthy or ctx expires.
+func (l *LlamaCPP) Start(ctx context.Context) error {
+	if l.cfg.Binary != "" {
+		u, err := url.Parse(l.cfg.URL)
+		if err != nil {
+			return fmt.Errorf("llama-server url: %w", err)
+		}
+		args := []string{"--host", u.Hostname(), "--port", u.Port()}
+		if l.cfg.ModelPath != "" {
+			args = append(args, "-m", l.cfg.ModelPath)
+		}
+		args = append(args, l.cfg.Args...)
+		l.cmd = exec.Command(l.cfg.Binary, args...)
+		if err := l.cmd.Start(); err != nil {
+			return fmt.Errorf("start llama-server: %w", err)
+		}
+		l.exited = make(chan struct{})
+		go func() {
+			l.cmd.Wait()
+			close(l.exited)
+		}()
+	}
+	return l.waitHealthy(ctx)
+}
+
+// waitHealthy polls /health until it returns 200.
+func (l *LlamaCPP) waitHealthy(ctx context.Context) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.cfg.URL+"/health", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := l.client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: llama-server not healthy: %v", ErrUnavailable, ctx.Err())
+		case <-l.exited:
+			return fmt.Errorf("%w: llama-server exited during startup", ErrUnavailable)
+		case <-ticker.C:
+		}
+	}
+}
+
+// LlamaCPPOpener returns an Opener that launches one llama-server per
+// model on a free local port. modelPath resolves a model name to its file.
+func LlamaCPPOpener(binary string, args []string, modelPath func(model string) (string, error)) Opener {
+	return func(ctx context.Context, model string) (Backend, error) {
+		path, err := modelPath(model)
+		if err != nil {
+			return nil, err
+		}
+		port, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+		l := NewLlamaCPP(LlamaCPPConfig{
+			URL:       fmt.Sprintf("http://127.0.0.1:%d", port),
+			Binary:    binary,
+			ModelPath: path,
+			Args:      args,
+		})
+		if err := l.Start(ctx); err != nil {
+			l.Close()
+			return nil, err
+		}
+		return l, nil
+	}
+}
+
+// freePort asks the kernel for an unused local TCP port
+func freePort() (int, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Close stops a launched llama-server. It is a no-op otherwise.
+func (l *LlamaCPP) Close() error {
+	if l.cmd == nil || l.cmd.Process == nil {
+		return nil
+	}
+	if err := l.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-l.exited
+	return nil
+}
+
+// completionRequest is the body of POST /completion.
+type completionRequest struct {
+	Prompt      string   `json:"prompt"`
+	NPredict    int      `json:"n_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Stream      bool     `json:"stream"`
+	CachePrompt bool     `json:"cache_prompt"`
+
+	// sampling, left out to keep the server's defaults
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	Seed          *int64   `json:"seed,omitempty"`
+}
+
+// completionResponse is a /completion reply or one streamed event.
+type completionResponse struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	StoppedLimit    bool   `json:"stopped_limit"`
+	TokensPredicted int    `json:"tokens_predicted"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+}
+
+func (r *completionResponse) result(text string) *Result {
+	res := &Result{
+		Text:             text,
+		FinishReason:     FinishStop,
+		PromptTokens:     r.TokensEvaluated,
+		CompletionTokens: r.TokensPredicted,
+	}
+	if r.StoppedLimit {
+		res.FinishReason = FinishLength
+	}
+	return res
+}
+
+// Generate implements Backend.
+func (l *LlamaCPP) Generate(ctx context.Context, req Request) (*Result, error) {
+	var out completionResponse
+	if err := l.postJSON(ctx, "/completion", l.completion(req, false), &out); err != nil {
+		return nil, err
+	}
+	return out.result(out.Content), nil
+}
+
+// Stream implements Backend using llama-server's server-sent events.
+func (l *LlamaCPP) Stream(ctx context.Context, req Request, emit func(token string) error) (*Result, error) {
+	resp, err := l.post(ctx, "/completion", l.completion(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev completionResponse
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil, fmt.Errorf("decode llama-server event: %w", err)
+		}
+		if ev.Content != "" {
+			if err := emit(ev.Content); err != nil {
+				return nil, err
+			}
+			text.WriteString(ev.Content)
+		}
+		if ev.Stop {
+			return ev.result(text.String()), nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read llama-server stream: %w", err)
+	}
+	return nil, fmt.Errorf("llama-server stream ended without a final event")
+}
+
+func (l *LlamaCPP) completion(req Request, stream bool) completionRequest {
+	c := completionRequest{
+		Prompt:      req.Prompt,
+		NPredict:    req.MaxTokens,
+		Stop:        req.Stop,
+		Stream:      stream,
+		CachePrompt: true,
+	}
+	if s := req.Sampling; s != nil {
+		c.Temperature = &s.Temperature
+		c.TopP = &s.TopP
+		c.TopK = &s.TopK
+		c.RepeatPenalty = &s.RepetitionPenalty
+		if s.Seed >= 0 {
+			c.Seed = &s.Seed
+		}
+	}
+	return c
+}
+
+// Tokenize implements Backend.
+func (l *LlamaCPP) Tokenize(ctx context.Context, text string) ([]int, error) {
+	var out struct {
+		Tokens []int `json:"tokens"`
+	}
+	if err := l.postJSON(ctx, "/tokenize", map[string]string{"content": text}, &out); err != nil {
+		return nil, err
+	}
+	return out.Tokens, nil
+}
+
+// ListModels implements Backend using the OpenAI-compatible model list.
+func (l *LlamaCPP) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.cfg.URL+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode llama-server models: %w", err)
+	}
+	models := make([]ModelInfo, len(out.Data))
+	for i, d := range out.Data {
+		models[i] = ModelInfo{ID: d.ID, OwnedBy: d.OwnedBy}
+	}
+	return models, nil
+}
+
+// postJSON posts body to path and decodes the JSON reply into out.
+func (l *LlamaCPP) postJSON(ctx context.Context, path string, body, out any) error {
+	resp, err := l.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode llama-server %s: %w", path, err)
+	}
+	return nil
+}
+
+// post sends body as JSON to path and returns a 200 response.
+func (l *LlamaCPP) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.cfg.URL+path, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return l.do(ctx, req)
+}
+
+// do sends req, mapping transport failures to ErrUnavailable and non-200
+// replies to errors carrying the server's message.
+func (l *LlamaCPP) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := l.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: llama-server is loading", ErrUnavailable)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("llama-server %s returned %s: %s", req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
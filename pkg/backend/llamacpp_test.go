@@ -0,0 +1,155 @@
+package backend_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+)
+
+// fakeLlamaServer mimics the llama-server endpoints the backend uses.
+func fakeLlamaServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"ok"}`)
+	})
+	mux.HandleFunc("POST /completion", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Prompt   string `json:"prompt"`
+			NPredict int    `json:"n_predict"`
+			Stream   bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limited := req.NPredict == 2
+		if !req.Stream {
+			json.NewEncoder(w).Encode(map[string]any{
+				"content": "Hello world", "stop": true, "stopped_limit": limited,
+				"tokens_predicted": 2, "tokens_evaluated": 4,
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range []string{"Hello", " world"} {
+			fmt.Fprintf(w, "data: {\"content\":%q,\"stop\":false}\n\n", c)
+		}
+		fmt.Fprintf(w, "data: {\"content\":\"\",\"stop\":true,\"stopped_limit\":%t,\"tokens_predicted\":2,\"tokens_evaluated\":4}\n\n", limited)
+	})
+	mux.HandleFunc("POST /tokenize", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tokens":[15043,3186]}`)
+	})
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"tiny.gguf","object":"model","owned_by":"llamacpp"}]}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newLlama(t *testing.T) *backend.LlamaCPP {
+	t.Helper()
+	srv := fakeLlamaServer(t)
+	l := backend.NewLlamaCPP(backend.LlamaCPPConfig{URL: srv.URL})
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("Start(): unexpected error: %v", err)
+	}
+	return l
+}
+
+func TestLlamaCPPGenerate(t *testing.T) {
+	l := newLlama(t)
+	res, err := l.Generate(context.Background(), backend.Request{Prompt: "Say hello"})
+	if err != nil {
+		t.Fatalf("Generate(): unexpected error: %v", err)
+	}
+	want := backend.Result{Text: "Hello world", FinishReason: backend.FinishStop, PromptTokens: 4, CompletionTokens: 2}
+	if *res != want {
+		t.Errorf("Result = %+v; want %+v", *res, want)
+	}
+}
+
+func TestLlamaCPPStream(t *testing.T) {
+	l := newLlama(t)
+	var toks []string
+	res, err := l.Stream(context.Background(), backend.Request{Prompt: "Say hello", MaxTokens: 2}, func(tok string) error {
+		toks = append(toks, tok)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream(): unexpected error: %v", err)
+	}
+	if len(toks) != 2 || toks[0] != "Hello" || toks[1] != " world" {
+		t.Errorf("tokens = %q", toks)
+	}
+	if res.Text != "Hello world" || res.FinishReason != backend.FinishLength {
+		t.Errorf("Result = %+v; want full text with length finish", res)
+	}
+}
+
+func TestLlamaCPPSampling(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"content":"ok","stop":true}`)
+	}))
+	defer srv.Close()
+	l := backend.NewLlamaCPP(backend.LlamaCPPConfig{URL: srv.URL})
+
+	req := backend.Request{Prompt: "hi", Sampling: &backend.Sampling{Temperature: 0, TopP: 0.5, TopK: 10, RepetitionPenalty: 1.2, Seed: 7}}
+	if _, err := l.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate(): unexpected error: %v", err)
+	}
+	want := map[string]float64{"temperature": 0, "top_p": 0.5, "top_k": 10, "repeat_penalty": 1.2, "seed": 7}
+	for k, v := range want {
+		if f, ok := got[k].(float64); !ok || f != v {
+			t.Errorf("%s = %v; want %v", k, got[k], v)
+		}
+	}
+
+	got = nil
+	req.Sampling.Seed = -1
+	if _, err := l.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate(): unexpected error: %v", err)
+	}
+	if _, ok := got["seed"]; ok {
+		t.Errorf("random seed sent as %v; want it left out", got["seed"])
+	}
+}
+
+func TestLlamaCPPTokenizeAndModels(t *testing.T) {
+	l := newLlama(t)
+	ids, err := l.Tokenize(context.Background(), "Hello world")
+	if err != nil {
+		t.Fatalf("Tokenize(): unexpected error: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 15043 {
+		t.Errorf("ids = %v", ids)
+	}
+	models, err := l.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels(): unexpected error: %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "tiny.gguf" {
+		t.Errorf("models = %+v", models)
+	}
+}
+
+func TestLlamaCPPUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	l := backend.NewLlamaCPP(backend.LlamaCPPConfig{URL: url})
+	_, err := l.Generate(context.Background(), backend.Request{Prompt: "hi"})
+	if !errors.Is(err, backend.ErrUnavailable) {
+		t.Errorf("Generate() error = %v; want ErrUnavailable", err)
+	}
+}
@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrPoolFull is returned when a model must be loaded but every resident
+// model is serving a request and none can be evicted
+var ErrPoolFull = errors.New("all loaded models are busy")
+
+// ErrNotLoaded is returned by Unload for models that are not resident
+var ErrNotLoaded = errors.New("model not loaded")
+
+// ErrModelBusy is returned by Unload for models serving requests
+var ErrModelBusy = errors.New("model is serving requests")
+
+// ErrPoolClosed is returned for models requested once the pool is closed
+var ErrPoolClosed = errors.New("model pool closed")
+
+// loadTimeout bounds how long loading a single model may take
+const loadTimeout = 5 * time.Minute
+
+// Opener starts a backend serving the named model
+type Opener func(ctx context.Context, model string) (Backend, error)
+
+// Resident describes a model currently loaded in a Pool
+type Resident struct {
+	Name     string
+	LoadedAt time.Time
+	LastUsed time.Time
+	InUse    int // requests currently using the model
+}
+
+// Pool keeps up to a fixed number of models loaded, opening them on
+// demand and evicting the least recently used idle model to make room.
+type Pool struct {
+	open Opener
+	max  int
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	lru     *list.List // of *poolEntry, most recently used at the front
+	closed  bool
+}
+
+type poolEntry struct {
+	name     string
+	backend  Backend
+	loadedAt time.Time
+	lastUsed time.Time
+	refs     int
+	elem     *list.Element
+
+	// closed once loading finishes; err is set if it failed
+	ready chan struct{}
+	err   error
+}
+
+// NewPool returns a pool that keeps at most max models resident
+func NewPool(max int, open Opener) *Pool {
+	if max < 1 {
+		max = 1
+	}
+	return &Pool{open: open, max: max, entries: map[string]*poolEntry{}, lru: list.New()}
+}
+
+// Acquire returns the backend for model, loading it first if needed.
+// The caller must call release once the request is done with it.
+func (p *Pool) Acquire(ctx context.Context, model string) (b Backend, release func(), err error) {
+	e, err := p.get(ctx, model, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return e.backend, func() { once.Do(func() { p.release(e) }) }, nil
+}
+
+// Load makes sure model is resident without using it
+func (p *Pool) Load(ctx context.Context, model string) error {
+	_, err := p.get(ctx, model, false)
+	return err
+}
+
+// get finds or loads model's entry, taking a reference if ref is set
+func (p *Pool) get(ctx context.Context, model string, ref bool) (*poolEntry, error) {
+	p.mu.Lock()
+	e, ok := p.entries[model]
+	if !ok {
+		var err error
+		if e, err = p.startLoad(model); err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+	}
+	if ref {
+		e.refs++
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		if ref {
+			p.release(e)
+		}
+		return nil, ctx.Err()
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	p.mu.Lock()
+	e.lastUsed = time.Now()
+	if e.elem != nil {
+		p.lru.MoveToFront(e.elem)
+	}
+	p.mu.Unlock()
+	return e, nil
+}
+
+// startLoad reserves a slot for model, evicting if necessary, and opens it
+// in the background; callers hold p.mu
+func (p *Pool) startLoad(model string) (*poolEntry, error) {
+	if p.closed {
+		return nil, fmt.Errorf("%w: cannot load %s", ErrPoolClosed, model)
+	}
+	if len(p.entries) >= p.max {
+		if !p.evictLocked() {
+			return nil, fmt.Errorf("%w: cannot load %s", ErrPoolFull, model)
+		}
+	}
+	e := &poolEntry{name: model, ready: make(chan struct{})}
+	p.entries[model] = e
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		b, err := p.open(ctx, model)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		switch {
+		case err != nil:
+			e.err = fmt.Errorf("load %s: %w", model, err)
+			delete(p.entries, model)
+		case p.closed:
+			// Close has already run and left this load to shut down
+			e.err = fmt.Errorf("%w: cannot load %s", ErrPoolClosed, model)
+			go closeBackend(b)
+		default:
+			e.backend = b
+			e.loadedAt = time.Now()
+			e.lastUsed = e.loadedAt
+			e.elem = p.lru.PushFront(e)
+		}
+		close(e.ready)
+	}()
+	return e, nil
+}
+
+// evictLocked closes the least recently used idle model; callers hold p.mu
+func (p *Pool) evictLocked() bool {
+	for el := p.lru.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(*poolEntry)
+		if e.refs > 0 {
+			continue
+		}
+		p.removeLocked(e)
+		return true
+	}
+	return false
+}
+
+// removeLocked drops e from the pool and closes its backend in the
+// background; callers hold p.mu
+func (p *Pool) removeLocked(e *poolEntry) {
+	delete(p.entries, e.name)
+	p.lru.Remove(e.elem)
+	e.elem = nil
+	go closeBackend(e.backend)
+}
+
+func (p *Pool) release(e *poolEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.refs--
+}
+
+// Unload closes model if it is resident and idle
+func (p *Pool) Unload(model string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[model]
+	if !ok || e.elem == nil {
+		return fmt.Errorf("%w: %s", ErrNotLoaded, model)
+	}
+	if e.refs > 0 {
+		return fmt.Errorf("%w: %s has %d running", ErrModelBusy, model, e.refs)
+	}
+	p.removeLocked(e)
+	return nil
+}
+
+// Resident lists the loaded models, most recently used first
+func (p *Pool) Resident() []Resident {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Resident, 0, p.lru.Len())
+	for el := p.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*poolEntry)
+		out = append(out, Resident{Name: e.name, LoadedAt: e.loadedAt, LastUsed: e.lastUsed, InUse: e.refs})
+	}
+	return out
+}
+
+// Close unloads every model. Models still loading are closed as soon as
+// they finish.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var backends []Backend
+	for el := p.lru.Front(); el != nil; el = el.Next() {
+		backends = append(backends, el.Value.(*poolEntry).backend)
+	}
+	p.entries = map[string]*poolEntry{}
+	p.lru.Init()
+	p.mu.Unlock()
+
+	var errs []error
+	for _, b := range backends {
+		errs = append(errs, closeBackend(b))
+	}
+	return errors.Join(errs...)
+}
+
+// closeBackend closes b if it holds resources
+func closeBackend(b Backend) error {
+	if c, ok := b.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
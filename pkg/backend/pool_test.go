@@ -0,0 +1,213 @@
+package backend_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+)
+
+// closingEcho records when the pool closes it
+type closingEcho struct {
+	backend.Echo
+	closed atomic.Bool
+}
+
+func (c *closingEcho) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// testOpener opens closingEchos, counting opens per model
+type testOpener struct {
+	mu     sync.Mutex
+	opened map[string]int
+	last   map[string]*closingEcho
+}
+
+func newTestOpener() *testOpener {
+	return &testOpener{opened: map[string]int{}, last: map[string]*closingEcho{}}
+}
+
+func (o *testOpener) open(_ context.Context, model string) (backend.Backend, error) {
+	if model == "broken" {
+		return nil, errors.New("bad weights")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.opened[model]++
+	b := &closingEcho{Echo: backend.Echo{Reply: model}}
+	o.last[model] = b
+	return b, nil
+}
+
+func residentNames(p *backend.Pool) []string {
+	var names []string
+	for _, r := range p.Resident() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestPoolEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	o := newTestOpener()
+	p := backend.NewPool(2, o.open)
+
+	for _, m := range []string{"a", "b", "a", "c"} {
+		_, release, err := p.Acquire(ctx, m)
+		if err != nil {
+			t.Fatalf("Acquire(%q): unexpected error: %v", m, err)
+		}
+		release()
+	}
+	if got := residentNames(p); len(got) != 2 || got[0] != "c" || got[1] != "a" {
+		t.Errorf("Resident() = %v; want [c a]", got)
+	}
+	if o.opened["a"] != 1 {
+		t.Errorf("model a opened %d times; want 1", o.opened["a"])
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close(): unexpected error: %v", err)
+	}
+	if !o.last["a"].closed.Load() || !o.last["c"].closed.Load() {
+		t.Errorf("Close() left resident models open")
+	}
+}
+
+func TestPoolFullWhenBusy(t *testing.T) {
+	ctx := context.Background()
+	p := backend.NewPool(1, newTestOpener().open)
+	defer p.Close()
+
+	b, release, err := p.Acquire(ctx, "a")
+	if err != nil {
+		t.Fatalf("Acquire(): unexpected error: %v", err)
+	}
+	res, err := b.Generate(ctx, backend.Request{})
+	if err != nil || res.Text != "a" {
+		t.Fatalf("Generate() = %v, %v; want reply from model a", res, err)
+	}
+	if _, _, err := p.Acquire(ctx, "b"); !errors.Is(err, backend.ErrPoolFull) {
+		t.Errorf("Acquire(b) error = %v; want %v", err, backend.ErrPoolFull)
+	}
+	if err := p.Unload("a"); err == nil {
+		t.Errorf("Unload() of a busy model succeeded")
+	}
+	release()
+	release() // releasing twice is harmless
+	if _, release, err := p.Acquire(ctx, "b"); err != nil {
+		t.Errorf("Acquire(b) after release: unexpected error: %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestPoolUnload(t *testing.T) {
+	ctx := context.Background()
+	p := backend.NewPool(2, newTestOpener().open)
+	defer p.Close()
+
+	if err := p.Load(ctx, "a"); err != nil {
+		t.Fatalf("Load(): unexpected error: %v", err)
+	}
+	if err := p.Unload("a"); err != nil {
+		t.Fatalf("Unload(): unexpected error: %v", err)
+	}
+	if err := p.Unload("a"); !errors.Is(err, backend.ErrNotLoaded) {
+		t.Errorf("second Unload() error = %v; want %v", err, backend.ErrNotLoaded)
+	}
+	if got := p.Resident(); len(got) != 0 {
+		t.Errorf("Resident() = %v; want none", got)
+	}
+
+	// a model serving a request stays until it is done
+	_, release, err := p.Acquire(ctx, "b")
+	if err != nil {
+		t.Fatalf("Acquire(): unexpected error: %v", err)
+	}
+	if err := p.Unload("b"); !errors.Is(err, backend.ErrModelBusy) {
+		t.Errorf("Unload() in use error = %v; want %v", err, backend.ErrModelBusy)
+	}
+	release()
+	if err := p.Unload("b"); err != nil {
+		t.Errorf("Unload() once released: unexpected error: %v", err)
+	}
+}
+
+func TestPoolLoadFailure(t *testing.T) {
+	p := backend.NewPool(1, newTestOpener().open)
+	defer p.Close()
+
+	if err := p.Load(context.Background(), "broken"); err == nil {
+		t.Fatal("Load(broken): expected error")
+	}
+	// the failed load must not hold the only slot
+	if err := p.Load(context.Background(), "a"); err != nil {
+		t.Errorf("Load(a): unexpected error: %v", err)
+	}
+}
+
+func TestPoolConcurrentAcquireLoadsOnce(t *testing.T) {
+	o := newTestOpener()
+	p := backend.NewPool(1, o.open)
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, release, err := p.Acquire(context.Background(), "a")
+			if err != nil {
+				t.Errorf("Acquire(): unexpected error: %v", err)
+				return
+			}
+			release()
+		}()
+	}
+	wg.Wait()
+	if o.opened["a"] != 1 {
+		t.Errorf("model opened %d times; want 1", o.opened["a"])
+	}
+}
+
+func TestPoolCloseWhileLoading(t *testing.T) {
+	b := &closingEcho{}
+	opening := make(chan struct{})
+	finish := make(chan struct{})
+	p := backend.NewPool(1, func(context.Context, string) (backend.Backend, error) {
+		close(opening)
+		<-finish
+		return b, nil
+	})
+
+	loaded := make(chan error, 1)
+	go func() { loaded <- p.Load(context.Background(), "a") }()
+	<-opening
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close(): unexpected error: %v", err)
+	}
+	close(finish)
+	if err := <-loaded; !errors.Is(err, backend.ErrPoolClosed) {
+		t.Errorf("Load() finishing after Close = %v; want %v", err, backend.ErrPoolClosed)
+	}
+	// the backend is closed in the background once its load returns
+	deadline := time.Now().Add(time.Second)
+	for !b.closed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("backend loaded after Close was never closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if names := residentNames(p); len(names) != 0 {
+		t.Errorf("resident after Close = %v; want none", names)
+	}
+	if err := p.Load(context.Background(), "b"); !errors.Is(err, backend.ErrPoolClosed) {
+		t.Errorf("Load() after Close = %v; want %v", err, backend.ErrPoolClosed)
+	}
+}
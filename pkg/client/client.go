@@ -2,6 +2,8 @@ package client
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"time"
 
@@ -10,7 +12,11 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/Billy-Davies-2/llm-test/pkg/cluster"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
 	proto "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	modelspb "github.com/Billy-Davies-2/llm-test/pkg/proto/models"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
 )
 
 // Metrics holds the domain-friendly view of MetricsResponse.
@@ -23,16 +29,23 @@ type Metrics struct {
 	GPUTempCelsius float64 // zero if no GPU
 }
 
-// Client wraps the gRPC stub.
+// Client wraps the gRPC stubs.
 type Client struct {
-	logger *slog.Logger
-	stub   proto.MetricsServiceClient
-	conn   *grpc.ClientConn
+	logger   *slog.Logger
+	stub     proto.MetricsServiceClient
+	chat     chatpb.ChatServiceClient
+	sessions chatpb.SessionServiceClient
+	models   modelspb.ModelServiceClient
+	conn     *grpc.ClientConn
 }
 
 // NewClient dials the server at addr (e.g. "host:50051").
 // It will retry for up to 5 seconds if the connection isn’t ready.
-func NewClient(ctx context.Context, addr string, logger *slog.Logger) (*Client, error) {
+// An addr of "llmcluster:///host:50051" dials every live backend of that
+// host's cluster instead; sessions stay on the backend that created them,
+// so only calls that need no session should go through such a client.
+// opts are added to the dial options, e.g. per-RPC credentials.
+func NewClient(ctx context.Context, addr string, logger *slog.Logger, opts ...grpc.DialOption) (*Client, error) {
 	logger.Debug("dialing metrics server", "addr", addr)
 	cp := grpc.ConnectParams{
 		Backoff: backoff.Config{
@@ -44,12 +57,17 @@ func NewClient(ctx context.Context, addr string, logger *slog.Logger) (*Client,
 		MinConnectTimeout: 5 * time.Second,
 	}
 
-	// grpc.NewClient is the new non-deprecated dialer
-	cc, err := grpc.NewClient(
-		addr,
+	dialOpts := append(tracing.DialOptions(),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithConnectParams(cp),
 	)
+	dialOpts = append(dialOpts, opts...)
+	// an llmcluster target asks its seeds for the members with the same
+	// credentials as every other call, since they check tokens too
+	resolvers := grpc.WithResolvers(cluster.NewBuilder(cluster.WithSeedDialOptions(dialOpts...), cluster.WithLogger(logger)))
+
+	// grpc.NewClient is the new non-deprecated dialer
+	cc, err := grpc.NewClient(addr, append(dialOpts, resolvers)...)
 	if err != nil {
 		logger.Error("failed to create grpc client", "err", err)
 		return nil, err
@@ -59,9 +77,12 @@ func NewClient(ctx context.Context, addr string, logger *slog.Logger) (*Client,
 	cc.Connect()
 
 	return &Client{
-		stub:   proto.NewMetricsServiceClient(cc),
-		conn:   cc,
-		logger: logger,
+		stub:     proto.NewMetricsServiceClient(cc),
+		chat:     chatpb.NewChatServiceClient(cc),
+		sessions: chatpb.NewSessionServiceClient(cc),
+		models:   modelspb.NewModelServiceClient(cc),
+		conn:     cc,
+		logger:   logger,
 	}, nil
 }
 
@@ -91,6 +112,86 @@ func (c *Client) FetchMetrics(ctx context.Context) (*Metrics, error) {
 	return m, nil
 }
 
+// StreamChat sends req over ChatStream and calls fn for every chunk as it
+// arrives. It returns nil once the server has sent its final chunk.
+func (c *Client) StreamChat(ctx context.Context, req *chatpb.ChatRequest, fn func(*chatpb.ChatChunk)) error {
+	stream, err := c.chat.ChatStream(ctx, req)
+	if err != nil {
+		c.logger.Warn("ChatStream RPC failed", "err", err)
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			c.logger.Warn("ChatStream recv failed", "err", err)
+			return err
+		}
+		fn(chunk)
+	}
+}
+
+// Cancel stops an in-flight chat request started with the given
+// request id.
+func (c *Client) Cancel(ctx context.Context, requestID string) error {
+	_, err := c.chat.Cancel(ctx, &chatpb.CancelRequest{RequestId: requestID})
+	return err
+}
+
+// CreateSession starts a new server-side chat session.
+func (c *Client) CreateSession(ctx context.Context, title string) (*chatpb.Session, error) {
+	return c.sessions.CreateSession(ctx, &chatpb.CreateSessionRequest{Title: title})
+}
+
+// ListSessions returns the caller's sessions without their messages.
+func (c *Client) ListSessions(ctx context.Context) ([]*chatpb.Session, error) {
+	resp, err := c.sessions.ListSessions(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.logger.Warn("ListSessions RPC failed", "err", err)
+		return nil, err
+	}
+	return resp.GetSessions(), nil
+}
+
+// GetSession fetches a session including its messages.
+func (c *Client) GetSession(ctx context.Context, id string) (*chatpb.Session, error) {
+	return c.sessions.GetSession(ctx, &chatpb.GetSessionRequest{Id: id})
+}
+
+// RenameSession changes a session's title.
+func (c *Client) RenameSession(ctx context.Context, id, title string) (*chatpb.Session, error) {
+	return c.sessions.RenameSession(ctx, &chatpb.RenameSessionRequest{Id: id, Title: title})
+}
+
+// DeleteSession removes a session and its messages.
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	_, err := c.sessions.DeleteSession(ctx, &chatpb.DeleteSessionRequest{Id: id})
+	return err
+}
+
+// ListModels returns the catalog of models available on the server's host.
+func (c *Client) ListModels(ctx context.Context) ([]*modelspb.ModelInfo, error) {
+	resp, err := c.models.ListModels(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.logger.Warn("ListModels RPC failed", "err", err)
+		return nil, err
+	}
+	return resp.GetModels(), nil
+}
+
+// ClusterState returns every live backend the server knows of through
+// gossip, itself included.
+func (c *Client) ClusterState(ctx context.Context) ([]*proto.NodeInfo, error) {
+	resp, err := c.stub.ClusterState(ctx, &emptypb.Empty{})
+	if err != nil {
+		c.logger.Warn("ClusterState RPC failed", "err", err)
+		return nil, err
+	}
+	return resp.GetNodes(), nil
+}
+
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
@@ -9,8 +9,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth/authtest"
 	"github.com/Billy-Davies-2/llm-test/pkg/client"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
 	proto "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/server"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -66,7 +70,7 @@ func TestFetchMetrics_Success(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	cli, err := client.NewClient(ctx, addr, &slog.Logger{})
+	cli, err := client.NewClient(ctx, addr, slog.New(slog.DiscardHandler))
 	if err != nil {
 		t.Fatalf("NewClient(): unexpected error: %v", err)
 	}
@@ -105,7 +109,7 @@ func TestFetchMetrics_ServerError(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	cli, err := client.NewClient(ctx, addr, &slog.Logger{})
+	cli, err := client.NewClient(ctx, addr, slog.New(slog.DiscardHandler))
 	if err != nil {
 		t.Fatalf("NewClient(): unexpected error: %v", err)
 	}
@@ -126,3 +130,88 @@ func TestFetchMetrics_ServerError(t *testing.T) {
 		t.Errorf("FetchMetrics() error message = %q; want %q", st.Message(), "server-side failure")
 	}
 }
+
+// chatServer streams a fixed reply one word at a time.
+type chatServer struct {
+	chatpb.UnimplementedChatServiceServer
+}
+
+func (s *chatServer) ChatStream(req *chatpb.ChatRequest, stream chatpb.ChatService_ChatStreamServer) error {
+	for _, d := range []string{"hello ", "from ", "test"} {
+		if err := stream.Send(&chatpb.ChatChunk{HostId: "test-host", Delta: d}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&chatpb.ChatChunk{
+		HostId:       "test-host",
+		FinishReason: chatpb.FinishReason_FINISH_REASON_STOP,
+		Usage:        &chatpb.Usage{PromptTokens: 1, CompletionTokens: 3, TotalTokens: 4},
+	})
+}
+
+func TestStreamChat(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listener error: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	chatpb.RegisterChatServiceServer(grpcServer, &chatServer{})
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cli, err := client.NewClient(ctx, lis.Addr().String(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewClient(): unexpected error: %v", err)
+	}
+	defer cli.Close()
+
+	var text string
+	var last *chatpb.ChatChunk
+	err = cli.StreamChat(ctx, &chatpb.ChatRequest{Text: "hi"}, func(c *chatpb.ChatChunk) {
+		text += c.GetDelta()
+		last = c
+	})
+	if err != nil {
+		t.Fatalf("StreamChat(): unexpected error: %v", err)
+	}
+	if got, want := text, "hello from test"; got != want {
+		t.Errorf("text = %q; want %q", got, want)
+	}
+	if got, want := last.GetFinishReason(), chatpb.FinishReason_FINISH_REASON_STOP; got != want {
+		t.Errorf("FinishReason = %v; want %v", got, want)
+	}
+	if got, want := last.GetUsage().GetTotalTokens(), uint32(4); got != want {
+		t.Errorf("TotalTokens = %d; want %d", got, want)
+	}
+}
+
+func TestNewClientClusterAuth(t *testing.T) {
+	iss := authtest.NewIssuer(t, "llm-client")
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listener error: %v", err)
+	}
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, server.WithAuth(iss.Provider(t), iss.ClientID))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	// the resolver's ClusterState lookup needs the token as much as the call
+	c, err := client.NewClient(context.Background(), "llmcluster:///"+lis.Addr().String(), slog.New(slog.DiscardHandler),
+		grpc.WithPerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, "ada"))))
+	if err != nil {
+		t.Fatalf("NewClient(): unexpected error: %v", err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m, err := c.FetchMetrics(ctx)
+	if err != nil {
+		t.Fatalf("FetchMetrics() through the cluster: unexpected error: %v", err)
+	}
+	if m.HostID != "test-host" {
+		t.Errorf("HostID = %q; want test-host", m.HostID)
+	}
+}
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/routing"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// BalancerName is the load balancing policy that sends each call to the
+// ready backend with the lowest load. The resolver selects it by default.
+const BalancerName = "llmcluster_least_loaded"
+
+func init() {
+	balancer.Register(balancerBuilder{})
+}
+
+type loadKey struct{}
+
+// withLoad attaches the load a backend last reported to its address
+func withLoad(a resolver.Address, m *metricspb.MetricsResponse) resolver.Address {
+	if m != nil {
+		a.BalancerAttributes = a.BalancerAttributes.WithValue(loadKey{}, m)
+	}
+	return a
+}
+
+func loadOf(a resolver.Address) *metricspb.MetricsResponse {
+	m, _ := a.BalancerAttributes.Value(loadKey{}).(*metricspb.MetricsResponse)
+	return m
+}
+
+// loadTable holds what the picker scores backends by. It outlives each
+// picker, as the base balancer only rebuilds pickers when connections
+// change state, not when the resolver reports new loads.
+type loadTable struct {
+	mu       sync.Mutex
+	load     map[string]*metricspb.MetricsResponse // by address
+	inflight map[string]int                        // calls this client has open
+}
+
+type balancerBuilder struct{}
+
+func (balancerBuilder) Name() string { return BalancerName }
+
+func (balancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	t := &loadTable{load: map[string]*metricspb.MetricsResponse{}, inflight: map[string]int{}}
+	b := base.NewBalancerBuilder(BalancerName, pickerBuilder{t}, base.Config{HealthCheck: true})
+	return &loadBalancer{Balancer: b.Build(cc, opts), loads: t}
+}
+
+// loadBalancer is a base balancer that also records the loads the
+// resolver reports
+type loadBalancer struct {
+	balancer.Balancer
+	loads *loadTable
+}
+
+func (b *loadBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	b.loads.mu.Lock()
+	clear(b.loads.load)
+	for _, a := range s.ResolverState.Addresses {
+		b.loads.load[a.Addr] = loadOf(a)
+	}
+	b.loads.mu.Unlock()
+	return b.Balancer.UpdateClientConnState(s)
+}
+
+type pickerBuilder struct{ loads *loadTable }
+
+func (pb pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{loads: pb.loads}
+	for sc, sci := range info.ReadySCs {
+		p.conns = append(p.conns, sc)
+		p.addrs = append(p.addrs, sci.Address.Addr)
+	}
+	return p
+}
+
+// picker sends each call to the ready backend with the lowest
+// routing.Candidate score, counting the calls this client already has
+// open there so a burst spreads out before the next load report
+type picker struct {
+	loads *loadTable
+	conns []balancer.SubConn
+	addrs []string
+}
+
+func (p *picker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	t := p.loads
+	t.mu.Lock()
+	best, bestScore, ties := 0, 0.0, 0
+	for i, addr := range p.addrs {
+		c := routing.Candidate{Addr: addr, Load: t.load[addr], Pending: t.inflight[addr]}
+		score := c.Score()
+		switch {
+		case i == 0 || score < bestScore:
+			best, bestScore, ties = i, score, 1
+		case score == bestScore:
+			// pick uniformly among equal scores
+			ties++
+			if rand.IntN(ties) == 0 {
+				best = i
+			}
+		}
+	}
+	addr := p.addrs[best]
+	t.inflight[addr]++
+	t.mu.Unlock()
+
+	return balancer.PickResult{
+		SubConn: p.conns[best],
+		Done: func(balancer.DoneInfo) {
+			t.mu.Lock()
+			t.inflight[addr]--
+			if t.inflight[addr] <= 0 {
+				delete(t.inflight, addr)
+			}
+			t.mu.Unlock()
+		},
+	}, nil
+}
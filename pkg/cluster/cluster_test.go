@@ -0,0 +1,145 @@
+package cluster_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/cluster"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// members is the cluster every fake backend reports
+type members struct {
+	mu    sync.Mutex
+	nodes []*metricspb.NodeInfo
+}
+
+func (m *members) set(nodes ...*metricspb.NodeInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes = nodes
+}
+
+type fakeBackend struct {
+	metricspb.UnimplementedMetricsServiceServer
+	hostID  string
+	members *members
+}
+
+func (f *fakeBackend) GetMetrics(context.Context, *emptypb.Empty) (*metricspb.MetricsResponse, error) {
+	return &metricspb.MetricsResponse{HostId: f.hostID}, nil
+}
+
+func (f *fakeBackend) ClusterState(context.Context, *emptypb.Empty) (*metricspb.ClusterStateResponse, error) {
+	f.members.mu.Lock()
+	defer f.members.mu.Unlock()
+	return &metricspb.ClusterStateResponse{HostId: f.hostID, Nodes: f.members.nodes}, nil
+}
+
+func startBackend(t *testing.T, hostID string, m *members) (addr string, stop func()) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	g := grpc.NewServer()
+	metricspb.RegisterMetricsServiceServer(g, &fakeBackend{hostID: hostID, members: m})
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	return lis.Addr().String(), g.Stop
+}
+
+func node(hostID, addr string, busy uint32) *metricspb.NodeInfo {
+	return &metricspb.NodeInfo{
+		HostId:   hostID,
+		ChatAddr: addr,
+		State:    metricspb.NodeState_NODE_STATE_ALIVE,
+		Load:     &metricspb.MetricsResponse{ActiveGenerations: busy, MaxConcurrentGenerations: 4},
+	}
+}
+
+// hostOf makes one call through conn and says which backend answered
+func hostOf(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	resp, err := metricspb.NewMetricsServiceClient(conn).GetMetrics(ctx, &emptypb.Empty{})
+	return resp.GetHostId(), err
+}
+
+func TestDialClusterPrefersIdleAndFailsOver(t *testing.T) {
+	m := &members{}
+	addrA, _ := startBackend(t, "a", m)
+	addrB, stopB := startBackend(t, "b", m)
+	addrC, _ := startBackend(t, "c", m)
+	m.set(node("a", addrA, 3), node("b", addrB, 0), node("c", addrC, 2))
+
+	conn, err := grpc.NewClient(cluster.Scheme+":///"+addrA,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithResolvers(cluster.NewBuilder(cluster.WithRefreshInterval(20*time.Millisecond))),
+	)
+	if err != nil {
+		t.Fatalf("NewClient(): unexpected error: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// wait for every backend to connect, then the idle one takes each call
+	waitFor(t, ctx, conn, func(host string) bool { return host == "b" })
+	for range 5 {
+		if host, err := hostOf(ctx, conn); err != nil || host != "b" {
+			t.Fatalf("call answered by %q (err %v); want the idle backend b", host, err)
+		}
+	}
+
+	// b goes away; its calls move to c, the next least loaded
+	stopB()
+	m.set(node("a", addrA, 3), node("c", addrC, 2))
+	waitFor(t, ctx, conn, func(host string) bool { return host == "c" })
+	for range 5 {
+		if host, err := hostOf(ctx, conn); err != nil || host != "c" {
+			t.Fatalf("call answered by %q (err %v); want c after b left", host, err)
+		}
+	}
+
+	// loads change and the balancer follows without reconnecting
+	m.set(node("a", addrA, 0), node("c", addrC, 2))
+	waitFor(t, ctx, conn, func(host string) bool { return host == "a" })
+}
+
+// waitFor calls through conn until a call is answered by a host ok accepts
+func waitFor(t *testing.T, ctx context.Context, conn *grpc.ClientConn, ok func(string) bool) {
+	t.Helper()
+	for {
+		host, err := hostOf(ctx, conn)
+		if err == nil && ok(host) {
+			return
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("gave up waiting; last call answered by %q (err %v)", host, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDialStandaloneBackend(t *testing.T) {
+	// a backend outside a gossip cluster lists itself with no address
+	m := &members{}
+	addr, _ := startBackend(t, "solo", m)
+	m.set(&metricspb.NodeInfo{HostId: "solo", State: metricspb.NodeState_NODE_STATE_ALIVE})
+
+	conn, err := grpc.NewClient(cluster.Scheme+":///"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient(): unexpected error: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if host, err := hostOf(ctx, conn); err != nil || host != "solo" {
+		t.Errorf("call answered by %q (err %v); want solo", host, err)
+	}
+}
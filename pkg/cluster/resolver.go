@@ -0,0 +1,279 @@
+// Package cluster lets a gRPC client dial the whole cluster of backends as
+// one target. A client dialing "llmcluster:///seed:50051" resolves to the
+// chat address of every live backend and spreads its calls over them by
+// load, dropping hosts as they fail or leave.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Scheme is the target scheme the resolver handles. The endpoint is a
+// comma-separated list of backends to ask for the cluster's members, e.g.
+// "llmcluster:///10.0.0.1:50051,10.0.0.2:50051".
+const Scheme = "llmcluster"
+
+// defaultRefresh is how often the members are looked up again, in step
+// with how often backends gossip their load
+const defaultRefresh = 2 * time.Second
+
+// ErrNoBackends is reported by the resolver when the cluster has no live
+// backend with a chat address
+var ErrNoBackends = errors.New("no live backends in the cluster")
+
+// serviceConfig makes connections through the resolver use the load
+// balancer unless the client asks for another
+var serviceConfig = `{"loadBalancingConfig":[{"` + BalancerName + `":{}}]}`
+
+func init() {
+	resolver.Register(NewBuilder())
+}
+
+// Builder resolves llmcluster targets. The one registered for the scheme
+// asks the seed backends in the target over MetricsService.ClusterState;
+// pass another to grpc.WithResolvers to change how members are found.
+type Builder struct {
+	node     *gossip.Node
+	refresh  time.Duration
+	logger   *slog.Logger
+	dialOpts []grpc.DialOption
+}
+
+// Option configures a Builder.
+type Option func(*Builder)
+
+// WithGossip reads the members from this host's own gossip view instead of
+// asking the seeds, for clients that run inside the cluster.
+func WithGossip(n *gossip.Node) Option {
+	return func(b *Builder) { b.node = n }
+}
+
+// WithRefreshInterval sets how often the members are looked up.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(b *Builder) { b.refresh = d }
+}
+
+// WithLogger sets the logger for lookup failures.
+func WithLogger(l *slog.Logger) Option {
+	return func(b *Builder) { b.logger = l }
+}
+
+// WithSeedDialOptions sets the options the resolver dials seeds with; by
+// default it uses plaintext like the rest of the cluster.
+func WithSeedDialOptions(opts ...grpc.DialOption) Option {
+	return func(b *Builder) { b.dialOpts = opts }
+}
+
+// NewBuilder returns a resolver builder for the llmcluster scheme.
+func NewBuilder(opts ...Option) *Builder {
+	b := &Builder{
+		refresh:  defaultRefresh,
+		logger:   slog.New(slog.DiscardHandler),
+		dialOpts: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Scheme implements resolver.Builder.
+func (b *Builder) Scheme() string { return Scheme }
+
+// Build implements resolver.Builder.
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	var seeds []string
+	for _, s := range strings.Split(target.Endpoint(), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			seeds = append(seeds, s)
+		}
+	}
+	if len(seeds) == 0 && b.node == nil {
+		return nil, errors.New("llmcluster target names no seed backends")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &clusterResolver{
+		b:       b,
+		cc:      cc,
+		seeds:   seeds,
+		sc:      cc.ParseServiceConfig(serviceConfig),
+		ctx:     ctx,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	go r.watch()
+	return r, nil
+}
+
+// clusterResolver keeps a ClientConn's addresses in line with the live
+// members of the cluster
+type clusterResolver struct {
+	b     *Builder
+	cc    resolver.ClientConn
+	seeds []string
+	sc    *serviceconfig.ParseResult
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	now     chan struct{}
+	stopped chan struct{}
+
+	// known are the chat addresses of the last lookup, tried before the
+	// seeds since the seeds may have gone
+	known []string
+	conn  *grpc.ClientConn // to the backend last asked for the members
+}
+
+// ResolveNow implements resolver.Resolver.
+func (r *clusterResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close implements resolver.Resolver.
+func (r *clusterResolver) Close() {
+	r.cancel()
+	<-r.stopped
+}
+
+func (r *clusterResolver) watch() {
+	defer close(r.stopped)
+	defer func() {
+		if r.conn != nil {
+			r.conn.Close()
+		}
+	}()
+	t := time.NewTicker(r.b.refresh)
+	defer t.Stop()
+	for {
+		r.update()
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.now:
+		case <-t.C:
+		}
+	}
+}
+
+// update looks up the members and hands their addresses to the ClientConn
+func (r *clusterResolver) update() {
+	nodes, err := r.lookup()
+	if err == nil {
+		var addrs []resolver.Address
+		for _, n := range nodes {
+			if n.GetState() != metricspb.NodeState_NODE_STATE_ALIVE || n.GetChatAddr() == "" {
+				continue
+			}
+			addrs = append(addrs, withLoad(resolver.Address{Addr: n.GetChatAddr()}, n.GetLoad()))
+		}
+		if len(addrs) == 0 {
+			err = ErrNoBackends
+		} else {
+			r.known = r.known[:0]
+			for _, a := range addrs {
+				r.known = append(r.known, a.Addr)
+			}
+			err = r.cc.UpdateState(resolver.State{Addresses: addrs, ServiceConfig: r.sc})
+			if err == nil {
+				return
+			}
+		}
+	}
+	if r.ctx.Err() == nil {
+		r.b.logger.Warn("cluster lookup failed", "err", err)
+		r.cc.ReportError(err)
+	}
+}
+
+// lookup returns the cluster's members from gossip, or else from the
+// first backend that answers ClusterState
+func (r *clusterResolver) lookup() ([]*metricspb.NodeInfo, error) {
+	if r.b.node != nil {
+		return gossipNodes(r.b.node), nil
+	}
+	var errs []error
+	if r.conn != nil {
+		nodes, err := r.clusterState()
+		if err == nil {
+			return nodes, nil
+		}
+		errs = append(errs, err)
+		r.conn.Close()
+		r.conn = nil
+	}
+	// spread the lookups over the cluster rather than always asking the
+	// first backend listed
+	addrs := append(append([]string(nil), r.known...), r.seeds...)
+	rand.Shuffle(len(r.known), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+	tried := map[string]bool{}
+	for _, addr := range addrs {
+		if tried[addr] {
+			continue
+		}
+		tried[addr] = true
+		conn, err := grpc.NewClient(addr, r.b.dialOpts...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.conn = conn
+		nodes, err := r.clusterState()
+		if err == nil {
+			return nodes, nil
+		}
+		errs = append(errs, err)
+		conn.Close()
+		r.conn = nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (r *clusterResolver) clusterState() ([]*metricspb.NodeInfo, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.b.refresh)
+	defer cancel()
+	resp, err := metricspb.NewMetricsServiceClient(r.conn).ClusterState(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range resp.GetNodes() {
+		// a backend outside a gossip cluster does not know its own
+		// address, but we just reached it on one
+		if n.GetHostId() == resp.GetHostId() && n.GetChatAddr() == "" {
+			n.ChatAddr = r.conn.Target()
+		}
+	}
+	return resp.GetNodes(), nil
+}
+
+// gossipNodes reads the metadata every live member of n gossips
+func gossipNodes(n *gossip.Node) []*metricspb.NodeInfo {
+	var nodes []*metricspb.NodeInfo
+	for _, m := range n.Members() {
+		info := &metricspb.NodeInfo{}
+		if m.State != gossip.StateAlive || proto.Unmarshal(m.Meta, info) != nil {
+			continue
+		}
+		info.HostId = m.Name
+		info.State = metricspb.NodeState_NODE_STATE_ALIVE
+		nodes = append(nodes, info)
+	}
+	return nodes
+}
@@ -0,0 +1,326 @@
+// Package gateway serves an OpenAI-compatible REST API on top of the
+// chat and model gRPC services, so tools written against OpenAI can talk
+// to the cluster unchanged.
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	modelspb "github.com/Billy-Davies-2/llm-test/pkg/proto/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// maxBodyBytes bounds request bodies
+const maxBodyBytes = 4 << 20
+
+// Gateway translates OpenAI REST calls into ChatService and ModelService
+// RPCs.
+type Gateway struct {
+	logger   *slog.Logger
+	chat     chatpb.ChatServiceClient
+	models   modelspb.ModelServiceClient
+	verifier *auth.Verifier
+}
+
+// New returns a gateway forwarding to the services on conn. Callers must
+// present a bearer token accepted by verifier; a nil verifier disables
+// authentication.
+func New(conn grpc.ClientConnInterface, verifier *auth.Verifier, logger *slog.Logger) *Gateway {
+	return &Gateway{
+		logger:   logger,
+		chat:     chatpb.NewChatServiceClient(conn),
+		models:   modelspb.NewModelServiceClient(conn),
+		verifier: verifier,
+	}
+}
+
+// Handler returns the gateway's HTTP routes.
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", g.chatCompletions)
+	mux.HandleFunc("POST /v1/completions", g.completions)
+	mux.HandleFunc("GET /v1/models", g.listModels)
+	return g.authenticate(mux)
+}
+
+// authenticate checks the caller's bearer token and forwards it to the
+// gRPC services, which see the same credentials the caller sent us.
+func (g *Gateway) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if g.verifier != nil {
+			if _, err := g.verifier.Verify(r.Context(), header); err != nil {
+				g.logger.Warn("rejected gateway request", "path", r.URL.Path, "err", err)
+				writeError(w, http.StatusUnauthorized, errorType(http.StatusUnauthorized), err.Error())
+				return
+			}
+		}
+		if header != "" {
+			r = r.WithContext(metadata.AppendToOutgoingContext(r.Context(), "authorization", header))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *Gateway) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var body chatCompletionRequest
+	if !decode(w, r, &body) {
+		return
+	}
+	req, err := body.chatRequest()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	created := time.Now().Unix()
+
+	if !body.Stream {
+		resp, err := g.chat.Chat(r.Context(), req)
+		if err != nil {
+			g.rpcError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, chatCompletion{
+			ID:      "chatcmpl-" + resp.GetRequestId(),
+			Object:  "chat.completion",
+			Created: created,
+			Model:   resp.GetModel(),
+			Choices: []chatChoice{{
+				Message:      &replyMessage{Role: "assistant", Content: resp.GetText()},
+				FinishReason: finishReason(resp.GetFinishReason()),
+			}},
+			Usage: openAIUsage(resp.GetUsage()),
+		})
+		return
+	}
+
+	first := true
+	g.stream(w, r, req, func(c *chatpb.ChatChunk) any {
+		delta := &replyMessage{Content: c.GetDelta()}
+		if first {
+			delta.Role = "assistant"
+			first = false
+		}
+		out := chatCompletion{
+			ID:      "chatcmpl-" + c.GetRequestId(),
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   c.GetModel(),
+			Choices: []chatChoice{{Delta: delta, FinishReason: finishReason(c.GetFinishReason())}},
+		}
+		if body.includeUsage() {
+			out.Usage = openAIUsage(c.GetUsage())
+		}
+		return out
+	})
+}
+
+func (g *Gateway) completions(w http.ResponseWriter, r *http.Request) {
+	var body completionRequest
+	if !decode(w, r, &body) {
+		return
+	}
+	req, err := body.chatRequest()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	created := time.Now().Unix()
+
+	if !body.Stream {
+		resp, err := g.chat.Chat(r.Context(), req)
+		if err != nil {
+			g.rpcError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, textCompletion{
+			ID:      "cmpl-" + resp.GetRequestId(),
+			Object:  "text_completion",
+			Created: created,
+			Model:   resp.GetModel(),
+			Choices: []textChoice{{Text: resp.GetText(), FinishReason: finishReason(resp.GetFinishReason())}},
+			Usage:   openAIUsage(resp.GetUsage()),
+		})
+		return
+	}
+
+	g.stream(w, r, req, func(c *chatpb.ChatChunk) any {
+		out := textCompletion{
+			ID:      "cmpl-" + c.GetRequestId(),
+			Object:  "text_completion",
+			Created: created,
+			Model:   c.GetModel(),
+			Choices: []textChoice{{Text: c.GetDelta(), FinishReason: finishReason(c.GetFinishReason())}},
+		}
+		if body.includeUsage() {
+			out.Usage = openAIUsage(c.GetUsage())
+		}
+		return out
+	})
+}
+
+// stream relays req's ChatStream as server-sent events, converting each
+// chunk with event and ending with OpenAI's [DONE] marker.
+func (g *Gateway) stream(w http.ResponseWriter, r *http.Request, req *chatpb.ChatRequest, event func(*chatpb.ChatChunk) any) {
+	stream, err := g.chat.ChatStream(r.Context(), req)
+	if err != nil {
+		g.rpcError(w, err)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	started := false
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !started {
+			// nothing sent yet, so a proper error status is still possible
+			g.rpcError(w, err)
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err != nil {
+			if r.Context().Err() != nil {
+				return // the client went away
+			}
+			g.logger.Warn("chat stream failed", "err", err)
+			st := status.Convert(err)
+			writeEvent(w, errorBody{Error: apiError{Message: st.Message(), Type: errorType(httpStatus(st.Code()))}})
+			break
+		}
+		if pos := chunk.GetQueuePosition(); pos > 0 {
+			// an SSE comment keeps the connection alive without
+			// confusing OpenAI clients
+			fmt.Fprintf(w, ": queue position %d\n\n", pos)
+		} else if err := writeEvent(w, event(chunk)); err != nil {
+			// returning cancels the RPC
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if !started {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (g *Gateway) listModels(w http.ResponseWriter, r *http.Request) {
+	resp, err := g.models.ListModels(r.Context(), &emptypb.Empty{})
+	if err != nil {
+		g.rpcError(w, err)
+		return
+	}
+	out := modelList{Object: "list", Data: []model{}}
+	for _, m := range resp.GetModels() {
+		out.Data = append(out.Data, model{
+			ID:      m.GetName(),
+			Object:  "model",
+			Created: m.GetModifiedAt().GetSeconds(),
+			OwnedBy: resp.GetHostId(),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// decode reads a JSON request body into v, answering 400 if it cannot
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeEvent(w http.ResponseWriter, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+	return err
+}
+
+func writeError(w http.ResponseWriter, code int, typ, msg string) {
+	writeJSON(w, code, errorBody{Error: apiError{Message: msg, Type: typ}})
+}
+
+// rpcError answers with the HTTP equivalent of a failed RPC
+func (g *Gateway) rpcError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := httpStatus(st.Code())
+	if code >= 500 {
+		g.logger.Error("gateway RPC failed", "code", st.Code(), "err", st.Message())
+	}
+	writeError(w, code, errorType(code), st.Message())
+}
+
+// httpStatus maps gRPC codes onto the statuses OpenAI clients expect
+func httpStatus(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499 // client closed request, as nginx reports it
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// errorType names the OpenAI error type for an HTTP status
+func errorType(code int) string {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return "authentication_error"
+	case code == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case code < 500:
+		return "invalid_request_error"
+	}
+	return "server_error"
+}
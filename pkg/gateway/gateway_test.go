@@ -0,0 +1,234 @@
+package gateway_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gateway"
+	"github.com/Billy-Davies-2/llm-test/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGateway serves the gateway over HTTP in front of a chat server
+// answering with reply.
+func newGateway(t *testing.T, reply string, verifier *auth.Verifier) *httptest.Server {
+	t.Helper()
+	logger := slog.New(slog.DiscardHandler)
+	srv := server.NewServer(logger, "test-host", 0, server.WithBackend(&backend.Echo{Reply: reply}))
+	lis := bufconn.Listen(1024 * 1024)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	hs := httptest.NewServer(gateway.New(conn, verifier, logger).Handler())
+	t.Cleanup(hs.Close)
+	return hs
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions(t *testing.T) {
+	hs := newGateway(t, "Hi there", nil)
+	resp := post(t, hs.URL+"/v1/chat/completions", `{
+		"model": "echo",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": [{"type": "text", "text": "Hello"}]}
+		],
+		"temperature": 0,
+		"seed": 1
+	}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var out struct {
+		Object  string
+		Model   string
+		Choices []struct {
+			Message      struct{ Role, Content string }
+			FinishReason string `json:"finish_reason"`
+		}
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.Object != "chat.completion" || out.Model != "echo" || len(out.Choices) != 1 {
+		t.Fatalf("response = %+v", out)
+	}
+	c := out.Choices[0]
+	if c.Message.Role != "assistant" || c.Message.Content != "Hi there" || c.FinishReason != "stop" {
+		t.Errorf("choice = %+v; want the assistant reply", c)
+	}
+	if out.Usage.TotalTokens == 0 {
+		t.Error("usage missing from response")
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	hs := newGateway(t, "one two three", nil)
+	resp := post(t, hs.URL+"/v1/chat/completions", `{
+		"messages": [{"role": "user", "content": "count"}],
+		"stream": true,
+		"stream_options": {"include_usage": true}
+	}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q; want text/event-stream", ct)
+	}
+
+	var text, finish string
+	done := false
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var ev struct {
+			Object  string
+			Choices []struct {
+				Delta        struct{ Content string }
+				FinishReason *string `json:"finish_reason"`
+			}
+		}
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatalf("decode event %q: %v", data, err)
+		}
+		if ev.Object != "chat.completion.chunk" {
+			t.Errorf("event object = %q", ev.Object)
+		}
+		text += ev.Choices[0].Delta.Content
+		if fr := ev.Choices[0].FinishReason; fr != nil {
+			finish = *fr
+		}
+	}
+	if !done {
+		t.Error("stream did not end with [DONE]")
+	}
+	if text != "one two three" || finish != "stop" {
+		t.Errorf("streamed %q (finish %q); want the whole reply", text, finish)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	hs := newGateway(t, "Paris", nil)
+	resp := post(t, hs.URL+"/v1/completions", `{"prompt": "The capital of France is", "max_tokens": 1, "stop": "\n", "seed": -7}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d; want 200", resp.StatusCode)
+	}
+	var out struct {
+		Object  string
+		Choices []struct{ Text string }
+		Usage   struct {
+			PromptTokens int `json:"prompt_tokens"`
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.Object != "text_completion" || len(out.Choices) != 1 || out.Choices[0].Text != "Paris" {
+		t.Errorf("response = %+v", out)
+	}
+	// the echo backend counts words, so a chat template would add some
+	if out.Usage.PromptTokens != 5 {
+		t.Errorf("prompt_tokens = %d; want 5 for the raw prompt", out.Usage.PromptTokens)
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	hs := newGateway(t, "x", nil)
+	for name, tc := range map[string]struct {
+		path, body string
+		want       int
+	}{
+		"malformed json":  {"/v1/chat/completions", `{`, http.StatusBadRequest},
+		"no messages":     {"/v1/chat/completions", `{"messages": []}`, http.StatusBadRequest},
+		"unknown role":    {"/v1/chat/completions", `{"messages": [{"role": "tool", "content": "x"}]}`, http.StatusBadRequest},
+		"bad temperature": {"/v1/chat/completions", `{"messages": [{"role": "user", "content": "x"}], "temperature": 9}`, http.StatusBadRequest},
+		"unknown model":   {"/v1/completions", `{"model": "gpt-4", "prompt": "x"}`, http.StatusNotFound},
+	} {
+		resp := post(t, hs.URL+tc.path, tc.body)
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status = %d; want %d", name, resp.StatusCode, tc.want)
+			continue
+		}
+		var out struct {
+			Error struct{ Message, Type string }
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Error.Message == "" {
+			t.Errorf("%s: error body missing (%v)", name, err)
+		}
+	}
+}
+
+func TestListModels(t *testing.T) {
+	hs := newGateway(t, "x", nil)
+	resp, err := http.Get(hs.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("GET /v1/models: %v", err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Object string
+		Data   []any
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if out.Object != "list" || out.Data == nil {
+		t.Errorf("response = %+v; want an empty list", out)
+	}
+}
+
+func TestGatewayRequiresToken(t *testing.T) {
+	hs := newGateway(t, "x", auth.NewVerifier(nil, "llm-client"))
+	resp := post(t, hs.URL+"/v1/chat/completions", `{"messages": [{"role": "user", "content": "x"}]}`)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without token = %d; want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", hs.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/models: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status with bad token = %d; want 401", resp2.StatusCode)
+	}
+}
@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+)
+
+// The OpenAI wire types below cover the fields the gateway understands.
+// Anything else in a request is accepted and ignored, as OpenAI clients
+// routinely send options a given server does not support.
+
+// samplingFields are shared by chat and text completion requests.
+// top_k and repetition_penalty are not OpenAI fields, but llama.cpp's
+// server accepts them too and several clients send them.
+type samplingFields struct {
+	Temperature       *float32  `json:"temperature"`
+	TopP              *float32  `json:"top_p"`
+	TopK              *uint32   `json:"top_k"`
+	MaxTokens         *uint32   `json:"max_tokens"`
+	MaxCompletion     *uint32   `json:"max_completion_tokens"`
+	RepetitionPenalty *float32  `json:"repetition_penalty"`
+	Seed              *int64    `json:"seed"`
+	Stop              stopField `json:"stop"`
+	N                 *int      `json:"n"`
+	Stream            bool      `json:"stream"`
+	StreamOptions     *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	samplingFields
+}
+
+type completionRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	samplingFields
+}
+
+type chatMessage struct {
+	Role    string         `json:"role"`
+	Content messageContent `json:"content"`
+}
+
+// messageContent is either a plain string or a list of content parts,
+// of which only text parts are supported
+type messageContent string
+
+func (c *messageContent) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*c = messageContent(s)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(b, &parts); err != nil {
+		return errors.New("content must be a string or a list of content parts")
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+		sb.WriteString(p.Text)
+	}
+	*c = messageContent(sb.String())
+	return nil
+}
+
+// stopField is a single stop string or a list of them
+type stopField []string
+
+func (s *stopField) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*s = stopField{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.New("stop must be a string or a list of strings")
+	}
+	*s = many
+	return nil
+}
+
+// roles maps OpenAI message roles onto ours; "developer" is the newer
+// name for "system".
+var roles = map[string]chatpb.Role{
+	"system":    chatpb.Role_ROLE_SYSTEM,
+	"developer": chatpb.Role_ROLE_SYSTEM,
+	"user":      chatpb.Role_ROLE_USER,
+	"assistant": chatpb.Role_ROLE_ASSISTANT,
+}
+
+// sampling converts the request's options, leaving validation and
+// defaults to the chat server
+func (f *samplingFields) sampling() (*chatpb.SamplingParams, error) {
+	if f.N != nil && *f.N != 1 {
+		return nil, errors.New("only n=1 is supported")
+	}
+	p := &chatpb.SamplingParams{
+		Temperature:       f.Temperature,
+		TopP:              f.TopP,
+		TopK:              f.TopK,
+		MaxTokens:         f.MaxTokens,
+		RepetitionPenalty: f.RepetitionPenalty,
+		Stop:              f.Stop,
+	}
+	if f.Seed != nil {
+		// OpenAI seeds are any integer; wrap them onto the backend's 32
+		// bits, as llama.cpp's server does with the seeds it is sent
+		seed := uint32(*f.Seed)
+		p.Seed = &seed
+	}
+	if f.MaxCompletion != nil {
+		p.MaxTokens = f.MaxCompletion
+	}
+	return p, nil
+}
+
+func (f *samplingFields) includeUsage() bool {
+	return f.StreamOptions != nil && f.StreamOptions.IncludeUsage
+}
+
+// chatRequest translates an OpenAI chat completion request
+func (r *chatCompletionRequest) chatRequest() (*chatpb.ChatRequest, error) {
+	if len(r.Messages) == 0 {
+		return nil, errors.New("messages must not be empty")
+	}
+	req := &chatpb.ChatRequest{Model: r.Model}
+	for i, m := range r.Messages {
+		role, ok := roles[m.Role]
+		if !ok {
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, m.Role)
+		}
+		req.Messages = append(req.Messages, &chatpb.ChatMessage{Role: role, Content: string(m.Content)})
+	}
+	var err error
+	req.Sampling, err = r.sampling()
+	return req, err
+}
+
+// chatRequest translates a text completion request. The prompt goes to
+// the model as is, without the chat template.
+func (r *completionRequest) chatRequest() (*chatpb.ChatRequest, error) {
+	if r.Prompt == "" {
+		return nil, errors.New("prompt must not be empty")
+	}
+	req := &chatpb.ChatRequest{Model: r.Model, Prompt: r.Prompt}
+	var err error
+	req.Sampling, err = r.sampling()
+	return req, err
+}
+
+// Response types
+
+type usage struct {
+	PromptTokens     uint32 `json:"prompt_tokens"`
+	CompletionTokens uint32 `json:"completion_tokens"`
+	TotalTokens      uint32 `json:"total_tokens"`
+}
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *usage       `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int           `json:"index"`
+	Message      *replyMessage `json:"message,omitempty"`
+	Delta        *replyMessage `json:"delta,omitempty"`
+	FinishReason *string       `json:"finish_reason"`
+}
+
+type replyMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+type textCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []textChoice `json:"choices"`
+	Usage   *usage       `json:"usage,omitempty"`
+}
+
+type textChoice struct {
+	Index        int     `json:"index"`
+	Text         string  `json:"text"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+type modelList struct {
+	Object string  `json:"object"`
+	Data   []model `json:"data"`
+}
+
+type model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type errorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Code    *string `json:"code"`
+}
+
+func openAIUsage(u *chatpb.Usage) *usage {
+	if u == nil {
+		return nil
+	}
+	return &usage{PromptTokens: u.GetPromptTokens(), CompletionTokens: u.GetCompletionTokens(), TotalTokens: u.GetTotalTokens()}
+}
+
+// finishReason names r the way OpenAI does, or nil while generating.
+// A cancelled reply is reported as stopped, OpenAI having no equivalent.
+func finishReason(r chatpb.FinishReason) *string {
+	var s string
+	switch r {
+	case chatpb.FinishReason_FINISH_REASON_UNSPECIFIED:
+		return nil
+	case chatpb.FinishReason_FINISH_REASON_LENGTH:
+		s = "length"
+	default:
+		s = "stop"
+	}
+	return &s
+}
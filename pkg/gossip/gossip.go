@@ -0,0 +1,693 @@
+// Package gossip maintains cluster membership with a SWIM-style protocol:
+// every node probes a random peer each round, asks others to probe it
+// indirectly when it does not answer, and spreads alive, suspect and dead
+// claims by piggybacking them on its messages. Nodes find each other
+// through a list of seed addresses, typically a Kubernetes headless
+// service.
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxMetaSize bounds the metadata a node may advertise, keeping a full
+// state sync of a large cluster within a few datagrams.
+const MaxMetaSize = 2048
+
+// ErrMetaTooLarge is returned by SetMeta for metadata over MaxMetaSize
+var ErrMetaTooLarge = errors.New("gossip: metadata too large")
+
+// Config tunes a Node. Zero durations and counts take the defaults noted.
+type Config struct {
+	Name          string   // unique node name, e.g. the host ID
+	BindAddr      string   // UDP address to listen on, e.g. ":7946"
+	AdvertiseAddr string   // address peers reach us at; guessed from the interfaces if empty
+	Seeds         []string // host:port of nodes to join through; hosts may resolve to many IPs
+
+	ProbeInterval    time.Duration // how often a peer is probed (1s)
+	ProbeTimeout     time.Duration // wait for a direct ack before asking others (400ms)
+	IndirectChecks   int           // peers asked to probe on our behalf (3)
+	SuspicionTimeout time.Duration // time a suspect has to refute (5s)
+	GossipInterval   time.Duration // how often pending updates are pushed (200ms)
+	Fanout           int           // peers each gossip round goes to (3)
+	SyncInterval     time.Duration // full state exchange, and seed retry while alone (30s)
+	ReapTimeout      time.Duration // how long dead members are remembered (1m)
+
+	// OnChange is called, in order and from a single goroutine, whenever
+	// a member's state changes.
+	OnChange func(Member)
+
+	Logger *slog.Logger
+}
+
+func (c *Config) setDefaults() {
+	def := func(d *time.Duration, v time.Duration) {
+		if *d <= 0 {
+			*d = v
+		}
+	}
+	def(&c.ProbeInterval, time.Second)
+	def(&c.ProbeTimeout, 400*time.Millisecond)
+	def(&c.SuspicionTimeout, 5*time.Second)
+	def(&c.GossipInterval, 200*time.Millisecond)
+	def(&c.SyncInterval, 30*time.Second)
+	def(&c.ReapTimeout, time.Minute)
+	if c.IndirectChecks <= 0 {
+		c.IndirectChecks = 3
+	}
+	if c.Fanout <= 0 {
+		c.Fanout = 3
+	}
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.DiscardHandler)
+	}
+}
+
+// Node is this process's membership in the cluster.
+type Node struct {
+	cfg    Config
+	logger *slog.Logger
+	conn   *net.UDPConn
+	addr   string // advertised
+
+	mu         sync.Mutex
+	members    map[string]*Member // including ourselves
+	broadcasts map[string]*broadcast
+	suspicions map[string]*time.Timer
+	acks       map[uint32]chan struct{}
+	seq        uint32
+	probeOrder []string
+	leaving    bool
+	events     []Member // waiting for OnChange
+	eventWake  chan struct{}
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// broadcast is an update still being spread
+type broadcast struct {
+	u         update
+	transmits int
+}
+
+// Start listens on cfg.BindAddr and begins joining the cluster through
+// cfg.Seeds in the background.
+func Start(cfg Config) (*Node, error) {
+	cfg.setDefaults()
+	if cfg.Name == "" {
+		return nil, errors.New("gossip: node name is required")
+	}
+	la, err := net.ResolveUDPAddr("udp", cfg.BindAddr)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: bind address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", la)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: listen: %w", err)
+	}
+	addr, err := advertiseAddr(cfg.AdvertiseAddr, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	n := &Node{
+		cfg:        cfg,
+		logger:     cfg.Logger.With("node", cfg.Name),
+		conn:       conn,
+		addr:       addr,
+		members:    map[string]*Member{},
+		broadcasts: map[string]*broadcast{},
+		suspicions: map[string]*time.Timer{},
+		acks:       map[uint32]chan struct{}{},
+		eventWake:  make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	self := &Member{Name: cfg.Name, Addr: addr, State: StateAlive, Since: time.Now()}
+	n.members[cfg.Name] = self
+	n.queueLocked(self.update())
+
+	n.logger.Info("gossip started", "addr", addr)
+	for _, loop := range []func(){n.receiveLoop, n.probeLoop, n.gossipLoop, n.syncLoop, n.eventLoop} {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			loop()
+		}()
+	}
+	return n, nil
+}
+
+// Name is this node's member name.
+func (n *Node) Name() string { return n.cfg.Name }
+
+// Addr is the gossip address this node advertises.
+func (n *Node) Addr() string { return n.addr }
+
+// Members returns the live members, this node included, sorted by name.
+func (n *Node) Members() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var out []Member
+	for _, m := range n.members {
+		if m.State.live() {
+			out = append(out, *m)
+		}
+	}
+	slices.SortFunc(out, func(a, b Member) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// SetMeta replaces the metadata this node advertises with its membership,
+// spreading it to peers. Metadata is opaque to gossip and limited to
+// MaxMetaSize bytes.
+func (n *Node) SetMeta(meta []byte) error {
+	if len(meta) > MaxMetaSize {
+		return fmt.Errorf("%w: %d bytes", ErrMetaTooLarge, len(meta))
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	self := n.members[n.cfg.Name]
+	if bytes.Equal(self.Meta, meta) || n.leaving {
+		return nil
+	}
+	// a new incarnation makes peers take the new metadata over the old
+	self.Meta = slices.Clone(meta)
+	self.Incarnation++
+	n.queueLocked(self.update())
+	return nil
+}
+
+// Leave tells the cluster this node is going away, so peers drop it at
+// once instead of waiting for it to fail probes. Shutdown should follow.
+func (n *Node) Leave(ctx context.Context) error {
+	n.mu.Lock()
+	n.leaving = true
+	self := n.members[n.cfg.Name]
+	self.Incarnation++
+	self.State = StateLeft
+	msg := &message{Type: msgGossip, From: n.cfg.Name, Updates: []update{self.update()}}
+	var peers []string
+	for _, m := range n.members {
+		if m.Name != n.cfg.Name && m.State.live() {
+			peers = append(peers, m.Addr)
+		}
+	}
+	n.mu.Unlock()
+
+	// tell everyone directly, a few times over in case packets drop
+	for range 3 {
+		for _, addr := range peers {
+			n.send(addr, msg)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(n.cfg.GossipInterval):
+		}
+	}
+	return nil
+}
+
+// Shutdown stops the node without notifying peers.
+func (n *Node) Shutdown() error {
+	select {
+	case <-n.done:
+		return nil
+	default:
+	}
+	close(n.done)
+	err := n.conn.Close()
+	n.wg.Wait()
+	n.mu.Lock()
+	for _, t := range n.suspicions {
+		t.Stop()
+	}
+	n.mu.Unlock()
+	return err
+}
+
+func (n *Node) stopped() bool {
+	select {
+	case <-n.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ── receiving ──────────────────────────────────────────────────────────
+
+func (n *Node) receiveLoop() {
+	buf := make([]byte, maxPacket)
+	for {
+		size, from, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if n.stopped() {
+				return
+			}
+			n.logger.Warn("gossip: read failed", "err", err)
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(buf[:size], &msg); err != nil {
+			n.logger.Debug("gossip: bad packet", "from", from, "err", err)
+			continue
+		}
+		n.handle(&msg, from)
+	}
+}
+
+func (n *Node) handle(msg *message, from *net.UDPAddr) {
+	n.mu.Lock()
+	for _, u := range msg.Updates {
+		n.mergeLocked(u)
+	}
+	n.mu.Unlock()
+
+	switch msg.Type {
+	case msgPing:
+		if msg.Target != n.cfg.Name {
+			return // meant for a previous occupant of this address
+		}
+		n.sendUDP(from, &message{Type: msgAck, Seq: msg.Seq, From: n.cfg.Name, Updates: n.piggyback()})
+
+	case msgPingReq:
+		seq, acked := n.expectAck()
+		n.send(msg.TargetAddr, &message{Type: msgPing, Seq: seq, From: n.cfg.Name, Target: msg.Target, Updates: n.piggyback()})
+		go func() {
+			defer n.forgetAck(seq)
+			select {
+			case <-acked:
+				n.sendUDP(from, &message{Type: msgAck, Seq: msg.Seq, From: n.cfg.Name})
+			case <-time.After(n.cfg.ProbeInterval):
+			case <-n.done:
+			}
+		}()
+
+	case msgAck:
+		n.mu.Lock()
+		if c, ok := n.acks[msg.Seq]; ok {
+			close(c)
+			delete(n.acks, msg.Seq)
+		}
+		n.mu.Unlock()
+
+	case msgSync:
+		n.sendUDP(from, &message{Type: msgSyncReply, From: n.cfg.Name, Updates: n.snapshot()})
+	}
+}
+
+// mergeLocked applies one claim, spreading it further if it was news.
+// Callers hold n.mu.
+func (n *Node) mergeLocked(u update) {
+	if u.Name == n.cfg.Name {
+		n.refuteLocked(u)
+		return
+	}
+	m, known := n.members[u.Name]
+	if known && !u.supersedes(m) {
+		return
+	}
+	if !known {
+		if u.State == StateSuspect {
+			// we cannot time out a suspicion for a member we never saw
+			// alive, so treat it as alive until we probe it ourselves
+			u.State = StateAlive
+		}
+		m = &Member{Name: u.Name}
+		n.members[u.Name] = m
+	}
+	changed := !known || m.State != u.State || m.Addr != u.Addr
+	m.Addr, m.Incarnation = u.Addr, u.Incarnation
+	if u.Meta != nil && !bytes.Equal(m.Meta, u.Meta) {
+		m.Meta, changed = u.Meta, true
+	}
+	if m.State != u.State {
+		m.State, m.Since = u.State, time.Now()
+	}
+	n.queueLocked(u)
+
+	if t, ok := n.suspicions[m.Name]; ok && m.State != StateSuspect {
+		t.Stop()
+		delete(n.suspicions, m.Name)
+	}
+	if m.State == StateSuspect {
+		n.startSuspicionLocked(m)
+	}
+	if changed {
+		n.logger.Info("gossip: member changed", "member", m.Name, "addr", m.Addr, "state", m.State, "incarnation", m.Incarnation)
+		n.emitLocked(*m)
+	}
+}
+
+// refuteLocked answers a claim about ourselves: anything but alive at our
+// current incarnation or above is contradicted with a higher one.
+func (n *Node) refuteLocked(u update) {
+	self := n.members[n.cfg.Name]
+	if n.leaving || u.Incarnation < self.Incarnation {
+		return
+	}
+	if u.State == StateAlive && u.Incarnation == self.Incarnation && u.Addr == self.Addr {
+		return
+	}
+	self.Incarnation = u.Incarnation + 1
+	n.logger.Info("gossip: refuting claim about ourselves", "state", u.State, "incarnation", self.Incarnation)
+	n.queueLocked(self.update())
+}
+
+// startSuspicionLocked declares m dead unless it refutes in time
+func (n *Node) startSuspicionLocked(m *Member) {
+	if _, ok := n.suspicions[m.Name]; ok {
+		return
+	}
+	name, inc := m.Name, m.Incarnation
+	n.suspicions[name] = time.AfterFunc(n.cfg.SuspicionTimeout, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.suspicions, name)
+		if m, ok := n.members[name]; ok && m.State == StateSuspect && m.Incarnation == inc {
+			u := m.update()
+			u.State = StateDead
+			n.mergeLocked(u)
+		}
+	})
+}
+
+// ── dissemination ─────────────────────────────────────────────────────
+
+// queueLocked schedules u to be spread, replacing older news about the
+// same member. Callers hold n.mu.
+func (n *Node) queueLocked(u update) {
+	n.broadcasts[u.Name] = &broadcast{u: u}
+}
+
+// piggyback picks the least-sent pending updates to attach to a message
+func (n *Node) piggyback() []update {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.broadcasts) == 0 {
+		return nil
+	}
+	pending := make([]*broadcast, 0, len(n.broadcasts))
+	for _, b := range n.broadcasts {
+		pending = append(pending, b)
+	}
+	slices.SortFunc(pending, func(a, b *broadcast) int { return a.transmits - b.transmits })
+	if len(pending) > maxPiggyback {
+		pending = pending[:maxPiggyback]
+	}
+	// each update is sent about mult*log(n) times, enough to reach every
+	// member with high probability
+	limit := 4 * int(math.Ceil(math.Log10(float64(len(n.members)+1))))
+	out := make([]update, 0, len(pending))
+	for _, b := range pending {
+		out = append(out, b.u)
+		if b.transmits++; b.transmits >= limit {
+			delete(n.broadcasts, b.u.Name)
+		}
+	}
+	return out
+}
+
+func (n *Node) gossipLoop() {
+	t := time.NewTicker(n.cfg.GossipInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-t.C:
+		}
+		n.mu.Lock()
+		pending := len(n.broadcasts) > 0
+		n.mu.Unlock()
+		if !pending {
+			continue
+		}
+		for _, m := range n.randomPeers(n.cfg.Fanout, "") {
+			if updates := n.piggyback(); len(updates) > 0 {
+				n.send(m.Addr, &message{Type: msgGossip, From: n.cfg.Name, Updates: updates})
+			}
+		}
+	}
+}
+
+// snapshot is every member we know of, for a full sync
+func (n *Node) snapshot() []update {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := make([]update, 0, len(n.members))
+	for _, m := range n.members {
+		out = append(out, m.update())
+	}
+	return out
+}
+
+// syncLoop joins through the seeds, retrying while we know nobody, and
+// periodically exchanges full state with a random peer to heal anything
+// gossip missed.
+func (n *Node) syncLoop() {
+	for {
+		if peers := n.randomPeers(1, ""); len(peers) == 0 {
+			n.joinSeeds()
+		} else {
+			n.send(peers[0].Addr, &message{Type: msgSync, From: n.cfg.Name, Updates: n.snapshot()})
+		}
+		wait := n.cfg.SyncInterval
+		if len(n.randomPeers(1, "")) == 0 {
+			// nobody yet: retry the seeds sooner
+			wait = min(wait, 2*n.cfg.ProbeInterval)
+		}
+		select {
+		case <-n.done:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// joinSeeds sends our state to every address the seeds resolve to
+func (n *Node) joinSeeds() {
+	msg := &message{Type: msgSync, From: n.cfg.Name, Updates: n.snapshot()}
+	for _, addr := range resolveSeeds(n.cfg.Seeds) {
+		if addr == n.addr || addr == n.conn.LocalAddr().String() {
+			continue
+		}
+		n.send(addr, msg)
+	}
+}
+
+// resolveSeeds expands seed host names into every address they resolve to
+func resolveSeeds(seeds []string) []string {
+	var out []string
+	for _, seed := range seeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			continue
+		}
+		ips, err := net.LookupHost(host)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			out = append(out, net.JoinHostPort(ip, port))
+		}
+	}
+	return out
+}
+
+// ── probing ───────────────────────────────────────────────────────────
+
+func (n *Node) probeLoop() {
+	t := time.NewTicker(n.cfg.ProbeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-t.C:
+		}
+		if m, ok := n.nextProbeTarget(); ok {
+			n.probe(m)
+		}
+		n.reap()
+	}
+}
+
+// nextProbeTarget walks the live peers in a shuffled round-robin, so each
+// is probed once per round
+func (n *Node) nextProbeTarget() (Member, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for {
+		if len(n.probeOrder) == 0 {
+			for name, m := range n.members {
+				if name != n.cfg.Name && m.State.live() {
+					n.probeOrder = append(n.probeOrder, name)
+				}
+			}
+			if len(n.probeOrder) == 0 {
+				return Member{}, false
+			}
+			rand.Shuffle(len(n.probeOrder), func(i, j int) {
+				n.probeOrder[i], n.probeOrder[j] = n.probeOrder[j], n.probeOrder[i]
+			})
+		}
+		name := n.probeOrder[0]
+		n.probeOrder = n.probeOrder[1:]
+		if m, ok := n.members[name]; ok && m.State.live() {
+			return *m, true
+		}
+	}
+}
+
+// probe pings m directly, then through other peers, and suspects it if
+// neither gets an answer within the probe interval
+func (n *Node) probe(m Member) {
+	seq, acked := n.expectAck()
+	defer n.forgetAck(seq)
+	n.send(m.Addr, &message{Type: msgPing, Seq: seq, From: n.cfg.Name, Target: m.Name, Updates: n.piggyback()})
+	select {
+	case <-acked:
+		return
+	case <-n.done:
+		return
+	case <-time.After(n.cfg.ProbeTimeout):
+	}
+
+	for _, helper := range n.randomPeers(n.cfg.IndirectChecks, m.Name) {
+		n.send(helper.Addr, &message{Type: msgPingReq, Seq: seq, From: n.cfg.Name, Target: m.Name, TargetAddr: m.Addr})
+	}
+	select {
+	case <-acked:
+		return
+	case <-n.done:
+		return
+	case <-time.After(n.cfg.ProbeInterval - n.cfg.ProbeTimeout):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if cur, ok := n.members[m.Name]; ok && cur.State == StateAlive && cur.Incarnation == m.Incarnation {
+		n.logger.Debug("gossip: probe failed", "member", m.Name)
+		u := cur.update()
+		u.State = StateSuspect
+		n.mergeLocked(u)
+	}
+}
+
+// expectAck allocates a sequence number and the channel closed when its
+// ack arrives
+func (n *Node) expectAck() (uint32, chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seq++
+	c := make(chan struct{})
+	n.acks[n.seq] = c
+	return n.seq, c
+}
+
+func (n *Node) forgetAck(seq uint32) {
+	n.mu.Lock()
+	delete(n.acks, seq)
+	n.mu.Unlock()
+}
+
+// randomPeers picks up to k live peers other than exclude
+func (n *Node) randomPeers(k int, exclude string) []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var peers []Member
+	for name, m := range n.members {
+		if name != n.cfg.Name && name != exclude && m.State.live() {
+			peers = append(peers, *m)
+		}
+	}
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > k {
+		peers = peers[:k]
+	}
+	return peers
+}
+
+// reap forgets members that have been dead for a while
+func (n *Node) reap() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for name, m := range n.members {
+		if !m.State.live() && name != n.cfg.Name && time.Since(m.Since) > n.cfg.ReapTimeout {
+			delete(n.members, name)
+		}
+	}
+}
+
+// ── events ────────────────────────────────────────────────────────────
+
+// emitLocked queues m for OnChange; callers hold n.mu
+func (n *Node) emitLocked(m Member) {
+	if n.cfg.OnChange == nil {
+		return
+	}
+	n.events = append(n.events, m)
+	select {
+	case n.eventWake <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Node) eventLoop() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-n.eventWake:
+		}
+		n.mu.Lock()
+		events := n.events
+		n.events = nil
+		n.mu.Unlock()
+		for _, m := range events {
+			n.cfg.OnChange(m)
+		}
+	}
+}
+
+// advertiseAddr picks the address peers should use to reach a node bound
+// to bound
+func advertiseAddr(configured string, bound *net.UDPAddr) (string, error) {
+	if configured != "" {
+		if _, _, err := net.SplitHostPort(configured); err != nil {
+			return "", fmt.Errorf("gossip: advertise address: %w", err)
+		}
+		return configured, nil
+	}
+	port := strconv.Itoa(bound.Port)
+	if !bound.IP.IsUnspecified() {
+		return net.JoinHostPort(bound.IP.String(), port), nil
+	}
+	ifaces, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", fmt.Errorf("gossip: list interfaces: %w", err)
+	}
+	for _, a := range ifaces {
+		if ipn, ok := a.(*net.IPNet); ok && !ipn.IP.IsLoopback() && ipn.IP.To4() != nil {
+			return net.JoinHostPort(ipn.IP.String(), port), nil
+		}
+	}
+	return net.JoinHostPort("127.0.0.1", port), nil
+}
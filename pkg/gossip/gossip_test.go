@@ -0,0 +1,175 @@
+package gossip_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+)
+
+// startNode starts a node on loopback with intervals short enough for
+// failures to be noticed within a test.
+func startNode(t *testing.T, name string, seeds ...string) *gossip.Node {
+	t.Helper()
+	n, err := gossip.Start(gossip.Config{
+		Name:             name,
+		BindAddr:         "127.0.0.1:0",
+		Seeds:            seeds,
+		ProbeInterval:    100 * time.Millisecond,
+		ProbeTimeout:     40 * time.Millisecond,
+		SuspicionTimeout: 300 * time.Millisecond,
+		GossipInterval:   20 * time.Millisecond,
+		SyncInterval:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Start(%q): unexpected error: %v", name, err)
+	}
+	t.Cleanup(func() { n.Shutdown() })
+	return n
+}
+
+func names(n *gossip.Node) []string {
+	var out []string
+	for _, m := range n.Members() {
+		out = append(out, m.Name)
+	}
+	return out
+}
+
+// eventually waits for every node to see exactly want as live members
+func eventually(t *testing.T, want []string, nodes ...*gossip.Node) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		done := true
+		for _, n := range nodes {
+			if !slices.Equal(names(n), want) {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			for _, n := range nodes {
+				t.Errorf("%s sees %v; want %v", n.Name(), names(n), want)
+			}
+			t.FailNow()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestJoinThroughSeed(t *testing.T) {
+	a := startNode(t, "a")
+	b := startNode(t, "b", a.Addr())
+	c := startNode(t, "c", a.Addr())
+	eventually(t, []string{"a", "b", "c"}, a, b, c)
+
+	for _, m := range c.Members() {
+		if m.State != gossip.StateAlive {
+			t.Errorf("member %s state = %v; want alive", m.Name, m.State)
+		}
+	}
+}
+
+func TestFailedMemberRemoved(t *testing.T) {
+	changes := make(chan gossip.Member, 32)
+	a, err := gossip.Start(gossip.Config{
+		Name:             "a",
+		BindAddr:         "127.0.0.1:0",
+		ProbeInterval:    100 * time.Millisecond,
+		ProbeTimeout:     40 * time.Millisecond,
+		SuspicionTimeout: 300 * time.Millisecond,
+		GossipInterval:   20 * time.Millisecond,
+		OnChange:         func(m gossip.Member) { changes <- m },
+	})
+	if err != nil {
+		t.Fatalf("Start(): unexpected error: %v", err)
+	}
+	t.Cleanup(func() { a.Shutdown() })
+	b := startNode(t, "b", a.Addr())
+	c := startNode(t, "c", a.Addr())
+	eventually(t, []string{"a", "b", "c"}, a, b, c)
+
+	// crash without telling anyone
+	c.Shutdown()
+	eventually(t, []string{"a", "b"}, a, b)
+
+	// the dead event may still be on its way once c has dropped out
+	var states []gossip.State
+	timeout := time.After(5 * time.Second)
+	for !slices.Contains(states, gossip.StateDead) {
+		select {
+		case m := <-changes:
+			if m.Name == "c" {
+				states = append(states, m.State)
+			}
+		case <-timeout:
+			t.Fatalf("changes for c = %v; never saw it dead", states)
+		}
+	}
+	want := []gossip.State{gossip.StateAlive, gossip.StateSuspect, gossip.StateDead}
+	if !slices.Equal(states, want) {
+		t.Errorf("changes for c = %v; want %v", states, want)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	a := startNode(t, "a")
+	b := startNode(t, "b", a.Addr())
+	c := startNode(t, "c", a.Addr())
+	eventually(t, []string{"a", "b", "c"}, a, b, c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Leave(ctx); err != nil {
+		t.Fatalf("Leave(): unexpected error: %v", err)
+	}
+	// Leave sends directly to every peer, so they should drop b without
+	// waiting for a probe to fail
+	for _, n := range []*gossip.Node{a, c} {
+		if got, want := names(n), []string{"a", "c"}; !slices.Equal(got, want) {
+			t.Errorf("%s sees %v after Leave; want %v", n.Name(), got, want)
+		}
+	}
+	b.Shutdown()
+	eventually(t, []string{"a", "c"}, a, c)
+}
+
+// memberMeta returns the meta n has for the member called name, or nil if
+// n does not know it
+func memberMeta(n *gossip.Node, name string) []byte {
+	for _, m := range n.Members() {
+		if m.Name == name {
+			return m.Meta
+		}
+	}
+	return nil
+}
+
+func TestMetaPropagates(t *testing.T) {
+	a := startNode(t, "a")
+	b := startNode(t, "b", a.Addr())
+	eventually(t, []string{"a", "b"}, a, b)
+
+	for _, meta := range []string{"v1", "v2"} {
+		if err := b.SetMeta([]byte(meta)); err != nil {
+			t.Fatalf("SetMeta(): unexpected error: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for got := memberMeta(a, "b"); string(got) != meta; got = memberMeta(a, "b") {
+			if time.Now().After(deadline) {
+				t.Fatalf("a sees b's meta = %q; want %q (members: %v)", got, meta, a.Members())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	if err := b.SetMeta(make([]byte, gossip.MaxMetaSize+1)); !errors.Is(err, gossip.ErrMetaTooLarge) {
+		t.Errorf("SetMeta() oversized error = %v; want %v", err, gossip.ErrMetaTooLarge)
+	}
+}
@@ -0,0 +1,82 @@
+package gossip
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is a member's liveness as this node believes it
+type State int
+
+const (
+	StateAlive   State = iota
+	StateSuspect       // missed a probe; declared dead unless it refutes in time
+	StateDead          // failed to refute a suspicion
+	StateLeft          // announced a graceful leave
+)
+
+func (s State) String() string {
+	switch s {
+	case StateAlive:
+		return "alive"
+	case StateSuspect:
+		return "suspect"
+	case StateDead:
+		return "dead"
+	case StateLeft:
+		return "left"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// live reports whether a member in state s should be used
+func (s State) live() bool { return s == StateAlive || s == StateSuspect }
+
+// Member is one node of the cluster
+type Member struct {
+	Name        string
+	Addr        string // gossip address, host:port
+	State       State
+	Incarnation uint64    // bumped by the member to refute suspicion
+	Since       time.Time // when this node saw State change
+	Meta        []byte    // set by the member with SetMeta
+}
+
+// update is the unit of dissemination: one claim about one member.
+// Claims with a higher incarnation win; at equal incarnations the more
+// severe state wins, except that only the member itself can move from
+// suspect back to alive, by raising its incarnation. Meta rides along so
+// that peers learning of a member late still get its metadata.
+type update struct {
+	Name        string `json:"name"`
+	Addr        string `json:"addr"`
+	State       State  `json:"state"`
+	Incarnation uint64 `json:"inc"`
+	Meta        []byte `json:"meta,omitempty"`
+}
+
+func (m *Member) update() update {
+	return update{Name: m.Name, Addr: m.Addr, State: m.State, Incarnation: m.Incarnation, Meta: m.Meta}
+}
+
+// supersedes reports whether claim u should replace what we know in m
+func (u update) supersedes(m *Member) bool {
+	switch {
+	case u.Incarnation > m.Incarnation:
+		return true
+	case u.Incarnation < m.Incarnation:
+		return false
+	}
+	// same incarnation: alive < suspect < dead/left
+	return severity(u.State) > severity(m.State)
+}
+
+func severity(s State) int {
+	switch s {
+	case StateAlive:
+		return 0
+	case StateSuspect:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,53 @@
+package gossip
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSupersedes(t *testing.T) {
+	m := &Member{Name: "x", State: StateSuspect, Incarnation: 3}
+	tests := []struct {
+		u    update
+		want bool
+	}{
+		{update{State: StateAlive, Incarnation: 3}, false},
+		{update{State: StateAlive, Incarnation: 4}, true},
+		{update{State: StateSuspect, Incarnation: 3}, false},
+		{update{State: StateDead, Incarnation: 3}, true},
+		{update{State: StateLeft, Incarnation: 3}, true},
+		{update{State: StateDead, Incarnation: 2}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.u.supersedes(m); got != tt.want {
+			t.Errorf("%v@%d supersedes suspect@3 = %v; want %v", tt.u.State, tt.u.Incarnation, got, tt.want)
+		}
+	}
+}
+
+func TestRefuteSuspicion(t *testing.T) {
+	n := &Node{
+		cfg:        Config{Name: "self"},
+		logger:     slog.New(slog.DiscardHandler),
+		members:    map[string]*Member{"self": {Name: "self", Addr: "10.0.0.1:7946", Incarnation: 2}},
+		broadcasts: map[string]*broadcast{},
+		suspicions: map[string]*time.Timer{},
+	}
+	n.mergeLocked(update{Name: "self", Addr: "10.0.0.1:7946", State: StateSuspect, Incarnation: 2})
+
+	self := n.members["self"]
+	if self.State != StateAlive || self.Incarnation != 3 {
+		t.Errorf("self after suspicion = %v@%d; want alive@3", self.State, self.Incarnation)
+	}
+	b, ok := n.broadcasts["self"]
+	if !ok || b.u.State != StateAlive || b.u.Incarnation != 3 {
+		t.Errorf("queued broadcast = %+v; want alive@3", b)
+	}
+
+	// stale claims are ignored
+	n.mergeLocked(update{Name: "self", Addr: "10.0.0.1:7946", State: StateDead, Incarnation: 1})
+	if self.Incarnation != 3 {
+		t.Errorf("incarnation after stale claim = %d; want 3", self.Incarnation)
+	}
+}
@@ -0,0 +1,59 @@
+package gossip
+
+import (
+	"encoding/json"
+	"net"
+)
+
+// maxPacket bounds datagrams; larger syncs are truncated by the sender
+const maxPacket = 64 * 1024
+
+// maxPiggyback is how many updates ride along on each message
+const maxPiggyback = 16
+
+type msgType string
+
+const (
+	msgPing      msgType = "ping"     // are you alive? answered with ack
+	msgPingReq   msgType = "ping-req" // please ping Target for me
+	msgAck       msgType = "ack"      // reply to ping, Seq echoed
+	msgGossip    msgType = "gossip"   // carries updates only
+	msgSync      msgType = "sync"     // full state exchange, answered with sync-reply
+	msgSyncReply msgType = "sync-reply"
+)
+
+// message is the single datagram format. Every message may carry
+// piggybacked updates.
+type message struct {
+	Type       msgType  `json:"t"`
+	Seq        uint32   `json:"seq,omitempty"`
+	From       string   `json:"from,omitempty"`
+	Target     string   `json:"target,omitempty"` // name the ping is meant for
+	TargetAddr string   `json:"target_addr,omitempty"`
+	Updates    []update `json:"updates,omitempty"`
+}
+
+// send writes msg to addr. Delivery is best effort, like UDP itself.
+func (n *Node) send(addr string, msg *message) {
+	ua, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		n.logger.Debug("gossip: bad address", "addr", addr, "err", err)
+		return
+	}
+	n.sendUDP(ua, msg)
+}
+
+func (n *Node) sendUDP(addr *net.UDPAddr, msg *message) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		n.logger.Error("gossip: encode message", "type", msg.Type, "err", err)
+		return
+	}
+	for len(b) > maxPacket && len(msg.Updates) > 1 {
+		msg.Updates = msg.Updates[:len(msg.Updates)/2]
+		b, _ = json.Marshal(msg)
+	}
+	if _, err := n.conn.WriteToUDP(b, addr); err != nil {
+		n.logger.Debug("gossip: send failed", "addr", addr, "type", msg.Type, "err", err)
+	}
+}
@@ -0,0 +1,314 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ggufMagic is "GGUF" read as a little-endian uint32
+const ggufMagic = 0x46554747
+
+// Limits that keep a corrupt header from triggering huge allocations
+const (
+	maxStringLen = 1 << 20
+	maxDims      = 8
+)
+
+// ErrNotGGUF is returned when a file does not start with the GGUF magic
+var ErrNotGGUF = errors.New("not a GGUF file")
+
+// GGUFInfo is what we read from a GGUF header
+type GGUFInfo struct {
+	Version        uint32
+	Name           string // general.name, may be empty
+	Architecture   string // general.architecture, e.g. "llama"
+	ParameterCount uint64 // sum of all tensor elements
+	Quantization   string // from general.file_type, e.g. "Q4_K_M"
+	ContextLength  uint64 // <arch>.context_length
+	BlockCount     uint64 // <arch>.block_count, i.e. transformer layers
+	SplitCount     uint64 // split.count for multi-file models, else 0
+}
+
+// gguf metadata value types
+const (
+	typeUint8 uint32 = iota
+	typeInt8
+	typeUint16
+	typeInt16
+	typeUint32
+	typeInt32
+	typeFloat32
+	typeBool
+	typeString
+	typeArray
+	typeUint64
+	typeInt64
+	typeFloat64
+)
+
+// fileTypes names llama.cpp's general.file_type values
+var fileTypes = map[uint64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 4: "Q4_1_SOME_F16",
+	7: "Q8_0", 8: "Q5_0", 9: "Q5_1", 10: "Q2_K", 11: "Q3_K_S",
+	12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M", 16: "Q5_K_S",
+	17: "Q5_K_M", 18: "Q6_K", 19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K_S",
+	22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S",
+	27: "IQ3_M", 28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M",
+	32: "BF16", 36: "TQ1_0", 37: "TQ2_0",
+}
+
+// ggufReader decodes little-endian GGUF primitives
+type ggufReader struct {
+	r       *bufio.Reader
+	version uint32
+}
+
+// ParseGGUF reads the header, metadata and tensor descriptions of a GGUF
+// file. Tensor data is not read.
+func ParseGGUF(r io.Reader) (*GGUFInfo, error) {
+	g := &ggufReader{r: bufio.NewReaderSize(r, 64*1024)}
+	magic, err := g.u32()
+	if err != nil {
+		return nil, err
+	}
+	if magic != ggufMagic {
+		return nil, ErrNotGGUF
+	}
+	if g.version, err = g.u32(); err != nil {
+		return nil, err
+	}
+	if g.version < 1 || g.version > 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", g.version)
+	}
+	tensors, err := g.count()
+	if err != nil {
+		return nil, err
+	}
+	kvs, err := g.count()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &GGUFInfo{Version: g.version}
+	meta := map[string]any{}
+	for i := uint64(0); i < kvs; i++ {
+		key, err := g.str()
+		if err != nil {
+			return nil, fmt.Errorf("metadata key %d: %w", i, err)
+		}
+		typ, err := g.u32()
+		if err != nil {
+			return nil, err
+		}
+		v, err := g.value(typ)
+		if err != nil {
+			return nil, fmt.Errorf("metadata %q: %w", key, err)
+		}
+		if v != nil {
+			meta[key] = v
+		}
+	}
+
+	info.Name, _ = meta["general.name"].(string)
+	info.Architecture, _ = meta["general.architecture"].(string)
+	if ft, ok := asUint(meta["general.file_type"]); ok {
+		info.Quantization = fileTypes[ft]
+		if info.Quantization == "" {
+			info.Quantization = fmt.Sprintf("type%d", ft)
+		}
+	}
+	if info.Architecture != "" {
+		info.ContextLength, _ = asUint(meta[info.Architecture+".context_length"])
+		info.BlockCount, _ = asUint(meta[info.Architecture+".block_count"])
+	}
+	info.SplitCount, _ = asUint(meta["split.count"])
+
+	for i := uint64(0); i < tensors; i++ {
+		n, err := g.tensorElements()
+		if err != nil {
+			return nil, fmt.Errorf("tensor %d: %w", i, err)
+		}
+		info.ParameterCount += n
+	}
+	return info, nil
+}
+
+// tensorElements reads one tensor description and returns its element count
+func (g *ggufReader) tensorElements() (uint64, error) {
+	if err := g.skipStr(); err != nil {
+		return 0, err
+	}
+	dims, err := g.u32()
+	if err != nil {
+		return 0, err
+	}
+	if dims > maxDims {
+		return 0, fmt.Errorf("tensor has %d dimensions", dims)
+	}
+	n := uint64(1)
+	for d := uint32(0); d < dims; d++ {
+		size, err := g.u64()
+		if err != nil {
+			return 0, err
+		}
+		n *= size
+	}
+	// ggml type and data offset
+	if _, err := g.u32(); err != nil {
+		return 0, err
+	}
+	if _, err := g.u64(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// value reads a metadata value. Arrays are skipped and returned as nil
+// since none of the fields we keep are arrays.
+func (g *ggufReader) value(typ uint32) (any, error) {
+	switch typ {
+	case typeUint8, typeInt8, typeBool:
+		b, err := g.r.ReadByte()
+		if typ == typeBool {
+			return b != 0, err
+		}
+		return uint64(b), err
+	case typeUint16, typeInt16:
+		var v uint16
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return uint64(v), err
+	case typeUint32, typeInt32:
+		v, err := g.u32()
+		return uint64(v), err
+	case typeFloat32:
+		v, err := g.u32()
+		return float64(math.Float32frombits(v)), err
+	case typeUint64, typeInt64:
+		return g.u64()
+	case typeFloat64:
+		v, err := g.u64()
+		return math.Float64frombits(v), err
+	case typeString:
+		return g.str()
+	case typeArray:
+		elem, err := g.u32()
+		if err != nil {
+			return nil, err
+		}
+		n, err := g.count()
+		if err != nil {
+			return nil, err
+		}
+		return nil, g.skipArray(elem, n)
+	}
+	return nil, fmt.Errorf("unknown value type %d", typ)
+}
+
+// skipArray discards n values of type elem
+func (g *ggufReader) skipArray(elem uint32, n uint64) error {
+	var width int64
+	switch elem {
+	case typeUint8, typeInt8, typeBool:
+		width = 1
+	case typeUint16, typeInt16:
+		width = 2
+	case typeUint32, typeInt32, typeFloat32:
+		width = 4
+	case typeUint64, typeInt64, typeFloat64:
+		width = 8
+	}
+	if width > 0 {
+		if n > math.MaxInt64/uint64(width) {
+			return fmt.Errorf("array of %d elements is too large", n)
+		}
+		return g.discard(int64(n) * width)
+	}
+	for i := uint64(0); i < n; i++ {
+		if elem == typeString {
+			if err := g.skipStr(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := g.value(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// count reads an element count, which GGUF v1 stored as 32 bits
+func (g *ggufReader) count() (uint64, error) {
+	if g.version == 1 {
+		v, err := g.u32()
+		return uint64(v), err
+	}
+	return g.u64()
+}
+
+func (g *ggufReader) str() (string, error) {
+	n, err := g.count()
+	if err != nil {
+		return "", err
+	}
+	if n > maxStringLen {
+		return "", fmt.Errorf("string of %d bytes is too long", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(g.r, buf); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	return string(buf), nil
+}
+
+func (g *ggufReader) skipStr() error {
+	n, err := g.count()
+	if err != nil {
+		return err
+	}
+	if n > math.MaxInt64 {
+		return fmt.Errorf("string of %d bytes is too long", n)
+	}
+	return g.discard(int64(n))
+}
+
+func (g *ggufReader) discard(n int64) error {
+	if _, err := io.CopyN(io.Discard, g.r, n); err != nil {
+		return unexpectedEOF(err)
+	}
+	return nil
+}
+
+func (g *ggufReader) u32() (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(g.r, b[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func (g *ggufReader) u64() (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(g.r, b[:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+// unexpectedEOF reports a header cut short as a truncated file
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// asUint converts an integer metadata value
+func asUint(v any) (uint64, bool) {
+	u, ok := v.(uint64)
+	return u, ok
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// testModel describes a synthetic GGUF file
+type testModel struct {
+	arch     string
+	fileType uint32
+	ctxLen   uint32
+	blocks   uint32
+	tensors  [][]uint64 // dims of each tensor
+}
+
+// encodeGGUF writes a minimal v3 GGUF header for tm
+func encodeGGUF(tm testModel) []byte {
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	str := func(s string) { le(uint64(len(s))); b.WriteString(s) }
+
+	le(uint32(ggufMagic))
+	le(uint32(3))
+	le(uint64(len(tm.tensors)))
+	le(uint64(6))
+
+	str("general.architecture")
+	le(typeString)
+	str(tm.arch)
+	str("general.file_type")
+	le(typeUint32)
+	le(tm.fileType)
+	str(tm.arch + ".context_length")
+	le(typeUint32)
+	le(tm.ctxLen)
+	str(tm.arch + ".block_count")
+	le(typeUint32)
+	le(tm.blocks)
+	// arrays are skipped, but must be consumed correctly
+	str("tokenizer.ggml.tokens")
+	le(typeArray)
+	le(typeString)
+	le(uint64(3))
+	for _, tok := range []string{"<s>", "hello", "world"} {
+		str(tok)
+	}
+	str("tokenizer.ggml.scores")
+	le(typeArray)
+	le(typeFloat32)
+	le(uint64(3))
+	le([]float32{0, -1, -2})
+
+	for i, dims := range tm.tensors {
+		str("blk." + string(rune('a'+i)) + ".weight")
+		le(uint32(len(dims)))
+		for _, d := range dims {
+			le(d)
+		}
+		le(uint32(12)) // ggml type Q4_K
+		le(uint64(0))
+	}
+	return b.Bytes()
+}
+
+// writeGGUF writes a synthetic GGUF file to path
+func writeGGUF(t *testing.T, path string, tm testModel) {
+	t.Helper()
+	if err := os.WriteFile(path, encodeGGUF(tm), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseGGUF(t *testing.T) {
+	data := encodeGGUF(testModel{
+		arch: "llama", fileType: 15, ctxLen: 4096, blocks: 32,
+		tensors: [][]uint64{{4096, 32000}, {4096}},
+	})
+	info, err := ParseGGUF(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseGGUF(): unexpected error: %v", err)
+	}
+	want := GGUFInfo{
+		Version:        3,
+		Architecture:   "llama",
+		ParameterCount: 4096*32000 + 4096,
+		Quantization:   "Q4_K_M",
+		ContextLength:  4096,
+		BlockCount:     32,
+	}
+	if *info != want {
+		t.Errorf("ParseGGUF() = %+v; want %+v", *info, want)
+	}
+}
+
+func TestParseGGUFErrors(t *testing.T) {
+	if _, err := ParseGGUF(bytes.NewReader([]byte("GGML\x03\x00\x00\x00"))); !errors.Is(err, ErrNotGGUF) {
+		t.Errorf("bad magic error = %v; want ErrNotGGUF", err)
+	}
+	data := encodeGGUF(testModel{arch: "llama", tensors: [][]uint64{{8, 8}}})
+	if _, err := ParseGGUF(bytes.NewReader(data[:len(data)-4])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated file error = %v; want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,207 @@
+// Package models keeps a catalog of the GGUF model files available on
+// this host.
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Model describes one model available on disk
+type Model struct {
+	Name           string // file name without the .gguf extension or split suffix
+	Path           string // first (or only) file of the model
+	SizeBytes      int64  // total size of all files
+	Architecture   string
+	ParameterCount uint64
+	Quantization   string
+	ContextLength  uint64
+	BlockCount     uint64
+	ModifiedAt     time.Time
+}
+
+// splitName matches the files of a model split with gguf-split,
+// e.g. "llama-70b-Q4_K_M-00001-of-00003.gguf"
+var splitName = regexp.MustCompile(`^(.+)-(\d{5})-of-(\d{5})$`)
+
+// Registry scans a directory for GGUF files and caches what it finds
+type Registry struct {
+	dir    string
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	models []Model
+	// parsed headers keyed by path, reused while size and mtime match
+	cache map[string]cachedHeader
+}
+
+type cachedHeader struct {
+	size    int64
+	modTime time.Time
+	info    *GGUFInfo
+}
+
+// NewRegistry returns a registry for dir. Call Scan to populate it.
+func NewRegistry(dir string, logger *slog.Logger) *Registry {
+	return &Registry{dir: dir, logger: logger, cache: map[string]cachedHeader{}}
+}
+
+// Dir returns the directory the registry scans
+func (r *Registry) Dir() string { return r.dir }
+
+// Scan walks the model directory and refreshes the catalog. Files whose
+// size and modification time are unchanged are not parsed again. Files
+// that cannot be parsed are logged and left out, and a directory that
+// does not exist holds no models.
+func (r *Registry) Scan() error {
+	type part struct {
+		path  string
+		index int
+		fi    fs.FileInfo
+	}
+	groups := map[string][]part{}
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == r.dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".gguf") {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+		idx := 1
+		if m := splitName.FindStringSubmatch(name); m != nil {
+			name = m[1]
+			fmt.Sscanf(m[2], "%d", &idx)
+		}
+		groups[name] = append(groups[name], part{path: path, index: idx, fi: fi})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scan %s: %w", r.dir, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[string]bool{}
+	var found []Model
+	for name, parts := range groups {
+		slices.SortFunc(parts, func(a, b part) int { return a.index - b.index })
+		m := Model{Name: name, Path: parts[0].path}
+		ok := true
+		for i, p := range parts {
+			seen[p.path] = true
+			info, err := r.header(p.path, p.fi)
+			if err != nil {
+				r.logger.Warn("skipping unreadable model file", "path", p.path, "err", err)
+				ok = false
+				break
+			}
+			if i == 0 {
+				m.Architecture = info.Architecture
+				m.Quantization = info.Quantization
+				m.ContextLength = info.ContextLength
+				m.BlockCount = info.BlockCount
+			}
+			m.ParameterCount += info.ParameterCount
+			m.SizeBytes += p.fi.Size()
+			if p.fi.ModTime().After(m.ModifiedAt) {
+				m.ModifiedAt = p.fi.ModTime()
+			}
+		}
+		if ok {
+			found = append(found, m)
+		}
+	}
+	for path := range r.cache {
+		if !seen[path] {
+			delete(r.cache, path)
+		}
+	}
+	slices.SortFunc(found, func(a, b Model) int { return strings.Compare(a.Name, b.Name) })
+	r.models = found
+	return nil
+}
+
+// header parses path's GGUF header unless a cached copy is still current;
+// callers hold r.mu
+func (r *Registry) header(path string, fi fs.FileInfo) (*GGUFInfo, error) {
+	if c, ok := r.cache[path]; ok && c.size == fi.Size() && c.modTime.Equal(fi.ModTime()) {
+		return c.info, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := ParseGGUF(f)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[path] = cachedHeader{size: fi.Size(), modTime: fi.ModTime(), info: info}
+	return info, nil
+}
+
+// Watch rescans the directory every interval until ctx is done, so that
+// files added or removed show up without a restart. Failed scans are
+// logged and keep the previous catalog. An interval of zero or less
+// disables rescanning.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Scan(); err != nil {
+				r.logger.Warn("model directory scan failed", "dir", r.dir, "err", err)
+			}
+		}
+	}
+}
+
+// Models returns the catalog from the last Scan, sorted by name
+func (r *Registry) Models() []Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.models)
+}
+
+// ErrModelNotFound is returned by Get for names not in the catalog
+var ErrModelNotFound = errors.New("model not found")
+
+// Get returns the named model from the last Scan
+func (r *Registry) Get(name string) (Model, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.models {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return Model{}, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryScan(t *testing.T) {
+	dir := t.TempDir()
+	writeGGUF(t, filepath.Join(dir, "tiny-Q8_0.gguf"), testModel{
+		arch: "qwen2", fileType: 7, ctxLen: 32768, blocks: 2,
+		tensors: [][]uint64{{16, 16}},
+	})
+	// a model split across two files
+	writeGGUF(t, filepath.Join(dir, "big-00001-of-00002.gguf"), testModel{
+		arch: "llama", fileType: 15, ctxLen: 8192, blocks: 80,
+		tensors: [][]uint64{{100, 10}},
+	})
+	writeGGUF(t, filepath.Join(dir, "big-00002-of-00002.gguf"), testModel{
+		arch: "llama", tensors: [][]uint64{{100, 20}},
+	})
+	// not models
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("hi"), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken.gguf"), []byte("nope"), 0o644)
+
+	r := NewRegistry(dir, slog.New(slog.DiscardHandler))
+	if err := r.Scan(); err != nil {
+		t.Fatalf("Scan(): unexpected error: %v", err)
+	}
+	got := r.Models()
+	if len(got) != 2 {
+		t.Fatalf("got %d models; want 2: %+v", len(got), got)
+	}
+
+	big := got[0]
+	if big.Name != "big" || big.Architecture != "llama" || big.Quantization != "Q4_K_M" {
+		t.Errorf("big = %+v", big)
+	}
+	if big.ParameterCount != 3000 {
+		t.Errorf("big.ParameterCount = %d; want both files summed (3000)", big.ParameterCount)
+	}
+	if filepath.Base(big.Path) != "big-00001-of-00002.gguf" {
+		t.Errorf("big.Path = %q; want the first split", big.Path)
+	}
+
+	tiny, err := r.Get("tiny-Q8_0")
+	if err != nil {
+		t.Fatalf("Get(): unexpected error: %v", err)
+	}
+	if tiny.ContextLength != 32768 || tiny.BlockCount != 2 || tiny.Quantization != "Q8_0" {
+		t.Errorf("tiny = %+v", tiny)
+	}
+
+	// removed files drop out on the next scan
+	os.Remove(filepath.Join(dir, "tiny-Q8_0.gguf"))
+	if err := r.Scan(); err != nil {
+		t.Fatalf("Scan(): unexpected error: %v", err)
+	}
+	if _, err := r.Get("tiny-Q8_0"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Get() after removal error = %v; want ErrModelNotFound", err)
+	}
+}
+
+func TestRegistryMissingDir(t *testing.T) {
+	dir := t.TempDir()
+	writeGGUF(t, filepath.Join(dir, "tiny.gguf"), testModel{arch: "qwen2"})
+	r := NewRegistry(dir, slog.New(slog.DiscardHandler))
+	if err := r.Scan(); err != nil {
+		t.Fatalf("Scan(): unexpected error: %v", err)
+	}
+
+	// a directory that goes away empties the catalog rather than failing
+	os.RemoveAll(dir)
+	if err := r.Scan(); err != nil {
+		t.Fatalf("Scan() of missing dir: unexpected error: %v", err)
+	}
+	if got := r.Models(); len(got) != 0 {
+		t.Errorf("Models() of missing dir = %+v; want none", got)
+	}
+}
+
+func TestRegistryWatch(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRegistry(dir, slog.New(slog.DiscardHandler))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	writeGGUF(t, filepath.Join(dir, "tiny.gguf"), testModel{arch: "qwen2"})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := r.Get("tiny"); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() never picked up the new model")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegistryWatchDisabled(t *testing.T) {
+	r := NewRegistry(t.TempDir(), slog.New(slog.DiscardHandler))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Watch(context.Background(), 0)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Watch() with no interval kept running; want it to return at once")
+	}
+}
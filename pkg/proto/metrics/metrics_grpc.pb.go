@@ -20,7 +20,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MetricsService_GetMetrics_FullMethodName = "/metrics.MetricsService/GetMetrics"
+	MetricsService_GetMetrics_FullMethodName        = "/metrics.MetricsService/GetMetrics"
+	MetricsService_WatchMetrics_FullMethodName      = "/metrics.MetricsService/WatchMetrics"
+	MetricsService_GetMetricsHistory_FullMethodName = "/metrics.MetricsService/GetMetricsHistory"
+	MetricsService_ClusterState_FullMethodName      = "/metrics.MetricsService/ClusterState"
 )
 
 // MetricsServiceClient is the client API for MetricsService service.
@@ -31,6 +34,15 @@ const (
 type MetricsServiceClient interface {
 	// GetMetrics returns current CPU, memory, and (if available) GPU metrics.
 	GetMetrics(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*MetricsResponse, error)
+	// WatchMetrics sends a snapshot straight away and then one per interval
+	// until the client hangs up.
+	WatchMetrics(ctx context.Context, in *WatchMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetricsResponse], error)
+	// GetMetricsHistory returns the snapshots the host has kept, oldest
+	// first, for drawing trends.
+	GetMetricsHistory(ctx context.Context, in *MetricsHistoryRequest, opts ...grpc.CallOption) (*MetricsHistoryResponse, error)
+	// ClusterState lists every live backend this host knows of through
+	// gossip, itself included, with what each one can run.
+	ClusterState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterStateResponse, error)
 }
 
 type metricsServiceClient struct {
@@ -51,6 +63,45 @@ func (c *metricsServiceClient) GetMetrics(ctx context.Context, in *emptypb.Empty
 	return out, nil
 }
 
+func (c *metricsServiceClient) WatchMetrics(ctx context.Context, in *WatchMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MetricsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MetricsService_ServiceDesc.Streams[0], MetricsService_WatchMetrics_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchMetricsRequest, MetricsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetricsService_WatchMetricsClient = grpc.ServerStreamingClient[MetricsResponse]
+
+func (c *metricsServiceClient) GetMetricsHistory(ctx context.Context, in *MetricsHistoryRequest, opts ...grpc.CallOption) (*MetricsHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MetricsHistoryResponse)
+	err := c.cc.Invoke(ctx, MetricsService_GetMetricsHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) ClusterState(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterStateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClusterStateResponse)
+	err := c.cc.Invoke(ctx, MetricsService_ClusterState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServiceServer is the server API for MetricsService service.
 // All implementations must embed UnimplementedMetricsServiceServer
 // for forward compatibility.
@@ -59,6 +110,15 @@ func (c *metricsServiceClient) GetMetrics(ctx context.Context, in *emptypb.Empty
 type MetricsServiceServer interface {
 	// GetMetrics returns current CPU, memory, and (if available) GPU metrics.
 	GetMetrics(context.Context, *emptypb.Empty) (*MetricsResponse, error)
+	// WatchMetrics sends a snapshot straight away and then one per interval
+	// until the client hangs up.
+	WatchMetrics(*WatchMetricsRequest, grpc.ServerStreamingServer[MetricsResponse]) error
+	// GetMetricsHistory returns the snapshots the host has kept, oldest
+	// first, for drawing trends.
+	GetMetricsHistory(context.Context, *MetricsHistoryRequest) (*MetricsHistoryResponse, error)
+	// ClusterState lists every live backend this host knows of through
+	// gossip, itself included, with what each one can run.
+	ClusterState(context.Context, *emptypb.Empty) (*ClusterStateResponse, error)
 	mustEmbedUnimplementedMetricsServiceServer()
 }
 
@@ -72,6 +132,15 @@ type UnimplementedMetricsServiceServer struct{}
 func (UnimplementedMetricsServiceServer) GetMetrics(context.Context, *emptypb.Empty) (*MetricsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
 }
+func (UnimplementedMetricsServiceServer) WatchMetrics(*WatchMetricsRequest, grpc.ServerStreamingServer[MetricsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchMetrics not implemented")
+}
+func (UnimplementedMetricsServiceServer) GetMetricsHistory(context.Context, *MetricsHistoryRequest) (*MetricsHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetricsHistory not implemented")
+}
+func (UnimplementedMetricsServiceServer) ClusterState(context.Context, *emptypb.Empty) (*ClusterStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClusterState not implemented")
+}
 func (UnimplementedMetricsServiceServer) mustEmbedUnimplementedMetricsServiceServer() {}
 func (UnimplementedMetricsServiceServer) testEmbeddedByValue()                        {}
 
@@ -111,6 +180,53 @@ func _MetricsService_GetMetrics_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetricsService_WatchMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MetricsServiceServer).WatchMetrics(m, &grpc.GenericServerStream[WatchMetricsRequest, MetricsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MetricsService_WatchMetricsServer = grpc.ServerStreamingServer[MetricsResponse]
+
+func _MetricsService_GetMetricsHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).GetMetricsHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_GetMetricsHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).GetMetricsHistory(ctx, req.(*MetricsHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_ClusterState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).ClusterState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_ClusterState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).ClusterState(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetricsService_ServiceDesc is the grpc.ServiceDesc for MetricsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -122,7 +238,21 @@ var MetricsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMetrics",
 			Handler:    _MetricsService_GetMetrics_Handler,
 		},
+		{
+			MethodName: "GetMetricsHistory",
+			Handler:    _MetricsService_GetMetricsHistory_Handler,
+		},
+		{
+			MethodName: "ClusterState",
+			Handler:    _MetricsService_ClusterState_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchMetrics",
+			Handler:       _MetricsService_WatchMetrics_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/proto/metrics/metrics.proto",
 }
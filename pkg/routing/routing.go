@@ -0,0 +1,165 @@
+// Package routing picks which host of the cluster should answer a chat
+// request, from the load and models each host gossips.
+package routing
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+)
+
+// ErrNoCandidate is returned by Route when no host can serve a model
+var ErrNoCandidate = errors.New("no host can serve the request")
+
+// Candidate is a host that could answer a request
+type Candidate struct {
+	HostID   string
+	Addr     string // chat gRPC address
+	Local    bool   // the host doing the routing
+	Models   []string
+	OnDemand bool // can load models that are not in Models
+	Load     *metricspb.MetricsResponse
+
+	// Pending counts requests already sent the host's way since Load was
+	// reported, so a burst does not all pile onto the same stale figure
+	Pending int
+}
+
+// Serves reports whether c can answer for model; "" is any model
+func (c Candidate) Serves(model string) bool {
+	return model == "" || c.OnDemand || c.Loaded(model)
+}
+
+// Loaded reports whether model is resident on c
+func (c Candidate) Loaded(model string) bool {
+	return slices.Contains(c.Models, model)
+}
+
+// Score is c's load: the fraction of generation slots taken or queued
+// for, which dominates, plus smaller terms for CPU and memory pressure.
+// Lower is better; 1 means every slot is busy.
+func (c Candidate) Score() float64 {
+	l := c.Load
+	slots := max(int(l.GetMaxConcurrentGenerations()), 1)
+	busy := float64(int(l.GetActiveGenerations())+int(l.GetQueueDepth())+c.Pending) / float64(slots)
+	cpu := l.GetCpuUsagePercent() / 100
+	mem := 0.0
+	if l.GetMemoryTotalMb() > 0 {
+		mem = l.GetMemoryUsedMb() / l.GetMemoryTotalMb()
+	}
+	return busy + 0.25*cpu + 0.25*mem
+}
+
+// Policy chooses among candidates that can all serve the model.
+type Policy interface {
+	Name() string
+	pick(model string, cands []Candidate) Candidate
+}
+
+// Route picks the host p sends a request for model to. Candidates that
+// cannot serve the model are skipped.
+func Route(p Policy, model string, cands []Candidate) (Candidate, error) {
+	var eligible []Candidate
+	for _, c := range cands {
+		if c.Serves(model) {
+			eligible = append(eligible, c)
+		}
+	}
+	if len(eligible) == 0 {
+		return Candidate{}, ErrNoCandidate
+	}
+	// a stable order makes ties go to the local host, then by name
+	slices.SortStableFunc(eligible, func(a, b Candidate) int {
+		if a.Local != b.Local {
+			if a.Local {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.HostID, b.HostID)
+	})
+	return p.pick(model, eligible), nil
+}
+
+// ParsePolicy returns the policy called name: "local", "least-loaded",
+// "model-affinity" or "round-robin".
+func ParsePolicy(name string) (Policy, error) {
+	switch name {
+	case "local":
+		return Local(), nil
+	case "least-loaded":
+		return LeastLoaded(), nil
+	case "model-affinity":
+		return ModelAffinity(), nil
+	case "round-robin":
+		return RoundRobin(), nil
+	}
+	return nil, fmt.Errorf("unknown routing policy %q", name)
+}
+
+type local struct{}
+
+// Local answers every request on the host it arrived at when that host
+// can serve it.
+func Local() Policy { return local{} }
+
+func (local) Name() string { return "local" }
+
+func (local) pick(_ string, cands []Candidate) Candidate {
+	return cands[0] // the local host sorts first when eligible
+}
+
+type leastLoaded struct{}
+
+// LeastLoaded sends each request to the host with the lowest Score.
+func LeastLoaded() Policy { return leastLoaded{} }
+
+func (leastLoaded) Name() string { return "least-loaded" }
+
+func (leastLoaded) pick(_ string, cands []Candidate) Candidate {
+	return slices.MinFunc(cands, func(a, b Candidate) int { return cmp.Compare(a.Score(), b.Score()) })
+}
+
+type modelAffinity struct{}
+
+// ModelAffinity prefers hosts that already have the model loaded, to
+// avoid loading it twice, and among those the least loaded. A host that
+// would have to load the model is used only once every host holding it is
+// busy.
+func ModelAffinity() Policy { return modelAffinity{} }
+
+func (modelAffinity) Name() string { return "model-affinity" }
+
+func (modelAffinity) pick(model string, cands []Candidate) Candidate {
+	var warm []Candidate
+	for _, c := range cands {
+		if model != "" && c.Loaded(model) {
+			warm = append(warm, c)
+		}
+	}
+	if len(warm) == 0 {
+		return leastLoaded{}.pick(model, cands)
+	}
+	best := leastLoaded{}.pick(model, warm)
+	if best.Score() < 1 {
+		return best
+	}
+	return leastLoaded{}.pick(model, cands)
+}
+
+type roundRobin struct{ next atomic.Uint64 }
+
+// RoundRobin takes turns among the hosts regardless of load.
+func RoundRobin() Policy { return &roundRobin{} }
+
+func (*roundRobin) Name() string { return "round-robin" }
+
+func (r *roundRobin) pick(_ string, cands []Candidate) Candidate {
+	// order by name alone so the rotation is the same on every call
+	slices.SortFunc(cands, func(a, b Candidate) int { return cmp.Compare(a.HostID, b.HostID) })
+	return cands[(r.next.Add(1)-1)%uint64(len(cands))]
+}
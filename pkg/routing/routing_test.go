@@ -0,0 +1,121 @@
+package routing_test
+
+import (
+	"errors"
+	"testing"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/routing"
+)
+
+// host is a candidate running active of 4 slots with queued waiting
+func host(name string, active, queued uint32, models ...string) routing.Candidate {
+	return routing.Candidate{
+		HostID: name,
+		Models: models,
+		Load: &metricspb.MetricsResponse{
+			ActiveGenerations:        active,
+			QueueDepth:               queued,
+			MaxConcurrentGenerations: 4,
+		},
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	local := host("a", 2, 0, "llama")
+	local.Local = true
+	cands := []routing.Candidate{local, host("b", 1, 0, "llama"), host("c", 4, 2, "llama")}
+
+	got, err := routing.Route(routing.LeastLoaded(), "llama", cands)
+	if err != nil {
+		t.Fatalf("Route(): unexpected error: %v", err)
+	}
+	if got.HostID != "b" {
+		t.Errorf("Route() = %s; want b", got.HostID)
+	}
+
+	// requests already sent b's way count against it
+	cands[1].Pending = 2
+	if got, _ := routing.Route(routing.LeastLoaded(), "llama", cands); got.HostID != "a" {
+		t.Errorf("Route() with b pending = %s; want a", got.HostID)
+	}
+
+	// ties go to the local host
+	cands[1].Pending = 1
+	if got, _ := routing.Route(routing.LeastLoaded(), "llama", cands); got.HostID != "a" {
+		t.Errorf("Route() on a tie = %s; want local a", got.HostID)
+	}
+}
+
+func TestModelAffinity(t *testing.T) {
+	cands := []routing.Candidate{host("a", 0, 0, "mistral"), host("b", 2, 0, "llama"), host("c", 3, 0, "llama")}
+	for i := range cands {
+		cands[i].OnDemand = true
+	}
+	p := routing.ModelAffinity()
+
+	if got, _ := routing.Route(p, "llama", cands); got.HostID != "b" {
+		t.Errorf("Route(llama) = %s; want b, the least loaded host with it loaded", got.HostID)
+	}
+	if got, _ := routing.Route(p, "phi", cands); got.HostID != "a" {
+		t.Errorf("Route(phi) = %s; want a, the least loaded host", got.HostID)
+	}
+
+	// every host with the model is saturated: load it somewhere idle
+	cands[1].Load.QueueDepth = 2
+	cands[2].Load.ActiveGenerations = 4
+	if got, _ := routing.Route(p, "llama", cands); got.HostID != "a" {
+		t.Errorf("Route(llama) with its hosts busy = %s; want a", got.HostID)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	cands := []routing.Candidate{host("b", 0, 0), host("a", 4, 4), host("c", 0, 0)}
+	p := routing.RoundRobin()
+	var got []string
+	for range 4 {
+		c, err := routing.Route(p, "", cands)
+		if err != nil {
+			t.Fatalf("Route(): unexpected error: %v", err)
+		}
+		got = append(got, c.HostID)
+	}
+	want := []string{"a", "b", "c", "a"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rotation = %v; want %v", got, want)
+		}
+	}
+}
+
+func TestRouteSkipsHostsWithoutModel(t *testing.T) {
+	local := host("a", 0, 0, "mistral")
+	local.Local = true
+	cands := []routing.Candidate{local, host("b", 3, 0, "llama")}
+
+	got, err := routing.Route(routing.Local(), "llama", cands)
+	if err != nil {
+		t.Fatalf("Route(): unexpected error: %v", err)
+	}
+	if got.HostID != "b" {
+		t.Errorf("Route() = %s; want b, the only host serving llama", got.HostID)
+	}
+	if _, err := routing.Route(routing.LeastLoaded(), "phi", cands); !errors.Is(err, routing.ErrNoCandidate) {
+		t.Errorf("Route(phi) error = %v; want %v", err, routing.ErrNoCandidate)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, name := range []string{"local", "least-loaded", "model-affinity", "round-robin"} {
+		p, err := routing.ParsePolicy(name)
+		if err != nil {
+			t.Fatalf("ParsePolicy(%q): unexpected error: %v", name, err)
+		}
+		if p.Name() != name {
+			t.Errorf("ParsePolicy(%q).Name() = %q", name, p.Name())
+		}
+	}
+	if _, err := routing.ParsePolicy("random"); err == nil {
+		t.Error("ParsePolicy(random): expected an error")
+	}
+}
@@ -0,0 +1,149 @@
+// Package scheduler limits how many generations a host runs at once,
+// queueing the rest in arrival order and turning requests away once the
+// queue is full.
+package scheduler
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by Acquire when no more requests can wait
+var ErrQueueFull = errors.New("request queue is full")
+
+// Stats is a snapshot of the scheduler's load
+type Stats struct {
+	Active        int // generations running
+	Queued        int // requests waiting for a slot
+	MaxActive     int
+	MaxQueueDepth int
+}
+
+// Scheduler hands out a fixed number of generation slots in FIFO order.
+type Scheduler struct {
+	maxActive int
+	maxQueue  int
+
+	mu     sync.Mutex
+	active int
+	queue  *list.List // of *waiter, oldest first
+}
+
+type waiter struct {
+	granted bool          // a slot was handed over; set under mu
+	ready   chan struct{} // closed once granted
+	moved   chan struct{} // signalled when the queue ahead shrinks
+}
+
+// New returns a scheduler running at most maxActive generations at once
+// with up to maxQueue more waiting. maxQueue may be 0 to reject anything
+// that cannot start immediately.
+func New(maxActive, maxQueue int) *Scheduler {
+	if maxActive < 1 {
+		maxActive = 1
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	return &Scheduler{maxActive: maxActive, maxQueue: maxQueue, queue: list.New()}
+}
+
+// Acquire waits for a generation slot. While the request is queued,
+// position is called with its place in line (1 is next) whenever that
+// changes; it may be nil. The caller must call release when the
+// generation is done.
+func (s *Scheduler) Acquire(ctx context.Context, position func(int)) (release func(), err error) {
+	s.mu.Lock()
+	if s.active < s.maxActive && s.queue.Len() == 0 {
+		s.active++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	if s.queue.Len() >= s.maxQueue {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{}), moved: make(chan struct{}, 1)}
+	el := s.queue.PushBack(w)
+	pos := s.queue.Len()
+	s.mu.Unlock()
+
+	last := 0
+	for {
+		if position != nil && pos != last {
+			position(pos)
+			last = pos
+		}
+		select {
+		case <-w.ready:
+			return s.releaseFunc(), nil
+		case <-w.moved:
+			s.mu.Lock()
+			if !w.granted {
+				pos = s.positionLocked(el)
+			}
+			s.mu.Unlock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			if w.granted {
+				// the slot arrived as we gave up; pass it on
+				s.mu.Unlock()
+				s.release()
+				return nil, ctx.Err()
+			}
+			s.queue.Remove(el)
+			s.notifyLocked()
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() { once.Do(s.release) }
+}
+
+// release frees a slot, handing it straight to the oldest waiter
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	front := s.queue.Front()
+	if front == nil {
+		s.active--
+		return
+	}
+	w := s.queue.Remove(front).(*waiter)
+	w.granted = true
+	close(w.ready)
+	s.notifyLocked()
+}
+
+// notifyLocked tells every waiter that its position may have changed;
+// callers hold s.mu
+func (s *Scheduler) notifyLocked() {
+	for el := s.queue.Front(); el != nil; el = el.Next() {
+		select {
+		case el.Value.(*waiter).moved <- struct{}{}:
+		default: // already pending
+		}
+	}
+}
+
+// positionLocked finds el's place in the queue; callers hold s.mu
+func (s *Scheduler) positionLocked(el *list.Element) int {
+	pos := 1
+	for e := s.queue.Front(); e != nil && e != el; e = e.Next() {
+		pos++
+	}
+	return pos
+}
+
+// Stats reports the current load.
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{Active: s.active, Queued: s.queue.Len(), MaxActive: s.maxActive, MaxQueueDepth: s.maxQueue}
+}
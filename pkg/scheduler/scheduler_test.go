@@ -0,0 +1,127 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/scheduler"
+)
+
+// queued starts a goroutine waiting on s and returns channels reporting its
+// queue positions and the release func it eventually gets.
+func queued(t *testing.T, ctx context.Context, s *scheduler.Scheduler) (<-chan int, <-chan func(), <-chan error) {
+	t.Helper()
+	positions := make(chan int, 8)
+	granted := make(chan func(), 1)
+	errc := make(chan error, 1)
+	go func() {
+		release, err := s.Acquire(ctx, func(p int) { positions <- p })
+		if err != nil {
+			errc <- err
+			return
+		}
+		granted <- release
+	}()
+	return positions, granted, errc
+}
+
+func wait[T any](t *testing.T, c <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out")
+		panic("unreachable")
+	}
+}
+
+func TestSchedulerQueuesInOrder(t *testing.T) {
+	ctx := context.Background()
+	s := scheduler.New(1, 2)
+	release, err := s.Acquire(ctx, nil)
+	if err != nil {
+		t.Fatalf("Acquire(): unexpected error: %v", err)
+	}
+
+	pos1, granted1, _ := queued(t, ctx, s)
+	if p := wait(t, pos1); p != 1 {
+		t.Errorf("first waiter position = %d; want 1", p)
+	}
+	pos2, granted2, _ := queued(t, ctx, s)
+	if p := wait(t, pos2); p != 2 {
+		t.Errorf("second waiter position = %d; want 2", p)
+	}
+	if _, err := s.Acquire(ctx, nil); !errors.Is(err, scheduler.ErrQueueFull) {
+		t.Errorf("Acquire() on a full queue error = %v; want %v", err, scheduler.ErrQueueFull)
+	}
+	if st := s.Stats(); st.Active != 1 || st.Queued != 2 {
+		t.Errorf("Stats() = %+v; want 1 active, 2 queued", st)
+	}
+
+	release()
+	release2 := wait(t, granted1)
+	if p := wait(t, pos2); p != 1 {
+		t.Errorf("second waiter moved to %d; want 1", p)
+	}
+	release2()
+	wait(t, granted2)()
+	if st := s.Stats(); st.Active != 0 || st.Queued != 0 {
+		t.Errorf("Stats() after all released = %+v; want idle", st)
+	}
+}
+
+func TestSchedulerCancelWhileQueued(t *testing.T) {
+	s := scheduler.New(1, 4)
+	release, _ := s.Acquire(context.Background(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pos1, _, errc := queued(t, ctx, s)
+	wait(t, pos1)
+	pos2, granted2, _ := queued(t, context.Background(), s)
+	wait(t, pos2)
+
+	cancel()
+	if err := wait(t, errc); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Acquire() error = %v; want %v", err, context.Canceled)
+	}
+	if p := wait(t, pos2); p != 1 {
+		t.Errorf("waiter behind a cancelled one at %d; want 1", p)
+	}
+	release()
+	wait(t, granted2)()
+}
+
+func TestSchedulerLimitsConcurrency(t *testing.T) {
+	s := scheduler.New(3, 100)
+	var mu sync.Mutex
+	running, peak := 0, 0
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := s.Acquire(context.Background(), nil)
+			if err != nil {
+				t.Errorf("Acquire(): unexpected error: %v", err)
+				return
+			}
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+	if peak > 3 {
+		t.Errorf("%d generations ran at once; want at most 3", peak)
+	}
+}
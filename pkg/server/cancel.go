@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// errCancelled is the cause of requests stopped through Cancel
+var errCancelled = errors.New("cancelled by client")
+
+// inflightKey scopes request ids to their owner, so one user cannot
+// cancel another's generation
+type inflightKey struct{ owner, id string }
+
+// inflight tracks running generations so Cancel can stop them
+type inflight struct {
+	mu   sync.Mutex
+	reqs map[inflightKey]context.CancelCauseFunc
+}
+
+// start registers request id and returns the context the request must
+// run under, plus a func to call once it has finished.
+func (f *inflight) start(ctx context.Context, id string) (context.Context, func(), error) {
+	key := inflightKey{caller(ctx), id}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.reqs[key]; ok {
+		cancel(nil)
+		return nil, nil, status.Errorf(codes.AlreadyExists, "request %s is already running", id)
+	}
+	if f.reqs == nil {
+		f.reqs = map[inflightKey]context.CancelCauseFunc{}
+	}
+	f.reqs[key] = cancel
+	return ctx, func() {
+		f.mu.Lock()
+		delete(f.reqs, key)
+		f.mu.Unlock()
+		cancel(nil)
+	}, nil
+}
+
+// cancel stops owner's request id, reporting whether it was running
+func (f *inflight) cancel(owner, id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cancel, ok := f.reqs[inflightKey{owner, id}]
+	if ok {
+		cancel(errCancelled)
+	}
+	return ok
+}
+
+// Cancel implements ChatServiceServer.Cancel
+func (s *Server) Cancel(ctx context.Context, req *chatpb.CancelRequest) (*emptypb.Empty, error) {
+	if req.GetRequestId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "request_id is required")
+	}
+	if !s.inflight.cancel(caller(ctx), req.GetRequestId()) {
+		return nil, status.Errorf(codes.NotFound, "no running request %s", req.GetRequestId())
+	}
+	s.logger.Info("request cancelled", "host", s.hostID, "request", req.GetRequestId())
+	return &emptypb.Empty{}, nil
+}
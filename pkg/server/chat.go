@@ -0,0 +1,404 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/models"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/scheduler"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Chat implements metrics.ChatServiceServer.Chat
+func (s *Server) Chat(ctx context.Context, req *chatpb.ChatRequest) (resp *chatpb.ChatResponse, err error) {
+	turn, err := s.newTurn(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.accept(ctx, func(md metadata.MD) error { return grpc.SendHeader(ctx, md) })
+	// Log which server handled it and what was asked
+	s.logger.Info("Chat request",
+		"host", s.hostID,
+		"request", turn.requestID,
+		"session", req.GetSessionId(),
+		"turns", len(turn.history)+len(turn.input),
+		"prompt", req.GetText(),
+	)
+
+	if peer, ok := s.route(ctx, req); ok {
+		resp, err := s.forwardChat(ctx, peer, turn.forwarded(req))
+		if !errors.Is(err, errPeerUnavailable) {
+			return resp, err
+		}
+		s.logger.Warn("peer unreachable, answering locally", "peer", peer.HostID, "request", turn.requestID)
+	}
+
+	rec := s.stats.start(s.statsModel(ctx, req.GetModel()))
+	defer func() { rec.finish(resp.GetUsage(), err) }()
+	ctx, done, err := s.inflight.start(ctx, turn.requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	admitted, err := s.queue(ctx, nil)
+	if err != nil {
+		return nil, s.backendError(ctx, err)
+	}
+	defer admitted()
+	rec.admitted()
+	b, model, release, err := s.acquire(ctx, req.GetModel())
+	if err != nil {
+		return nil, s.backendError(ctx, err)
+	}
+	defer release()
+	rec.generating()
+	gctx, span := tracing.Start(ctx, "generate", attribute.String("llm.model", model))
+	res, err := b.Generate(gctx, turn.request())
+	tracing.End(span, err)
+	if err != nil {
+		return nil, s.backendError(ctx, err)
+	}
+	if err := s.saveTurn(ctx, turn, res.Text); err != nil {
+		return nil, err
+	}
+	return &chatpb.ChatResponse{
+		HostId:       s.hostID,
+		Text:         res.Text,
+		FinishReason: finishReason(res.FinishReason),
+		Usage:        usage(res),
+		SessionId:    req.GetSessionId(),
+		Model:        model,
+		RequestId:    turn.requestID,
+	}, nil
+}
+
+// ChatStream implements ChatServiceServer.ChatStream, sending the reply
+// one token at a time and finishing with a chunk that carries usage.
+func (s *Server) ChatStream(req *chatpb.ChatRequest, stream chatpb.ChatService_ChatStreamServer) (err error) {
+	turn, err := s.newTurn(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	s.accept(stream.Context(), stream.SendHeader)
+	s.logger.Info("ChatStream request",
+		"host", s.hostID,
+		"request", turn.requestID,
+		"session", req.GetSessionId(),
+		"turns", len(turn.history)+len(turn.input),
+		"prompt", req.GetText(),
+	)
+
+	if peer, ok := s.route(stream.Context(), req); ok {
+		err := s.forwardStream(peer, turn.forwarded(req), stream)
+		if !errors.Is(err, errPeerUnavailable) {
+			return err
+		}
+		s.logger.Warn("peer unreachable, answering locally", "peer", peer.HostID, "request", turn.requestID)
+	}
+
+	rec := s.stats.start(s.statsModel(stream.Context(), req.GetModel()))
+	var used *chatpb.Usage
+	defer func() { rec.finish(used, err) }()
+	ctx, done, err := s.inflight.start(stream.Context(), turn.requestID)
+	if err != nil {
+		return err
+	}
+	defer done()
+	admitted, err := s.queue(ctx, func(pos int) {
+		// a failed send means the client left, which cancels ctx
+		stream.Send(&chatpb.ChatChunk{
+			HostId:        s.hostID,
+			SessionId:     req.GetSessionId(),
+			RequestId:     turn.requestID,
+			QueuePosition: uint32(pos),
+		})
+	})
+	if err != nil {
+		return s.backendError(ctx, err)
+	}
+	defer admitted()
+	rec.admitted()
+	b, model, release, err := s.acquire(ctx, req.GetModel())
+	if err != nil {
+		return s.backendError(ctx, err)
+	}
+	defer release()
+	rec.generating()
+
+	var partial strings.Builder
+	emitted := 0
+	gctx, span := tracing.Start(ctx, "generate", attribute.String("llm.model", model))
+	res, err := b.Stream(gctx, turn.request(), func(tok string) error {
+		if emitted == 0 {
+			span.AddEvent("first token")
+		}
+		rec.firstToken()
+		partial.WriteString(tok)
+		emitted++
+		return stream.Send(&chatpb.ChatChunk{
+			HostId:    s.hostID,
+			Delta:     tok,
+			SessionId: req.GetSessionId(),
+			Model:     model,
+			RequestId: turn.requestID,
+		})
+	})
+	tracing.End(span, err)
+	var finish chatpb.FinishReason
+	switch {
+	case err == nil:
+		finish = finishReason(res.FinishReason)
+	case errors.Is(context.Cause(ctx), errCancelled) && stream.Context().Err() == nil:
+		// stopped through Cancel: the client is still listening, so keep
+		// what was generated and end the stream normally
+		res = &backend.Result{Text: partial.String(), CompletionTokens: emitted}
+		finish = chatpb.FinishReason_FINISH_REASON_CANCELLED
+		ctx = stream.Context()
+	default:
+		return s.backendError(ctx, err)
+	}
+	if err := s.saveTurn(ctx, turn, res.Text); err != nil {
+		return err
+	}
+	used = usage(res)
+	return stream.Send(&chatpb.ChatChunk{
+		HostId:       s.hostID,
+		FinishReason: finish,
+		Usage:        used,
+		SessionId:    req.GetSessionId(),
+		Model:        model,
+		RequestId:    turn.requestID,
+	})
+}
+
+// queue waits for a generation slot, under a span showing how long
+func (s *Server) queue(ctx context.Context, position func(int)) (func(), error) {
+	ctx, span := tracing.Start(ctx, "queue")
+	admitted, err := s.sched.Acquire(ctx, position)
+	tracing.End(span, err)
+	return admitted, err
+}
+
+// acquire picks the backend for a requested model name, loading it into
+// the pool if needed. It returns the name of the model that will answer.
+func (s *Server) acquire(ctx context.Context, name string) (backend.Backend, string, func(), error) {
+	if name == "" {
+		name = s.defaultModel
+	}
+	if s.pool != nil {
+		if name == "" {
+			return nil, "", nil, status.Error(codes.InvalidArgument, "no model requested and the host has no default model")
+		}
+		if err := inCatalog(s.models, name); err != nil {
+			return nil, "", nil, err
+		}
+		ctx, span := tracing.Start(ctx, "load model", attribute.String("llm.model", name))
+		b, release, err := s.pool.Acquire(ctx, name)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return b, name, release, nil
+	}
+	static := s.staticModelName(ctx)
+	if name != "" && name != static {
+		return nil, "", nil, fmt.Errorf("%w: %s", models.ErrModelNotFound, name)
+	}
+	return s.backend, static, func() {}, nil
+}
+
+// statsModel is the model a request for name is counted under in the
+// inference stats: the one that will answer it, or "" when the host does
+// not have it, so made-up names do not each get their own stats
+func (s *Server) statsModel(ctx context.Context, name string) string {
+	if name == "" {
+		name = s.defaultModel
+	}
+	if s.pool == nil {
+		if static := s.staticModelName(ctx); name == "" || name == static {
+			return static
+		}
+		return ""
+	}
+	if s.models != nil {
+		if _, err := s.models.Get(name); err != nil {
+			return ""
+		}
+	}
+	return name
+}
+
+// staticModelName asks the fixed backend which model it serves, caching
+// the answer once it succeeds
+func (s *Server) staticModelName(ctx context.Context) string {
+	s.staticMu.Lock()
+	defer s.staticMu.Unlock()
+	if s.staticModel == "" {
+		if list, err := s.backend.ListModels(ctx); err == nil && len(list) > 0 {
+			s.staticModel = list[0].ID
+		}
+	}
+	return s.staticModel
+}
+
+// residentModels reports the loaded models for MetricsResponse
+func (s *Server) residentModels() []*metricspb.ResidentModel {
+	if s.pool == nil {
+		name := s.staticModelName(context.Background())
+		if name == "" {
+			return nil
+		}
+		return []*metricspb.ResidentModel{{Name: name}}
+	}
+	var out []*metricspb.ResidentModel
+	for _, r := range s.pool.Resident() {
+		out = append(out, &metricspb.ResidentModel{
+			Name:     r.Name,
+			LoadedAt: timestamppb.New(r.LoadedAt),
+			LastUsed: timestamppb.New(r.LastUsed),
+			InUse:    uint32(r.InUse),
+		})
+	}
+	return out
+}
+
+// chatTurn is one request/reply exchange, possibly within a session
+type chatTurn struct {
+	sessionID string
+	history   []*chatpb.ChatMessage // stored session turns
+	input     []*chatpb.ChatMessage // turns sent with this request
+	raw       string                // prompt sent without the chat template
+	gen       generation
+	requestID string
+}
+
+// forwarded is req as sent on to another host, under the request id
+// this host assigned
+func (t *chatTurn) forwarded(req *chatpb.ChatRequest) *chatpb.ChatRequest {
+	req = proto.Clone(req).(*chatpb.ChatRequest)
+	req.RequestId = t.requestID
+	return req
+}
+
+// newTurn validates req and loads the session it continues, if any
+func (s *Server) newTurn(ctx context.Context, req *chatpb.ChatRequest) (*chatTurn, error) {
+	gen, err := samplingParams(req.GetSampling())
+	if err != nil {
+		return nil, err
+	}
+	turn := &chatTurn{sessionID: req.GetSessionId(), raw: req.GetPrompt(), gen: gen, requestID: req.GetRequestId()}
+	if turn.raw != "" {
+		if req.GetText() != "" || len(req.GetMessages()) > 0 || turn.sessionID != "" {
+			return nil, status.Error(codes.InvalidArgument, "prompt cannot be combined with text, messages or session_id")
+		}
+	} else if turn.input, err = conversation(req); err != nil {
+		return nil, err
+	}
+	if turn.requestID == "" {
+		if turn.requestID, err = newID(); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	if turn.sessionID != "" {
+		owner, err := sessionOwner(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sess, err := s.sessions.Get(owner, turn.sessionID)
+		if err != nil {
+			return nil, sessionError(err)
+		}
+		turn.history = sess.chatMessages()
+	}
+	return turn, nil
+}
+
+// request renders the whole conversation into a backend request
+func (t *chatTurn) request() backend.Request {
+	if t.raw != "" {
+		return backend.Request{
+			Prompt:    t.raw,
+			MaxTokens: t.gen.maxTokens,
+			Stop:      slices.Clone(t.gen.stop),
+			Sampling:  &t.gen.sampling,
+		}
+	}
+	msgs := append(slices.Clone(t.history), t.input...)
+	return backend.Request{
+		Prompt:    buildPrompt(msgs),
+		MaxTokens: t.gen.maxTokens,
+		Stop:      append(slices.Clone(promptStop), t.gen.stop...),
+		Sampling:  &t.gen.sampling,
+	}
+}
+
+// saveTurn appends the request's turns and the reply to the session
+func (s *Server) saveTurn(ctx context.Context, t *chatTurn, reply string) error {
+	if t.sessionID == "" {
+		return nil
+	}
+	msgs := append(storedMessages(t.input), sessionMessage{Role: roleNames[chatpb.Role_ROLE_ASSISTANT], Content: reply})
+	// the turn was only built after sessionOwner accepted the caller
+	owner, _ := sessionOwner(ctx)
+	if _, err := s.sessions.Append(owner, t.sessionID, msgs...); err != nil {
+		s.logger.Error("saving session turn failed", "session", t.sessionID, "err", err)
+		return sessionError(err)
+	}
+	return nil
+}
+
+// backendError maps a backend failure of a request running under ctx
+// onto a gRPC status
+func (s *Server) backendError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(context.Cause(ctx), errCancelled) {
+		return status.Error(codes.Canceled, errCancelled.Error())
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	switch {
+	case errors.Is(err, models.ErrModelNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, backend.ErrPoolFull), errors.Is(err, scheduler.ErrQueueFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	s.logger.Error("backend generation failed", "host", s.hostID, "err", err)
+	if errors.Is(err, backend.ErrUnavailable) || errors.Is(err, backend.ErrPoolClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// finishReason converts a backend finish reason to its protobuf enum
+func finishReason(r backend.FinishReason) chatpb.FinishReason {
+	switch r {
+	case backend.FinishStop:
+		return chatpb.FinishReason_FINISH_REASON_STOP
+	case backend.FinishLength:
+		return chatpb.FinishReason_FINISH_REASON_LENGTH
+	}
+	return chatpb.FinishReason_FINISH_REASON_UNSPECIFIED
+}
+
+// usage builds protobuf token counts from a backend result
+func usage(res *backend.Result) *chatpb.Usage {
+	p := uint32(res.PromptTokens)
+	c := uint32(res.CompletionTokens)
+	return &chatpb.Usage{PromptTokens: p, CompletionTokens: c, TotalTokens: p + c}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Version is the build version hosts advertise to each other. Release
+// builds set it with -ldflags "-X github.com/Billy-Davies-2/llm-test/pkg/server.Version=v1.2.3";
+// otherwise the module version or VCS revision is used.
+var Version string
+
+// advertiseInterval is how often a host refreshes its metadata. Peers
+// route by the load it carries, so it is kept short.
+const advertiseInterval = 2 * time.Second
+
+// WithCluster advertises this host through the gossip node n and lets
+// ClusterState report the other members. chatAddr and metricsAddr are
+// where peers reach this host's gRPC services.
+func WithCluster(n *gossip.Node, chatAddr, metricsAddr string) Option {
+	return func(s *Server) {
+		s.cluster = n
+		s.chatAddr = chatAddr
+		s.metricsAddr = metricsAddr
+	}
+}
+
+// nodeInfo describes this host as ClusterState reports it
+func (s *Server) nodeInfo() *metricspb.NodeInfo {
+	info := &metricspb.NodeInfo{
+		HostId:      s.hostID,
+		ChatAddr:    s.chatAddr,
+		MetricsAddr: s.metricsAddr,
+		Hardware:    s.hardware,
+		Version:     buildVersion(),
+		State:       metricspb.NodeState_NODE_STATE_ALIVE,
+		// the pool loads whatever the registry has; a fixed backend
+		// serves only its own model
+		LoadsOnDemand: s.pool != nil,
+	}
+	if load, err := s.metrics.snapshot(); err == nil {
+		// the lists grow with the machine and would not fit in gossip
+		load.CpuCorePercent, load.Disks, load.Networks, load.ModelStats = nil, nil, nil, nil
+		info.Load = load
+	}
+	if s.cluster != nil {
+		info.GossipAddr = s.cluster.Addr()
+	}
+	for _, r := range s.residentModels() {
+		info.Models = append(info.Models, r.Name)
+	}
+	slices.Sort(info.Models)
+	return info
+}
+
+// gossipInfo is nodeInfo cut down to fit gossip.MaxMetaSize: of the load
+// only the figures routing and pipeline planning use are kept, so the
+// size no longer grows with the machine
+func (s *Server) gossipInfo() *metricspb.NodeInfo {
+	info := s.nodeInfo()
+	// liveness comes from gossip itself
+	info.State = metricspb.NodeState_NODE_STATE_UNSPECIFIED
+	if l := info.GetLoad(); l != nil {
+		info.Load = &metricspb.MetricsResponse{
+			CpuUsagePercent:          l.GetCpuUsagePercent(),
+			MemoryUsedMb:             l.GetMemoryUsedMb(),
+			MemoryTotalMb:            l.GetMemoryTotalMb(),
+			QueueDepth:               l.GetQueueDepth(),
+			ActiveGenerations:        l.GetActiveGenerations(),
+			MaxConcurrentGenerations: l.GetMaxConcurrentGenerations(),
+		}
+	}
+	return info
+}
+
+// advertise keeps this host's gossip metadata current until the server
+// stops. A failure is logged once until advertising works again, rather
+// than every interval.
+func (s *Server) advertise() {
+	t := time.NewTicker(advertiseInterval)
+	defer t.Stop()
+	failing := false
+	for {
+		meta, err := proto.Marshal(s.gossipInfo())
+		if err == nil {
+			err = s.cluster.SetMeta(meta)
+		}
+		switch {
+		case err != nil && !failing:
+			s.logger.Warn("advertising node metadata failed", "bytes", len(meta), "max", gossip.MaxMetaSize, "err", err)
+			failing = true
+		case err == nil && failing:
+			s.logger.Info("advertising node metadata again", "bytes", len(meta))
+			failing = false
+		}
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ClusterState lists the live members of the cluster from gossip, or
+// just this host when it is not part of one.
+func (m *metricsService) ClusterState(ctx context.Context, _ *emptypb.Empty) (*metricspb.ClusterStateResponse, error) {
+	resp := &metricspb.ClusterStateResponse{HostId: m.hostID}
+	if m.cluster == nil {
+		resp.Nodes = []*metricspb.NodeInfo{m.self()}
+		return resp, nil
+	}
+	for _, member := range m.cluster.Members() {
+		if member.Name == m.hostID {
+			// our own view is fresher than what we last gossiped
+			resp.Nodes = append(resp.Nodes, m.self())
+			continue
+		}
+		info, err := peerInfo(member)
+		if err != nil {
+			m.logger.Warn("bad node metadata", "member", member.Name, "err", err)
+			info = &metricspb.NodeInfo{}
+		}
+		// a member that has not advertised yet is still worth listing
+		info.HostId = member.Name
+		info.GossipAddr = member.Addr
+		info.State = nodeState(member.State)
+		resp.Nodes = append(resp.Nodes, info)
+	}
+	return resp, nil
+}
+
+func nodeState(s gossip.State) metricspb.NodeState {
+	switch s {
+	case gossip.StateAlive:
+		return metricspb.NodeState_NODE_STATE_ALIVE
+	case gossip.StateSuspect:
+		return metricspb.NodeState_NODE_STATE_SUSPECT
+	}
+	return metricspb.NodeState_NODE_STATE_UNSPECIFIED
+}
+
+// hostHardware summarises this machine. It is read once at startup, as
+// none of it changes while the server runs.
+func hostHardware() *metricspb.Hardware {
+	hw := &metricspb.Hardware{
+		Os:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CpuCores: uint32(runtime.NumCPU()),
+	}
+	if infos, err := cpu.Info(); err == nil && len(infos) > 0 {
+		hw.CpuModel = strings.TrimSpace(infos[0].ModelName)
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		hw.MemoryTotalMb = float64(vm.Total) / 1024 / 1024
+	}
+	return hw
+}
+
+// buildVersion reports Version, falling back to what the Go toolchain
+// recorded about the build
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, kv := range bi.Settings {
+		if kv.Key == "vcs.revision" && len(kv.Value) >= 12 {
+			return kv.Value[:12]
+		}
+	}
+	return "devel"
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// addHostStats fills in the diagnostics beyond CPU and memory. Each is
+// best effort: one the OS cannot report is left out rather than failing
+// the whole snapshot.
+func (m *metricsService) addHostStats(resp *metricspb.MetricsResponse) {
+	if runtime.GOOS != "windows" {
+		if avg, err := load.Avg(); err == nil {
+			resp.LoadAverage = &metricspb.LoadAverage{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}
+		}
+	}
+	if swap, err := mem.SwapMemory(); err == nil {
+		resp.SwapUsedMb = float64(swap.Used) / 1024 / 1024
+		resp.SwapTotalMb = float64(swap.Total) / 1024 / 1024
+	}
+	if up, err := host.Uptime(); err == nil {
+		resp.UptimeSeconds = up
+	}
+	resp.Disks = diskUsage()
+	resp.Networks = m.net.sample()
+}
+
+// diskUsage reports each physical device once, at the first place it is
+// mounted, so bind mounts do not repeat it
+func diskUsage() []*metricspb.DiskUsage {
+	parts, err := disk.Partitions(false)
+	if err != nil {
+		return nil
+	}
+	slices.SortFunc(parts, func(a, b disk.PartitionStat) int { return cmp.Compare(a.Mountpoint, b.Mountpoint) })
+	seen := map[string]bool{}
+	var out []*metricspb.DiskUsage
+	for _, p := range parts {
+		if seen[p.Device] {
+			continue
+		}
+		u, err := disk.Usage(p.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		seen[p.Device] = true
+		out = append(out, &metricspb.DiskUsage{
+			Mountpoint: p.Mountpoint,
+			Device:     p.Device,
+			Fstype:     p.Fstype,
+			UsedMb:     float64(u.Used) / 1024 / 1024,
+			TotalMb:    float64(u.Total) / 1024 / 1024,
+		})
+	}
+	return out
+}
+
+// cpuUsage turns the CPU time counters into usage between samples. It
+// keeps its own counters because cpu.Percent's are shared by the whole
+// process, and its first call measures from when the process started.
+type cpuUsage struct {
+	total, cores []cpu.TimesStat
+}
+
+// sample returns the total and per-core usage since the previous sample,
+// or none on the first. Per-core figures are best effort. It is called
+// only by the sampler, so needs no lock.
+func (u *cpuUsage) sample() (total float64, cores []float64, err error) {
+	all, err := cpu.Times(false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(all) > 0 && len(u.total) > 0 {
+		total = busyPercent(u.total[0], all[0])
+	}
+	u.total = all
+	if perCore, err := cpu.Times(true); err == nil {
+		// cores can come and go between samples
+		if len(perCore) == len(u.cores) {
+			cores = make([]float64, len(perCore))
+			for i := range perCore {
+				cores[i] = busyPercent(u.cores[i], perCore[i])
+			}
+		}
+		u.cores = perCore
+	}
+	return total, cores, nil
+}
+
+// busyPercent is the share of the CPU time between a and b not spent idle
+func busyPercent(a, b cpu.TimesStat) float64 {
+	elapsed := b.Total() - a.Total()
+	if elapsed <= 0 {
+		return 0
+	}
+	idle := b.Idle - a.Idle
+	return min(100, max(0, (elapsed-idle)/elapsed*100))
+}
+
+// netRates turns the interface byte counters into rates between
+// samples
+type netRates struct {
+	at   time.Time
+	last map[string]net.IOCountersStat
+}
+
+// sample is called only by the sampler, so needs no lock
+func (r *netRates) sample() []*metricspb.NetworkInterface {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	secs := now.Sub(r.at).Seconds()
+	prev := r.last
+	r.at, r.last = now, map[string]net.IOCountersStat{}
+	for _, c := range counters {
+		r.last[c.Name] = c
+	}
+
+	var out []*metricspb.NetworkInterface
+	for _, c := range counters {
+		if isLoopback(c.Name) {
+			continue
+		}
+		n := &metricspb.NetworkInterface{Name: c.Name, RxBytesTotal: c.BytesRecv, TxBytesTotal: c.BytesSent}
+		// counters reset when an interface is recreated
+		if p, ok := prev[c.Name]; ok && c.BytesRecv >= p.BytesRecv && c.BytesSent >= p.BytesSent {
+			n.RxBytesPerSec = float64(c.BytesRecv-p.BytesRecv) / secs
+			n.TxBytesPerSec = float64(c.BytesSent-p.BytesSent) / secs
+		}
+		out = append(out, n)
+	}
+	slices.SortFunc(out, func(a, b *metricspb.NetworkInterface) int { return cmp.Compare(a.GetName(), b.GetName()) })
+	return out
+}
+
+// isLoopback reports whether name is a loopback interface: lo on Linux,
+// lo0 on macOS and the BSDs, "Loopback Pseudo-Interface" on Windows
+func isLoopback(name string) bool {
+	return name == "lo" || name == "lo0" || strings.HasPrefix(name, "Loopback")
+}
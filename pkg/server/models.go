@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/models"
+	modelspb "github.com/Billy-Davies-2/llm-test/pkg/proto/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// modelService implements the ModelServiceServer interface
+// on top of the host's model registry
+type modelService struct {
+	modelspb.UnimplementedModelServiceServer
+	hostID   string
+	registry *models.Registry
+	pool     *backend.Pool
+	admin    func(context.Context) error // checks the caller may manage models
+}
+
+func (m *modelService) ListModels(ctx context.Context, _ *emptypb.Empty) (*modelspb.ListModelsResponse, error) {
+	resp := &modelspb.ListModelsResponse{HostId: m.hostID}
+	if m.registry == nil {
+		return resp, nil
+	}
+	// the registry's owner keeps it scanned; see models.Registry.Watch
+	for _, model := range m.registry.Models() {
+		resp.Models = append(resp.Models, modelInfo(model))
+	}
+	return resp, nil
+}
+
+func (m *modelService) LoadModel(ctx context.Context, req *modelspb.LoadModelRequest) (*emptypb.Empty, error) {
+	if err := m.admin(ctx); err != nil {
+		return nil, err
+	}
+	if m.pool == nil {
+		return nil, status.Error(codes.FailedPrecondition, "this host serves a fixed model")
+	}
+	if err := inCatalog(m.registry, req.GetName()); err != nil {
+		return nil, poolError(err)
+	}
+	if err := m.pool.Load(ctx, req.GetName()); err != nil {
+		return nil, poolError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (m *modelService) UnloadModel(ctx context.Context, req *modelspb.UnloadModelRequest) (*emptypb.Empty, error) {
+	if err := m.admin(ctx); err != nil {
+		return nil, err
+	}
+	if m.pool == nil {
+		return nil, status.Error(codes.FailedPrecondition, "this host serves a fixed model")
+	}
+	if err := m.pool.Unload(req.GetName()); err != nil {
+		return nil, poolError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// admin checks that the caller may load and unload models: one of the
+// admins when callers are authenticated, anyone when they are not
+func (s *Server) admin(ctx context.Context) error {
+	if s.authUnary == nil {
+		return nil
+	}
+	return auth.Authorize(ctx, s.admins)
+}
+
+// inCatalog fails with ErrModelNotFound for a model r does not list, so a
+// request for it is turned away before the pool evicts a resident model to
+// make room. Without a catalog the pool's opener decides.
+func inCatalog(r *models.Registry, name string) error {
+	if r == nil {
+		return nil
+	}
+	_, err := r.Get(name)
+	return err
+}
+
+// poolError maps model pool failures onto gRPC statuses
+func poolError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrModelNotFound), errors.Is(err, backend.ErrNotLoaded):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, backend.ErrPoolFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, backend.ErrModelBusy):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, backend.ErrPoolClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// modelInfo converts a catalog entry to its protobuf form
+func modelInfo(m models.Model) *modelspb.ModelInfo {
+	return &modelspb.ModelInfo{
+		Name:           m.Name,
+		Path:           m.Path,
+		SizeBytes:      uint64(m.SizeBytes),
+		Architecture:   m.Architecture,
+		ParameterCount: m.ParameterCount,
+		Quantization:   m.Quantization,
+		ContextLength:  m.ContextLength,
+		ModifiedAt:     timestamppb.New(m.ModifiedAt),
+		BlockCount:     m.BlockCount,
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+)
+
+// MetricsHandler serves GET /metrics: what GetMetrics reports, plus counts
+// of the RPCs this server has handled, in the Prometheus text format.
+func (s *Server) MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.servePrometheus)
+	return mux
+}
+
+func (s *Server) servePrometheus(w http.ResponseWriter, r *http.Request) {
+	snap, err := s.metrics.snapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	var e exposition
+	writeHostMetrics(&e, snap)
+	writeModelMetrics(&e, snap.GetModelStats())
+	writeRPCMetrics(&e, s.rpcs.report())
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(e.buf.Bytes())
+}
+
+const mib = 1024 * 1024
+
+func writeHostMetrics(e *exposition, m *metricspb.MetricsResponse) {
+	e.family("llm_host_info", "gauge", "Always 1, labelled with the host ID")
+	e.sample("llm_host_info", 1, "host", m.GetHostId())
+
+	e.family("llm_cpu_usage_percent", "gauge", "CPU usage over the last sampling interval")
+	e.sample("llm_cpu_usage_percent", m.GetCpuUsagePercent())
+	e.family("llm_cpu_core_usage_percent", "gauge", "Usage of each logical core over the last sampling interval")
+	for i, pct := range m.GetCpuCorePercent() {
+		e.sample("llm_cpu_core_usage_percent", pct, "core", strconv.Itoa(i))
+	}
+	if l := m.GetLoadAverage(); l != nil {
+		e.family("llm_load_average", "gauge", "Run-queue length averaged over the period")
+		e.sample("llm_load_average", l.GetLoad1(), "period", "1m")
+		e.sample("llm_load_average", l.GetLoad5(), "period", "5m")
+		e.sample("llm_load_average", l.GetLoad15(), "period", "15m")
+	}
+
+	e.family("llm_memory_used_bytes", "gauge", "Memory in use")
+	e.sample("llm_memory_used_bytes", m.GetMemoryUsedMb()*mib)
+	e.family("llm_memory_total_bytes", "gauge", "Memory installed")
+	e.sample("llm_memory_total_bytes", m.GetMemoryTotalMb()*mib)
+	e.family("llm_swap_used_bytes", "gauge", "Swap in use")
+	e.sample("llm_swap_used_bytes", m.GetSwapUsedMb()*mib)
+	e.family("llm_swap_total_bytes", "gauge", "Swap available")
+	e.sample("llm_swap_total_bytes", m.GetSwapTotalMb()*mib)
+
+	e.family("llm_disk_used_bytes", "gauge", "Space used on each physical filesystem")
+	for _, d := range m.GetDisks() {
+		e.sample("llm_disk_used_bytes", d.GetUsedMb()*mib, "mountpoint", d.GetMountpoint(), "device", d.GetDevice(), "fstype", d.GetFstype())
+	}
+	e.family("llm_disk_total_bytes", "gauge", "Size of each physical filesystem")
+	for _, d := range m.GetDisks() {
+		e.sample("llm_disk_total_bytes", d.GetTotalMb()*mib, "mountpoint", d.GetMountpoint(), "device", d.GetDevice(), "fstype", d.GetFstype())
+	}
+	e.family("llm_network_receive_bytes_total", "counter", "Bytes received on each interface other than loopback")
+	for _, n := range m.GetNetworks() {
+		e.sample("llm_network_receive_bytes_total", float64(n.GetRxBytesTotal()), "interface", n.GetName())
+	}
+	e.family("llm_network_transmit_bytes_total", "counter", "Bytes sent on each interface other than loopback")
+	for _, n := range m.GetNetworks() {
+		e.sample("llm_network_transmit_bytes_total", float64(n.GetTxBytesTotal()), "interface", n.GetName())
+	}
+	e.family("llm_uptime_seconds", "gauge", "Time since the host booted")
+	e.sample("llm_uptime_seconds", float64(m.GetUptimeSeconds()))
+	if gpu := m.GetGpu(); gpu.GetName() != "" {
+		e.family("llm_gpu_temperature_celsius", "gauge", "GPU temperature")
+		e.sample("llm_gpu_temperature_celsius", gpu.GetTemperatureCelsius(), "gpu", gpu.GetName())
+	}
+
+	e.family("llm_queue_depth", "gauge", "Chat requests waiting for a generation slot")
+	e.sample("llm_queue_depth", float64(m.GetQueueDepth()))
+	e.family("llm_active_generations", "gauge", "Generations running now")
+	e.sample("llm_active_generations", float64(m.GetActiveGenerations()))
+	e.family("llm_max_concurrent_generations", "gauge", "Generations allowed to run at once")
+	e.sample("llm_max_concurrent_generations", float64(m.GetMaxConcurrentGenerations()))
+	e.family("llm_resident_model_in_use", "gauge", "Requests being served by each loaded model")
+	for _, rm := range m.GetResidentModels() {
+		e.sample("llm_resident_model_in_use", float64(rm.GetInUse()), "model", rm.GetName())
+	}
+}
+
+func writeModelMetrics(e *exposition, stats []*metricspb.ModelStats) {
+	gauges := []struct {
+		name, help string
+		value      func(*metricspb.ModelStats) float64
+	}{
+		{"llm_model_queued_requests", "Requests for the model waiting for a generation slot", func(s *metricspb.ModelStats) float64 { return float64(s.GetQueued()) }},
+		{"llm_model_active_requests", "Requests for the model generating now", func(s *metricspb.ModelStats) float64 { return float64(s.GetActive()) }},
+		{"llm_model_tokens_per_second", "Completion tokens per second of decoding, for the requests last finished", (*metricspb.ModelStats).GetTokensPerSecond},
+	}
+	for _, g := range gauges {
+		e.family(g.name, "gauge", g.help)
+		for _, s := range stats {
+			e.sample(g.name, g.value(s), "model", s.GetModel())
+		}
+	}
+	counters := []struct {
+		name, help string
+		value      func(*metricspb.ModelStats) uint64
+	}{
+		{"llm_model_requests_total", "Requests for the model that have finished", (*metricspb.ModelStats).GetRequestsTotal},
+		{"llm_model_prompt_tokens_total", "Prompt tokens the model has read", (*metricspb.ModelStats).GetPromptTokensTotal},
+		{"llm_model_completion_tokens_total", "Completion tokens the model has generated", (*metricspb.ModelStats).GetCompletionTokensTotal},
+	}
+	for _, c := range counters {
+		e.family(c.name, "counter", c.help)
+		for _, s := range stats {
+			e.sample(c.name, float64(c.value(s)), "model", s.GetModel())
+		}
+	}
+	e.family("llm_model_errors_total", "counter", "Failed requests for the model, by gRPC status code")
+	for _, s := range stats {
+		codes := make([]string, 0, len(s.GetErrorsByCode()))
+		for code := range s.GetErrorsByCode() {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			e.sample("llm_model_errors_total", float64(s.GetErrorsByCode()[code]), "model", s.GetModel(), "code", code)
+		}
+	}
+	e.family("llm_model_time_to_first_token_seconds", "histogram", "Time from arrival to the first streamed token")
+	for _, s := range stats {
+		e.histogram("llm_model_time_to_first_token_seconds", s.GetTimeToFirstToken(), "model", s.GetModel())
+	}
+	e.family("llm_model_request_duration_seconds", "histogram", "Time from arrival to the end of the reply, for requests that succeeded")
+	for _, s := range stats {
+		e.histogram("llm_model_request_duration_seconds", s.GetLatency(), "model", s.GetModel())
+	}
+}
+
+// writeRPCMetrics uses the names of the go-grpc-prometheus server
+// metrics, so existing gRPC dashboards work unchanged
+func writeRPCMetrics(e *exposition, methods []rpcMethod) {
+	e.family("grpc_server_started_total", "counter", "RPCs started on the server")
+	for _, m := range methods {
+		e.sample("grpc_server_started_total", float64(m.started), "grpc_type", m.kind, "grpc_service", m.service, "grpc_method", m.method)
+	}
+	e.family("grpc_server_handled_total", "counter", "RPCs completed on the server, by status code")
+	for _, m := range methods {
+		codes := make([]string, 0, len(m.handled))
+		for code := range m.handled {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			e.sample("grpc_server_handled_total", float64(m.handled[code]),
+				"grpc_type", m.kind, "grpc_service", m.service, "grpc_method", m.method, "grpc_code", code)
+		}
+	}
+	e.family("grpc_server_handling_seconds", "histogram", "Time taken to complete RPCs on the server")
+	for _, m := range methods {
+		e.histogram("grpc_server_handling_seconds", m.duration, "grpc_type", m.kind, "grpc_service", m.service, "grpc_method", m.method)
+	}
+}
+
+// exposition builds a page in the Prometheus text format
+type exposition struct {
+	buf bytes.Buffer
+}
+
+func (e *exposition) family(name, typ, help string) {
+	fmt.Fprintf(&e.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one value; labels are name, value pairs
+func (e *exposition) sample(name string, v float64, labels ...string) {
+	e.buf.WriteString(name)
+	if len(labels) > 0 {
+		e.buf.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&e.buf, "%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+		e.buf.WriteByte('}')
+	}
+	e.buf.WriteByte(' ')
+	e.buf.WriteString(formatValue(v))
+	e.buf.WriteByte('\n')
+}
+
+// histogram writes h's cumulative buckets, sum and count
+func (e *exposition) histogram(name string, h *metricspb.Histogram, labels ...string) {
+	var cum uint64
+	for i, n := range h.GetCounts() {
+		cum += n
+		le := math.Inf(1)
+		if i < len(h.GetBoundsSeconds()) {
+			le = h.GetBoundsSeconds()[i]
+		}
+		e.sample(name+"_bucket", float64(cum), append(slices.Clip(labels), "le", formatValue(le))...)
+	}
+	e.sample(name+"_sum", h.GetSumSeconds(), labels...)
+	e.sample(name+"_count", float64(h.GetCount()), labels...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
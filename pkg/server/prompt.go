@@ -0,0 +1,74 @@
+package server
+
+import (
+	"strings"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChatML turn delimiters. Most instruction-tuned GGUF models understand
+// this template, and llama-server stops cleanly on the end marker.
+const (
+	turnStart = "<|im_start|>"
+	turnEnd   = "<|im_end|>"
+)
+
+// promptStop are the stop sequences that end an assistant turn.
+var promptStop = []string{turnEnd}
+
+// conversation returns the turns of req, oldest first, with the legacy
+// text field appended as the final user message.
+func conversation(req *chatpb.ChatRequest) ([]*chatpb.ChatMessage, error) {
+	msgs := make([]*chatpb.ChatMessage, 0, len(req.GetMessages())+1)
+	for i, m := range req.GetMessages() {
+		if _, ok := roleNames[m.GetRole()]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "messages[%d]: invalid role %v", i, m.GetRole())
+		}
+		if hasDelimiter(m.GetContent()) {
+			return nil, status.Errorf(codes.InvalidArgument, "messages[%d]: content contains a turn delimiter", i)
+		}
+		msgs = append(msgs, m)
+	}
+	if hasDelimiter(req.GetText()) {
+		return nil, status.Error(codes.InvalidArgument, "text contains a turn delimiter")
+	}
+	if req.GetText() != "" {
+		msgs = append(msgs, &chatpb.ChatMessage{Role: chatpb.Role_ROLE_USER, Content: req.GetText()})
+	}
+	if len(msgs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "request has no text or messages")
+	}
+	return msgs, nil
+}
+
+// hasDelimiter reports whether content holds a ChatML marker, which would
+// let it close its own turn and open one under another role
+func hasDelimiter(content string) bool {
+	return strings.Contains(content, turnStart) || strings.Contains(content, turnEnd)
+}
+
+// roleNames maps each valid role to its template name
+var roleNames = map[chatpb.Role]string{
+	chatpb.Role_ROLE_SYSTEM:    "system",
+	chatpb.Role_ROLE_USER:      "user",
+	chatpb.Role_ROLE_ASSISTANT: "assistant",
+}
+
+// buildPrompt renders msgs with the ChatML template and opens the
+// assistant turn the model should complete.
+func buildPrompt(msgs []*chatpb.ChatMessage) string {
+	var b strings.Builder
+	for _, m := range msgs {
+		b.WriteString(turnStart)
+		b.WriteString(roleNames[m.GetRole()])
+		b.WriteString("\n")
+		b.WriteString(m.GetContent())
+		b.WriteString(turnEnd)
+		b.WriteString("\n")
+	}
+	b.WriteString(turnStart)
+	b.WriteString("assistant\n")
+	return b.String()
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/routing"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// forwardedHeader marks a request another host has already routed, so it
+// is answered where it lands instead of being passed on again
+const forwardedHeader = "x-llm-forwarded-by"
+
+// acceptedHeader is sent back as soon as a host takes on a forwarded
+// request, so the host that forwarded it can tell a peer it never reached
+// from one that failed the request itself
+const acceptedHeader = "x-llm-accepted-by"
+
+// errPeerUnavailable reports a peer that could not be reached before it
+// produced any of the reply, so the request can still be answered locally
+var errPeerUnavailable = errors.New("peer unavailable")
+
+// WithRouting lets any host answer a chat request by forwarding it to the
+// host of the cluster that p picks. Requests continuing a session stay
+// on the host the session is stored on. It has no effect without
+// WithCluster.
+func WithRouting(p routing.Policy) Option {
+	return func(s *Server) {
+		s.router = &router{policy: p, conns: map[string]*grpc.ClientConn{}, pending: map[string]int{}}
+	}
+}
+
+// router holds connections to the peers requests are forwarded to
+type router struct {
+	policy routing.Policy
+
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn // by address
+	pending map[string]int              // forwarded requests running, by host
+}
+
+// client returns a chat client for the peer at addr, connecting lazily
+func (r *router) client(addr string) (chatpb.ChatServiceClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn, ok := r.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(addr, append(tracing.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+		if err != nil {
+			return nil, err
+		}
+		r.conns[addr] = conn
+	}
+	return chatpb.NewChatServiceClient(conn), nil
+}
+
+// begin counts a request forwarded to host until the returned func is
+// called
+func (r *router) begin(host string) func() {
+	r.mu.Lock()
+	r.pending[host]++
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		if r.pending[host]--; r.pending[host] <= 0 {
+			delete(r.pending, host)
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *router) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, conn := range r.conns {
+		conn.Close()
+		delete(r.conns, addr)
+	}
+}
+
+// peerInfo decodes the metadata a member gossips
+func peerInfo(m gossip.Member) (*metricspb.NodeInfo, error) {
+	info := &metricspb.NodeInfo{}
+	if err := proto.Unmarshal(m.Meta, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// candidates lists this host and every healthy peer that has advertised
+// its load
+func (s *Server) candidates() []routing.Candidate {
+	self := s.nodeInfo()
+	out := []routing.Candidate{{
+		HostID:   s.hostID,
+		Local:    true,
+		Models:   self.GetModels(),
+		OnDemand: self.GetLoadsOnDemand(),
+		Load:     self.GetLoad(),
+	}}
+	s.router.mu.Lock()
+	defer s.router.mu.Unlock()
+	for _, m := range s.cluster.Members() {
+		if m.Name == s.hostID || m.State != gossip.StateAlive {
+			continue
+		}
+		info, err := peerInfo(m)
+		if err != nil || info.GetChatAddr() == "" || info.GetLoad() == nil {
+			continue
+		}
+		out = append(out, routing.Candidate{
+			HostID:   m.Name,
+			Addr:     info.GetChatAddr(),
+			Models:   info.GetModels(),
+			OnDemand: info.GetLoadsOnDemand(),
+			Load:     info.GetLoad(),
+			Pending:  s.router.pending[m.Name],
+		})
+	}
+	return out
+}
+
+// route picks the peer req should be forwarded to, reporting false when
+// it is best answered here
+func (s *Server) route(ctx context.Context, req *chatpb.ChatRequest) (routing.Candidate, bool) {
+	if s.router == nil || s.cluster == nil || req.GetSessionId() != "" {
+		return routing.Candidate{}, false
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(forwardedHeader)) > 0 {
+		return routing.Candidate{}, false
+	}
+	model := req.GetModel()
+	if model == "" {
+		model = s.defaultModel
+	}
+	c, err := routing.Route(s.router.policy, model, s.candidates())
+	if err != nil || c.Local {
+		// a request nobody can serve gets its error from this host
+		return routing.Candidate{}, false
+	}
+	return c, true
+}
+
+// accept tells the host that forwarded the request in ctx, if any, that
+// this host has taken it on; send delivers the response headers
+func (s *Server) accept(ctx context.Context, send func(metadata.MD) error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(forwardedHeader)) > 0 {
+		// a peer that misses the header only answers the request twice
+		_ = send(metadata.Pairs(acceptedHeader, s.hostID))
+	}
+}
+
+// forwardContext carries the caller's credentials to a peer and marks the
+// request as already routed
+func (s *Server) forwardContext(ctx context.Context) context.Context {
+	md := metadata.Pairs(forwardedHeader, s.hostID)
+	if in, ok := metadata.FromIncomingContext(ctx); ok {
+		if auth := in.Get("authorization"); len(auth) > 0 {
+			md.Set("authorization", auth...)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// forward runs call against peer on the caller's behalf. The request is
+// registered under id so that Cancel on this host reaches the peer too.
+func (s *Server) forward(ctx context.Context, peer routing.Candidate, id string, call func(context.Context, chatpb.ChatServiceClient) error) (err error) {
+	ctx, span := tracing.Start(ctx, "forward",
+		attribute.String("llm.peer", peer.HostID), attribute.String("llm.routing_policy", s.router.policy.Name()))
+	defer func() { tracing.End(span, err) }()
+	cli, err := s.router.client(peer.Addr)
+	if err != nil {
+		return errPeerUnavailable
+	}
+	cancelled, done, err := s.inflight.start(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer s.router.begin(peer.HostID)()
+	s.logger.Info("forwarding chat request", "host", s.hostID, "request", id, "to", peer.HostID, "policy", s.router.policy.Name())
+
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-finished:
+		case <-cancelled.Done():
+			if !errors.Is(context.Cause(cancelled), errCancelled) {
+				return // the caller left, which the peer sees for itself
+			}
+			// let the peer end the reply the way a local cancel would
+			cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if _, err := cli.Cancel(s.forwardContext(cctx), &chatpb.CancelRequest{RequestId: id}); err != nil {
+				s.logger.Warn("forwarding cancel failed", "request", id, "to", peer.HostID, "err", err)
+			}
+		}
+	}()
+	return call(s.forwardContext(ctx), cli)
+}
+
+// unreachable reports whether err means the peer never got the request:
+// it is Unavailable and came before the peer's accepting header. The peer
+// itself may fail a request it took on with Unavailable too.
+func unreachable(err error, header metadata.MD) bool {
+	return status.Code(err) == codes.Unavailable && len(header.Get(acceptedHeader)) == 0
+}
+
+// forwardChat answers a unary Chat through peer
+func (s *Server) forwardChat(ctx context.Context, peer routing.Candidate, req *chatpb.ChatRequest) (*chatpb.ChatResponse, error) {
+	var resp *chatpb.ChatResponse
+	err := s.forward(ctx, peer, req.GetRequestId(), func(ctx context.Context, cli chatpb.ChatServiceClient) error {
+		var (
+			header metadata.MD
+			err    error
+		)
+		resp, err = cli.Chat(ctx, req, grpc.Header(&header))
+		if unreachable(err, header) {
+			return errPeerUnavailable
+		}
+		return err
+	})
+	return resp, err
+}
+
+// forwardStream relays peer's reply chunks to stream
+func (s *Server) forwardStream(peer routing.Candidate, req *chatpb.ChatRequest, stream chatpb.ChatService_ChatStreamServer) error {
+	return s.forward(stream.Context(), peer, req.GetRequestId(), func(ctx context.Context, cli chatpb.ChatServiceClient) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		upstream, err := cli.ChatStream(ctx, req)
+		relayed := false
+		for err == nil {
+			var chunk *chatpb.ChatChunk
+			if chunk, err = upstream.Recv(); err == nil {
+				relayed = true
+				err = stream.Send(chunk)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var header metadata.MD
+		if upstream != nil {
+			header, _ = upstream.Header()
+		}
+		if unreachable(err, header) && !relayed {
+			return errPeerUnavailable
+		}
+		return err
+	})
+}
@@ -0,0 +1,244 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth/authtest"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/routing"
+	"github.com/Billy-Davies-2/llm-test/pkg/server"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// startHost runs a clustered server on a real port, since peers dial each
+// other by the address they gossip, and returns a traced connection to it
+// signed in as ada with iss.
+func startHost(t *testing.T, name string, p routing.Policy, b backend.Backend, iss *authtest.Issuer, seeds ...string) (*gossip.Node, *grpc.ClientConn) {
+	t.Helper()
+	node, err := gossip.Start(gossip.Config{
+		Name:           name,
+		BindAddr:       "127.0.0.1:0",
+		Seeds:          seeds,
+		ProbeInterval:  100 * time.Millisecond,
+		GossipInterval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("gossip.Start(): unexpected error: %v", err)
+	}
+	t.Cleanup(func() { node.Shutdown() })
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	srv := server.NewServer(slog.New(slog.DiscardHandler), name, 0,
+		server.WithBackend(b), server.WithCluster(node, addr, addr), server.WithRouting(p),
+		server.WithAuth(iss.Provider(t), iss.ClientID))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(addr, append(tracing.DialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, "ada"))),
+	)...)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return node, conn
+}
+
+// startPair runs hosts a and b, both requiring tokens, and waits for a to see b's load,
+// returning a connection to a
+func startPair(t *testing.T, p routing.Policy, b backend.Backend) *grpc.ClientConn {
+	t.Helper()
+	iss := authtest.NewIssuer(t, "llm-client")
+	nodeA, connA := startHost(t, "host-a", p, backend.NewEcho(), iss)
+	startHost(t, "host-b", p, b, iss, nodeA.Addr())
+
+	metrics := metricspb.NewMetricsServiceClient(connA)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, err := metrics.ClusterState(context.Background(), &emptypb.Empty{})
+		if err != nil {
+			t.Fatalf("ClusterState(): unexpected error: %v", err)
+		}
+		if nodes := state.GetNodes(); len(nodes) == 2 && nodes[1].GetLoad() != nil {
+			return connA
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("host-a never saw host-b's load: %v", state)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestChatRoundRobin(t *testing.T) {
+	conn := startPair(t, routing.RoundRobin(), backend.NewEcho())
+	cli := chatpb.NewChatServiceClient(conn)
+	ctx := context.Background()
+
+	var hosts []string
+	for range 4 {
+		resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"})
+		if err != nil {
+			t.Fatalf("Chat(): unexpected error: %v", err)
+		}
+		hosts = append(hosts, resp.GetHostId())
+	}
+	want := []string{"host-a", "host-b", "host-a", "host-b"}
+	for i := range want {
+		if hosts[i] != want[i] {
+			t.Fatalf("answering hosts = %v; want %v", hosts, want)
+		}
+	}
+
+	// sessions live on one host, so their turns are never forwarded
+	sess, err := chatpb.NewSessionServiceClient(conn).CreateSession(ctx, &chatpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("CreateSession(): unexpected error: %v", err)
+	}
+	for range 2 {
+		resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", SessionId: sess.GetId()})
+		if err != nil {
+			t.Fatalf("Chat() in session: unexpected error: %v", err)
+		}
+		if resp.GetHostId() != "host-a" {
+			t.Errorf("session turn answered by %s; want host-a", resp.GetHostId())
+		}
+	}
+}
+
+func TestForwardedStreamCancel(t *testing.T) {
+	b := &stallBackend{stopped: make(chan struct{})}
+	cli := chatpb.NewChatServiceClient(startPair(t, routing.RoundRobin(), b))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the first request of the rotation stays on host-a; the second goes
+	// to host-b, which stalls
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "go on forever", RequestId: "r1"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	if first.GetHostId() != "host-b" || first.GetRequestId() != "r1" {
+		t.Errorf("first chunk = %v; want from host-b for r1", first)
+	}
+
+	// cancelling on the host the client talks to stops it on host-b
+	if _, err := cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r1"}); err != nil {
+		t.Fatalf("Cancel(): unexpected error: %v", err)
+	}
+	last, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() after Cancel: unexpected error: %v", err)
+	}
+	if last.GetFinishReason() != chatpb.FinishReason_FINISH_REASON_CANCELLED {
+		t.Errorf("finish reason = %v; want %v", last.GetFinishReason(), chatpb.FinishReason_FINISH_REASON_CANCELLED)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv() after final chunk = %v; want EOF", err)
+	}
+	<-b.stopped
+
+	_, err = cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r1"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Cancel() of finished request code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestForwardedBackendUnavailable(t *testing.T) {
+	cli := chatpb.NewChatServiceClient(startPair(t, routing.RoundRobin(), &downBackend{}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// host-b takes on every second request and its backend fails it; that
+	// is host-b's answer, not a sign host-a should run it again itself
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Chat() on host-b = %v, %v; want code %v", resp, err, codes.Unavailable)
+	}
+
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	chunk, err := stream.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv() from host-b = %v, %v; want code %v", chunk, err, codes.Unavailable)
+	}
+}
+
+func TestForwardedChatTrace(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	cli := chatpb.NewChatServiceClient(startPair(t, routing.RoundRobin(), backend.NewEcho()))
+
+	// the first request is answered by host-a, the second forwarded to b
+	spans.Reset()
+	ctx, root := tracing.Start(context.Background(), "test")
+	for range 2 {
+		if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); err != nil {
+			t.Fatalf("Chat(): unexpected error: %v", err)
+		}
+	}
+	root.End()
+
+	count := map[string]int{}
+	for _, s := range spans.GetSpans() {
+		if s.SpanContext.TraceID() != root.SpanContext().TraceID() {
+			continue
+		}
+		count[s.Name]++
+	}
+	want := map[string]int{
+		"test": 1,
+		// the client's call to a, the server span on a, and a's call to b
+		// with b's server span
+		"proto.ChatService/Chat": 6,
+		"forward":                1,
+		"queue":                  2,
+		"generate":               2,
+	}
+	for name, n := range want {
+		if count[name] != n {
+			t.Errorf("trace has %d %q spans; want %d (all spans: %v)", count[name], name, n, count)
+		}
+	}
+}
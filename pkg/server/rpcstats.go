@@ -0,0 +1,107 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// rpcStats counts the RPCs the server handles, by method and outcome, for
+// the Prometheus endpoint
+type rpcStats struct {
+	mu      sync.Mutex
+	methods map[string]*methodStats
+}
+
+type methodStats struct {
+	kind     string // unary, server_stream, client_stream or bidi_stream
+	started  uint64
+	handled  map[string]uint64 // by status code name
+	duration histogram
+}
+
+func (r *rpcStats) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := r.start(info.FullMethod, "unary")
+	resp, err := handler(ctx, req)
+	r.done(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (r *rpcStats) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	kind := "bidi_stream"
+	switch {
+	case !info.IsClientStream && info.IsServerStream:
+		kind = "server_stream"
+	case info.IsClientStream && !info.IsServerStream:
+		kind = "client_stream"
+	}
+	start := r.start(info.FullMethod, kind)
+	err := handler(srv, ss)
+	r.done(info.FullMethod, start, err)
+	return err
+}
+
+func (r *rpcStats) start(method, kind string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.methods == nil {
+		r.methods = map[string]*methodStats{}
+	}
+	m, ok := r.methods[method]
+	if !ok {
+		m = &methodStats{kind: kind, handled: map[string]uint64{}, duration: newHistogram()}
+		r.methods[method] = m
+	}
+	m.started++
+	return time.Now()
+}
+
+func (r *rpcStats) done(method string, start time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.methods[method]
+	m.handled[status.Code(err).String()]++
+	m.duration.observe(time.Since(start))
+}
+
+// rpcMethod is a copy of one method's counts
+type rpcMethod struct {
+	service, method, kind string
+	started               uint64
+	handled               map[string]uint64
+	duration              *metricspb.Histogram
+}
+
+// report copies every method's counts, sorted by full method name
+func (r *rpcStats) report() []rpcMethod {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]rpcMethod, 0, len(r.methods))
+	for full, m := range r.methods {
+		// full is "/package.Service/Method"
+		service, method, _ := strings.Cut(strings.TrimPrefix(full, "/"), "/")
+		handled := make(map[string]uint64, len(m.handled))
+		for code, n := range m.handled {
+			handled[code] = n
+		}
+		out = append(out, rpcMethod{
+			service:  service,
+			method:   method,
+			kind:     m.kind,
+			started:  m.started,
+			handled:  handled,
+			duration: m.duration.proto(),
+		})
+	}
+	slices.SortFunc(out, func(a, b rpcMethod) int {
+		return cmp.Or(cmp.Compare(a.service, b.service), cmp.Compare(a.method, b.method))
+	})
+	return out
+}
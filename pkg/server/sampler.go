@@ -0,0 +1,120 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+)
+
+// Default sampling cadence and how much history is kept
+const (
+	defaultSampleInterval = time.Second
+	defaultHistory        = 5 * time.Minute
+)
+
+// sampler measures the host on a fixed cadence. Usage figures such as CPU
+// are averages since the previous measurement, so taking them on a clock
+// rather than per request makes them independent of how many clients
+// poll and how often.
+type sampler struct {
+	interval time.Duration
+	measure  func() (*metricspb.MetricsResponse, error)
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	samples ring[*metricspb.MetricsResponse]
+}
+
+func newSampler(interval, history time.Duration, measure func() (*metricspb.MetricsResponse, error), logger *slog.Logger) *sampler {
+	return &sampler{
+		interval: interval,
+		measure:  measure,
+		logger:   logger,
+		samples:  newRing[*metricspb.MetricsResponse](max(int(history/interval), 1)),
+	}
+}
+
+// run samples every interval until done is closed
+func (s *sampler) run(done <-chan struct{}) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			s.sample()
+		}
+	}
+}
+
+// sample takes one measurement into the history
+func (s *sampler) sample() {
+	m, err := s.measure()
+	if err != nil {
+		s.logger.Warn("sampling host metrics failed", "err", err)
+		return
+	}
+	s.mu.Lock()
+	s.samples.push(m)
+	s.mu.Unlock()
+}
+
+// latest is the most recent sample, nil before the first. Samples are
+// shared and must not be modified.
+func (s *sampler) latest() *metricspb.MetricsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.samples.last()
+}
+
+// since returns the samples taken at or after t, oldest first
+func (s *sampler) since(t time.Time) []*metricspb.MetricsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*metricspb.MetricsResponse
+	for _, m := range s.samples.items() {
+		if !m.GetSampledAt().AsTime().Before(t) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// ring is a fixed-size buffer that overwrites its oldest item when full
+type ring[T any] struct {
+	buf  []T
+	next int // where the next push goes
+	full bool
+}
+
+func newRing[T any](size int) ring[T] {
+	return ring[T]{buf: make([]T, size)}
+}
+
+func (r *ring[T]) push(v T) {
+	r.buf[r.next] = v
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// items returns the contents oldest first
+func (r *ring[T]) items() []T {
+	if !r.full {
+		return append([]T(nil), r.buf[:r.next]...)
+	}
+	return append(append([]T(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+}
+
+// last returns the newest item, or the zero value when empty
+func (r *ring[T]) last() T {
+	if !r.full && r.next == 0 {
+		var zero T
+		return zero
+	}
+	return r.buf[(r.next+len(r.buf)-1)%len(r.buf)]
+}
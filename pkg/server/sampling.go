@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for sampling fields a request leaves unset
+const (
+	defaultTemperature       = 0.8
+	defaultTopP              = 0.95
+	defaultTopK              = 40
+	defaultRepetitionPenalty = 1.1
+	defaultMaxTokens         = 1024
+)
+
+// Limits on what a request may ask for
+const (
+	maxTemperature       = 2
+	maxRepetitionPenalty = 2
+	maxMaxTokens         = 32768
+	maxStopSequences     = 8
+	maxStopLength        = 64
+)
+
+// generation is a validated SamplingParams with defaults filled in
+type generation struct {
+	sampling  backend.Sampling
+	maxTokens int
+	stop      []string
+}
+
+// samplingParams validates p and fills in the defaults. A nil p gives
+// the defaults.
+func samplingParams(p *chatpb.SamplingParams) (generation, error) {
+	g := generation{
+		sampling: backend.Sampling{
+			Temperature:       defaultTemperature,
+			TopP:              defaultTopP,
+			TopK:              defaultTopK,
+			RepetitionPenalty: defaultRepetitionPenalty,
+			Seed:              -1,
+		},
+		maxTokens: defaultMaxTokens,
+	}
+	if p == nil {
+		return g, nil
+	}
+	if p.Temperature != nil {
+		// written so NaN fails too
+		if t := p.GetTemperature(); !(t >= 0 && t <= maxTemperature) {
+			return g, invalidSampling("temperature must be between 0 and %d, got %v", maxTemperature, t)
+		}
+		g.sampling.Temperature = float64(p.GetTemperature())
+	}
+	if p.TopP != nil {
+		if v := p.GetTopP(); !(v > 0 && v <= 1) {
+			return g, invalidSampling("top_p must be in (0, 1], got %v", v)
+		}
+		g.sampling.TopP = float64(p.GetTopP())
+	}
+	if p.TopK != nil {
+		g.sampling.TopK = int(p.GetTopK())
+	}
+	if p.RepetitionPenalty != nil {
+		if v := p.GetRepetitionPenalty(); !(v > 0 && v <= maxRepetitionPenalty) {
+			return g, invalidSampling("repetition_penalty must be in (0, %d], got %v", maxRepetitionPenalty, v)
+		}
+		g.sampling.RepetitionPenalty = float64(p.GetRepetitionPenalty())
+	}
+	if p.Seed != nil {
+		g.sampling.Seed = int64(p.GetSeed())
+	}
+	if p.MaxTokens != nil {
+		if v := p.GetMaxTokens(); v < 1 || v > maxMaxTokens {
+			return g, invalidSampling("max_tokens must be between 1 and %d, got %d", maxMaxTokens, v)
+		}
+		g.maxTokens = int(p.GetMaxTokens())
+	}
+	if len(p.GetStop()) > maxStopSequences {
+		return g, invalidSampling("at most %d stop sequences are allowed, got %d", maxStopSequences, len(p.GetStop()))
+	}
+	for _, s := range p.GetStop() {
+		if s == "" || len(s) > maxStopLength {
+			return g, invalidSampling("stop sequences must be 1 to %d bytes, got %q", maxStopLength, s)
+		}
+	}
+	g.stop = p.GetStop()
+	return g, nil
+}
+
+func invalidSampling(format string, args ...any) error {
+	return status.Error(codes.InvalidArgument, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"math"
+	"testing"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSamplingDefaults(t *testing.T) {
+	g, err := samplingParams(&chatpb.SamplingParams{Temperature: proto.Float32(0), Seed: proto.Uint32(42)})
+	if err != nil {
+		t.Fatalf("samplingParams(): unexpected error: %v", err)
+	}
+	if g.sampling.Temperature != 0 {
+		t.Errorf("explicit zero temperature became %v", g.sampling.Temperature)
+	}
+	if g.sampling.Seed != 42 {
+		t.Errorf("seed = %d; want 42", g.sampling.Seed)
+	}
+	if g.sampling.TopP != defaultTopP || g.maxTokens != defaultMaxTokens {
+		t.Errorf("unset fields = top_p %v, max_tokens %d; want defaults", g.sampling.TopP, g.maxTokens)
+	}
+
+	g, err = samplingParams(nil)
+	if err != nil {
+		t.Fatalf("samplingParams(nil): unexpected error: %v", err)
+	}
+	if g.sampling.Seed >= 0 || g.sampling.Temperature != defaultTemperature {
+		t.Errorf("samplingParams(nil) = %+v; want defaults with a random seed", g)
+	}
+}
+
+func TestSamplingValidation(t *testing.T) {
+	for name, p := range map[string]*chatpb.SamplingParams{
+		"temperature too high": {Temperature: proto.Float32(2.5)},
+		"temperature NaN":      {Temperature: proto.Float32(float32(math.NaN()))},
+		"zero top_p":           {TopP: proto.Float32(0)},
+		"negative penalty":     {RepetitionPenalty: proto.Float32(-1)},
+		"zero max_tokens":      {MaxTokens: proto.Uint32(0)},
+		"empty stop":           {Stop: []string{""}},
+		"too many stops":       {Stop: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}},
+	} {
+		if _, err := samplingParams(p); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: code = %v; want %v", name, status.Code(err), codes.InvalidArgument)
+		}
+	}
+}
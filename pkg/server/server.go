@@ -6,33 +6,238 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"sync"
+	"time"
 
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	"github.com/Billy-Davies-2/llm-test/pkg/models"
 	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
 	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
-	"github.com/shirou/gopsutil/cpu"
+	modelspb "github.com/Billy-Davies-2/llm-test/pkg/proto/models"
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+	"github.com/Billy-Davies-2/llm-test/pkg/scheduler"
+	"github.com/Billy-Davies-2/llm-test/pkg/shard"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	oidc "github.com/coreos/go-oidc"
 	"github.com/shirou/gopsutil/mem"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// defaultWatchInterval is the shortest interval WatchMetrics streams at
+// unless WithWatchInterval says otherwise
+const defaultWatchInterval = time.Second
+
 // Server wraps the gRPC server for metrics reporting
 type Server struct {
-	logger *slog.Logger
-	hostID string
-	port   int
-	grpc   *grpc.Server
+	logger   *slog.Logger
+	hostID   string
+	port     int
+	grpc     *grpc.Server
+	backend  backend.Backend
+	sessions *SessionStore
+	models   *models.Registry
+
+	// pool serves per-request model selection when set
+	pool         *backend.Pool
+	defaultModel string
+
+	// staticModel caches the name of the model behind backend
+	staticMu    sync.Mutex
+	staticModel string
+
+	// running generations, for Cancel
+	inflight inflight
+
+	// how each model has performed, for MetricsResponse
+	stats inferenceStats
+
+	// RPCs handled, for MetricsHandler
+	rpcs rpcStats
+
+	// admits generations, queueing any beyond its limit
+	sched *scheduler.Scheduler
+
+	// gossip membership this host advertises itself through, if any
+	cluster     *gossip.Node
+	chatAddr    string
+	metricsAddr string
+	hardware    *metricspb.Hardware
+	metrics     *metricsService
+
+	// shortest interval WatchMetrics will stream at
+	watchInterval time.Duration
+
+	// how often host metrics are sampled, and for how long they are kept
+	sampleInterval time.Duration
+	history        time.Duration
+
+	// sends requests to other hosts of the cluster
+	router *router
+
+	// runs pipeline stages for other hosts
+	shards *shard.Service
+
+	// check callers' bearer tokens; nil serves everyone anonymously
+	authUnary  grpc.UnaryServerInterceptor
+	authStream grpc.StreamServerInterceptor
+	admins     []string // token subjects allowed to load and unload models
+
+	stopOnce sync.Once
+	done     chan struct{} // closed by Stop
 	chatpb.UnimplementedChatServiceServer
 	metricspb.UnimplementedMetricsServiceServer
 }
 
-// NewServer constructs a metrics server for a given hostID and port
-func NewServer(logger *slog.Logger, hostID string, port int) *Server {
-	s := grpc.NewServer()
-	impl := &metricsService{hostID: hostID}
-	metricspb.RegisterMetricsServiceServer(s, impl)
-	reflection.Register(s)
-	return &Server{logger: logger, hostID: hostID, port: port, grpc: s}
+// Option customises a Server built by NewServer
+type Option func(*Server)
+
+// WithBackend sets the inference backend Chat delegates to.
+// The default is the canned-reply echo backend.
+func WithBackend(b backend.Backend) Option {
+	return func(s *Server) { s.backend = b }
+}
+
+// WithSessions sets the store that chat sessions are kept in.
+// The default keeps sessions in memory only.
+func WithSessions(st *SessionStore) Option {
+	return func(s *Server) { s.sessions = st }
+}
+
+// WithModelRegistry sets the catalog that ListModels reports.
+// Without one the host reports no models.
+func WithModelRegistry(r *models.Registry) Option {
+	return func(s *Server) { s.models = r }
+}
+
+// WithModelPool lets requests pick a model, loading it into p on demand.
+// Requests that name no model use defaultModel, and are rejected if that
+// is empty too. The backend set with WithBackend is not used.
+func WithModelPool(p *backend.Pool, defaultModel string) Option {
+	return func(s *Server) {
+		s.pool = p
+		s.defaultModel = defaultModel
+	}
+}
+
+// WithScheduler sets how many generations may run at once and how many
+// requests may queue for a slot. The default allows 4 and 64.
+func WithScheduler(sc *scheduler.Scheduler) Option {
+	return func(s *Server) { s.sched = sc }
+}
+
+// WithShards serves svc, letting other hosts run a share of a model's
+// layers on this one.
+func WithShards(svc *shard.Service) Option {
+	return func(s *Server) { s.shards = svc }
+}
+
+// WithWatchInterval sets the shortest interval WatchMetrics streams
+// snapshots at; clients asking for less get this. The default is 1s.
+func WithWatchInterval(d time.Duration) Option {
+	return func(s *Server) { s.watchInterval = d }
+}
+
+// WithSampling sets how often the host's metrics are measured and how long
+// the samples are kept for GetMetricsHistory. The defaults are 1s and 5m.
+func WithSampling(interval, history time.Duration) Option {
+	return func(s *Server) {
+		s.sampleInterval = interval
+		s.history = history
+	}
+}
+
+// WithAuth requires every call but health checks and reflection to carry
+// a bearer token from provider issued to clientID, and makes the caller
+// available to handlers through auth.IdentityFromContext. Without it
+// calls are anonymous, and session calls are refused.
+func WithAuth(provider *oidc.Provider, clientID string) Option {
+	return func(s *Server) {
+		public := auth.AllowUnauthenticated(auth.PublicMethods...)
+		// neither constructor can fail
+		s.authUnary, _ = auth.UnaryServerInterceptor(provider, clientID, public)
+		s.authStream, _ = auth.StreamServerInterceptor(provider, clientID, public)
+	}
+}
+
+// WithAdmins lets the callers whose tokens have these subjects load and
+// unload models. Under WithAuth nobody else can; without it every caller
+// is anonymous and may.
+func WithAdmins(subjects ...string) Option {
+	return func(s *Server) { s.admins = subjects }
+}
+
+// NewServer constructs a metrics and chat server for a given hostID and port
+func NewServer(logger *slog.Logger, hostID string, port int, opts ...Option) *Server {
+	srv := &Server{logger: logger, hostID: hostID, port: port, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	// calls are traced and counted whether or not they are authorised
+	unary := []grpc.UnaryServerInterceptor{tracing.UnaryServerInterceptor(), srv.rpcs.unary}
+	stream := []grpc.StreamServerInterceptor{tracing.StreamServerInterceptor(), srv.rpcs.stream}
+	if srv.authUnary != nil {
+		unary = append(unary, srv.authUnary)
+		stream = append(stream, srv.authStream)
+	}
+	g := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	srv.grpc = g
+	if srv.backend == nil {
+		srv.backend = backend.NewEcho()
+	}
+	if srv.sched == nil {
+		srv.sched = scheduler.New(4, 64)
+	}
+	if srv.watchInterval <= 0 {
+		srv.watchInterval = defaultWatchInterval
+	}
+	if srv.sampleInterval <= 0 {
+		srv.sampleInterval = defaultSampleInterval
+	}
+	if srv.history <= 0 {
+		srv.history = defaultHistory
+	}
+	if srv.sessions == nil {
+		srv.sessions, _ = OpenSessionStore("")
+	}
+	srv.hardware = hostHardware()
+	impl := &metricsService{
+		logger:   logger,
+		hostID:   hostID,
+		resident: srv.residentModels,
+		sched:    srv.sched,
+		cluster:  srv.cluster,
+		self:     srv.nodeInfo,
+		minWatch: srv.watchInterval,
+		done:     srv.done,
+		stats:    &srv.stats,
+	}
+	impl.samples = newSampler(srv.sampleInterval, srv.history, impl.measure, logger)
+	// the first sample is taken now so metrics are served from the start,
+	// though its CPU figures wait for a second to compare with
+	impl.samples.sample()
+	go impl.samples.run(srv.done)
+	srv.metrics = impl
+	if srv.cluster != nil {
+		go srv.advertise()
+	}
+	metricspb.RegisterMetricsServiceServer(g, impl)
+	chatpb.RegisterChatServiceServer(g, srv)
+	chatpb.RegisterSessionServiceServer(g, &sessionService{store: srv.sessions})
+	modelspb.RegisterModelServiceServer(g, &modelService{hostID: hostID, registry: srv.models, pool: srv.pool, admin: srv.admin})
+	if srv.shards != nil {
+		shardpb.RegisterShardServiceServer(g, srv.shards)
+	}
+	reflection.Register(g)
+	return srv
 }
 
 // Run starts listening on the configured port and serves gRPC requests
@@ -43,6 +248,11 @@ func (s *Server) Run() error {
 	if err != nil {
 		return fmt.Errorf("listen %s: %w", addr, err)
 	}
+	return s.Serve(lis)
+}
+
+// Serve serves gRPC requests on an existing listener
+func (s *Server) Serve(lis net.Listener) error {
 	if err := s.grpc.Serve(lis); err != nil {
 		s.logger.Error("grpc serve failed", "err", err)
 		return err
@@ -50,26 +260,101 @@ func (s *Server) Run() error {
 	return nil
 }
 
+// Stop gracefully stops the gRPC server
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+	s.grpc.GracefulStop()
+	if s.router != nil {
+		s.router.close()
+	}
+}
+
 // metricsService implements the MetricsServiceServer interface
 // backed by gopsutil for CPU and memory stats
 type metricsService struct {
 	metricspb.UnimplementedMetricsServiceServer
-	hostID string
+	logger   *slog.Logger
+	hostID   string
+	resident func() []*metricspb.ResidentModel
+	sched    *scheduler.Scheduler
+	cluster  *gossip.Node
+	self     func() *metricspb.NodeInfo
+	minWatch time.Duration
+	done     <-chan struct{} // ends watches when the server stops
+	cpu      cpuUsage
+	net      netRates
+	samples  *sampler
+	stats    *inferenceStats
 }
 
 func (m *metricsService) GetMetrics(
 	ctx context.Context,
 	_ *emptypb.Empty,
 ) (*metricspb.MetricsResponse, error) {
+	return m.snapshot()
+}
+
+// WatchMetrics streams a snapshot now and then one every interval, no
+// more often than the server's minimum.
+func (m *metricsService) WatchMetrics(req *metricspb.WatchMetricsRequest, stream metricspb.MetricsService_WatchMetricsServer) error {
+	interval := max(req.GetInterval().AsDuration(), m.minWatch)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		snap, err := m.snapshot()
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(snap); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-m.done:
+			// GracefulStop waits for every stream to end
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-t.C:
+		}
+	}
+}
+
+// GetMetricsHistory returns the kept samples within the window asked for.
+func (m *metricsService) GetMetricsHistory(ctx context.Context, req *metricspb.MetricsHistoryRequest) (*metricspb.MetricsHistoryResponse, error) {
+	var since time.Time
+	if w := req.GetWindow(); w != nil {
+		since = time.Now().Add(-w.AsDuration())
+	}
+	return &metricspb.MetricsHistoryResponse{
+		Samples:  m.samples.since(since),
+		Interval: durationpb.New(m.samples.interval),
+	}, nil
+}
+
+// snapshot is the latest sample with the scheduler and model figures,
+// which are exact counts rather than averages, read as they are now
+func (m *metricsService) snapshot() (*metricspb.MetricsResponse, error) {
+	latest := m.samples.latest()
+	if latest == nil {
+		return nil, status.Error(codes.Unavailable, "no metrics sampled yet")
+	}
+	resp := proto.Clone(latest).(*metricspb.MetricsResponse)
+	load := m.sched.Stats()
+	resp.ResidentModels = m.resident()
+	resp.QueueDepth = uint32(load.Queued)
+	resp.ActiveGenerations = uint32(load.Active)
+	resp.MaxConcurrentGenerations = uint32(load.MaxActive)
+	resp.ModelStats = m.stats.report()
+	return resp, nil
+}
+
+// measure reads the host's current load
+func (m *metricsService) measure() (*metricspb.MetricsResponse, error) {
 	// CPU usage
-	perc, err := cpu.Percent(0, false)
+	cpuPct, cores, err := m.cpu.sample()
 	if err != nil {
 		return nil, err
 	}
-	cpuPct := 0.0
-	if len(perc) > 0 {
-		cpuPct = perc[0]
-	}
 
 	// Memory usage
 	vm, err := mem.VirtualMemory()
@@ -77,26 +362,21 @@ func (m *metricsService) GetMetrics(
 		return nil, err
 	}
 
-	return &metricspb.MetricsResponse{
-		HostId:          m.hostID,
-		CpuUsagePercent: cpuPct,
-		MemoryUsedMb:    float64(vm.Used) / 1024 / 1024,
-		MemoryTotalMb:   float64(vm.Total) / 1024 / 1024,
-	}, nil
-}
-
-// Chat implements metrics.ChatServiceServer.Chat
-func (s *Server) Chat(ctx context.Context, req *chatpb.ChatRequest) (*chatpb.ChatResponse, error) {
-	// Log which server handled it and what was asked
-	s.logger.Info("Chat request",
-		"host", s.hostID,
-		"prompt", req.GetText(),
-	)
-
-	// For now: echo a canned AI reply
-	reply := "🤖 This is a canned AI response."
-	return &chatpb.ChatResponse{
-		HostId: s.hostID,
-		Text:   reply,
-	}, nil
+	load := m.sched.Stats()
+	resp := &metricspb.MetricsResponse{
+		HostId:                   m.hostID,
+		CpuUsagePercent:          cpuPct,
+		CpuCorePercent:           cores,
+		MemoryUsedMb:             float64(vm.Used) / 1024 / 1024,
+		MemoryTotalMb:            float64(vm.Total) / 1024 / 1024,
+		ResidentModels:           m.resident(),
+		QueueDepth:               uint32(load.Queued),
+		ActiveGenerations:        uint32(load.Active),
+		MaxConcurrentGenerations: uint32(load.MaxActive),
+		SampledAt:                timestamppb.Now(),
+	}
+	m.addHostStats(resp)
+	m.stats.tick()
+	resp.ModelStats = m.stats.report()
+	return resp, nil
 }
@@ -0,0 +1,1007 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/auth/authtest"
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	"github.com/Billy-Davies-2/llm-test/pkg/models"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	modelspb "github.com/Billy-Davies-2/llm-test/pkg/proto/models"
+	"github.com/Billy-Davies-2/llm-test/pkg/scheduler"
+	"github.com/Billy-Davies-2/llm-test/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const bufSize = 1024 * 1024
+
+// startServer runs srv on an in-memory listener and returns a client conn
+// dialed with opts.
+func startServer(t *testing.T, srv *server.Server, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(bufSize)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append([]grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// signIn makes a server require tokens from a test issuer, returning the
+// server option and a dial option sending user's token.
+func signIn(t *testing.T, user string) (server.Option, grpc.DialOption) {
+	t.Helper()
+	iss := authtest.NewIssuer(t, "llm-client")
+	return server.WithAuth(iss.Provider(t), iss.ClientID),
+		grpc.WithPerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, user)))
+}
+
+func newTestServer(t *testing.T, opts ...server.Option) (*server.Server, chatpb.ChatServiceClient) {
+	t.Helper()
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, opts...)
+	return srv, chatpb.NewChatServiceClient(startServer(t, srv))
+}
+
+// downBackend fails every call as if the engine were unreachable.
+type downBackend struct{ backend.Echo }
+
+func (downBackend) Generate(context.Context, backend.Request) (*backend.Result, error) {
+	return nil, backend.ErrUnavailable
+}
+
+func (downBackend) Stream(context.Context, backend.Request, func(string) error) (*backend.Result, error) {
+	return nil, backend.ErrUnavailable
+}
+
+func TestChat(t *testing.T) {
+	_, cli := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hello there"})
+	if err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	if got, want := resp.GetHostId(), "test-host"; got != want {
+		t.Errorf("HostId = %q; want %q", got, want)
+	}
+	if resp.GetText() == "" {
+		t.Error("Text is empty")
+	}
+	if resp.GetUsage().GetPromptTokens() == 0 {
+		t.Error("PromptTokens = 0; want the rendered prompt counted")
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	_, cli := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	unary, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hello"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	var chunks []*chatpb.ChatChunk
+	for {
+		c, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv(): unexpected error: %v", err)
+		}
+		chunks = append(chunks, c)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks; want at least 2", len(chunks))
+	}
+
+	var text string
+	for _, c := range chunks[:len(chunks)-1] {
+		if c.GetFinishReason() != chatpb.FinishReason_FINISH_REASON_UNSPECIFIED {
+			t.Errorf("intermediate chunk has finish reason %v", c.GetFinishReason())
+		}
+		text += c.GetDelta()
+	}
+	if text != unary.GetText() {
+		t.Errorf("streamed text = %q; want %q", text, unary.GetText())
+	}
+
+	last := chunks[len(chunks)-1]
+	if got, want := last.GetFinishReason(), chatpb.FinishReason_FINISH_REASON_STOP; got != want {
+		t.Errorf("FinishReason = %v; want %v", got, want)
+	}
+	u := last.GetUsage()
+	if got, want := u.GetCompletionTokens(), uint32(len(chunks)-1); got != want {
+		t.Errorf("CompletionTokens = %d; want %d", got, want)
+	}
+	if u.GetTotalTokens() != u.GetPromptTokens()+u.GetCompletionTokens() {
+		t.Errorf("TotalTokens = %d; want prompt+completion", u.GetTotalTokens())
+	}
+}
+
+func TestChatBackendUnavailable(t *testing.T) {
+	_, cli := newTestServer(t, server.WithBackend(&downBackend{}))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if got := status.Code(err); got != codes.Unavailable {
+		t.Errorf("Chat() code = %v; want %v", got, codes.Unavailable)
+	}
+}
+
+func TestChatRequiresInput(t *testing.T) {
+	_, cli := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for name, req := range map[string]*chatpb.ChatRequest{
+		"empty":           {},
+		"bad role":        {Messages: []*chatpb.ChatMessage{{Content: "hi"}}},
+		"prompt and text": {Prompt: "Once upon a time", Text: "hi"},
+	} {
+		if _, err := cli.Chat(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: Chat() code = %v; want %v", name, status.Code(err), codes.InvalidArgument)
+		}
+	}
+}
+
+func TestChatSampling(t *testing.T) {
+	_, cli := newTestServer(t, server.WithBackend(&backend.Echo{Reply: "one two three"}))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", Sampling: &chatpb.SamplingParams{MaxTokens: proto.Uint32(2)}})
+	if err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	if resp.GetText() != "one two " || resp.GetFinishReason() != chatpb.FinishReason_FINISH_REASON_LENGTH {
+		t.Errorf("Chat() = %q (%v); want reply cut at 2 tokens", resp.GetText(), resp.GetFinishReason())
+	}
+
+	_, err = cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", Sampling: &chatpb.SamplingParams{TopP: proto.Float32(1.5)}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Chat() with top_p 1.5 code = %v; want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestChatSession(t *testing.T) {
+	withAuth, creds := signIn(t, "ada")
+	conn := startServer(t, server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, withAuth), creds)
+	cli := chatpb.NewChatServiceClient(conn)
+	sessions := chatpb.NewSessionServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	sess, err := sessions.CreateSession(ctx, &chatpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("CreateSession(): unexpected error: %v", err)
+	}
+	for _, text := range []string{"first question", "follow up"} {
+		resp, err := cli.Chat(ctx, &chatpb.ChatRequest{SessionId: sess.GetId(), Text: text})
+		if err != nil {
+			t.Fatalf("Chat(%q): unexpected error: %v", text, err)
+		}
+		if resp.GetSessionId() != sess.GetId() {
+			t.Errorf("SessionId = %q; want %q", resp.GetSessionId(), sess.GetId())
+		}
+	}
+
+	got, err := sessions.GetSession(ctx, &chatpb.GetSessionRequest{Id: sess.GetId()})
+	if err != nil {
+		t.Fatalf("GetSession(): unexpected error: %v", err)
+	}
+	if got.GetMessageCount() != 4 || got.GetMessages()[2].GetContent() != "follow up" {
+		t.Errorf("session messages = %v", got.GetMessages())
+	}
+	if got.GetTitle() != "first question" {
+		t.Errorf("Title = %q; want %q", got.GetTitle(), "first question")
+	}
+
+	if _, err := sessions.RenameSession(ctx, &chatpb.RenameSessionRequest{Id: sess.GetId(), Title: "renamed"}); err != nil {
+		t.Fatalf("RenameSession(): unexpected error: %v", err)
+	}
+	list, err := sessions.ListSessions(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("ListSessions(): unexpected error: %v", err)
+	}
+	if len(list.GetSessions()) != 1 || list.GetSessions()[0].GetTitle() != "renamed" {
+		t.Errorf("ListSessions() = %v", list.GetSessions())
+	}
+
+	if _, err := sessions.DeleteSession(ctx, &chatpb.DeleteSessionRequest{Id: sess.GetId()}); err != nil {
+		t.Fatalf("DeleteSession(): unexpected error: %v", err)
+	}
+	_, err = cli.Chat(ctx, &chatpb.ChatRequest{SessionId: sess.GetId(), Text: "hello?"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Chat() on deleted session code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestSessionsNeedCaller(t *testing.T) {
+	conn := startServer(t, server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0))
+	sessions := chatpb.NewSessionServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// without auth every caller is anonymous, and anonymous callers get
+	// no sessions rather than one shared by all of them
+	if _, err := sessions.CreateSession(ctx, &chatpb.CreateSessionRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("CreateSession() error = %v; want Unauthenticated", err)
+	}
+	if _, err := sessions.ListSessions(ctx, &emptypb.Empty{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ListSessions() error = %v; want Unauthenticated", err)
+	}
+	if _, err := sessions.GetSession(ctx, &chatpb.GetSessionRequest{Id: "any"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetSession() error = %v; want Unauthenticated", err)
+	}
+	_, err := chatpb.NewChatServiceClient(conn).Chat(ctx, &chatpb.ChatRequest{Text: "hi", SessionId: "any"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Chat() in a session error = %v; want Unauthenticated", err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	iss := authtest.NewIssuer(t, "llm-client")
+	conn := startServer(t, server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, server.WithAuth(iss.Provider(t), "llm-client")))
+	cli := chatpb.NewChatServiceClient(conn)
+	metrics := metricspb.NewMetricsServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// every call is refused without a token, streams included
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Chat() without token error = %v; want Unauthenticated", err)
+	}
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ChatStream() without token error = %v; want Unauthenticated", err)
+	}
+	watch, err := metrics.WatchMetrics(ctx, &metricspb.WatchMetricsRequest{})
+	if err == nil {
+		_, err = watch.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("WatchMetrics() without token error = %v; want Unauthenticated", err)
+	}
+
+	// but reflection is public
+	refl, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo(): unexpected error: %v", err)
+	}
+	if err := refl.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+		t.Fatalf("Send(): unexpected error: %v", err)
+	}
+	if _, err := refl.Recv(); err != nil {
+		t.Errorf("reflection without token: unexpected error: %v", err)
+	}
+
+	creds := grpc.PerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, "ada")))
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}, creds); err != nil {
+		t.Errorf("Chat() with token: unexpected error: %v", err)
+	}
+	stream, err = cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hi"}, creds)
+	if err != nil {
+		t.Fatalf("ChatStream() with token: unexpected error: %v", err)
+	}
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ChatStream() with token: unexpected error: %v", err)
+		}
+	}
+}
+
+func TestListModels(t *testing.T) {
+	dir := t.TempDir()
+	reg := models.NewRegistry(dir, slog.New(slog.DiscardHandler))
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, server.WithModelRegistry(reg))
+	cli := modelspb.NewModelServiceClient(startServer(t, srv))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := cli.ListModels(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("ListModels(): unexpected error: %v", err)
+	}
+	if resp.GetHostId() != "test-host" || len(resp.GetModels()) != 0 {
+		t.Errorf("ListModels() = %v; want empty catalog for test-host", resp)
+	}
+}
+
+func TestChatModelPool(t *testing.T) {
+	pool := backend.NewPool(1, func(_ context.Context, model string) (backend.Backend, error) {
+		if model != "small" && model != "large" {
+			return nil, fmt.Errorf("%w: %s", models.ErrModelNotFound, model)
+		}
+		return &backend.Echo{Reply: "from " + model}, nil
+	})
+	defer pool.Close()
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, server.WithModelPool(pool, "small"))
+	conn := startServer(t, srv)
+	cli := chatpb.NewChatServiceClient(conn)
+	modelCli := modelspb.NewModelServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	if resp.GetModel() != "small" || resp.GetText() != "from small" {
+		t.Errorf("Chat() with default model = %q from %q; want reply from small", resp.GetText(), resp.GetModel())
+	}
+	resp, err = cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", Model: "large"})
+	if err != nil {
+		t.Fatalf("Chat(large): unexpected error: %v", err)
+	}
+	if resp.GetModel() != "large" || resp.GetText() != "from large" {
+		t.Errorf("Chat(large) = %q from %q; want reply from large", resp.GetText(), resp.GetModel())
+	}
+
+	metrics, err := metricspb.NewMetricsServiceClient(conn).GetMetrics(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	if rm := metrics.GetResidentModels(); len(rm) != 1 || rm[0].GetName() != "large" {
+		t.Errorf("ResidentModels = %v; want only large", rm)
+	}
+
+	if _, err := modelCli.UnloadModel(ctx, &modelspb.UnloadModelRequest{Name: "large"}); err != nil {
+		t.Fatalf("UnloadModel(): unexpected error: %v", err)
+	}
+	if _, err := modelCli.UnloadModel(ctx, &modelspb.UnloadModelRequest{Name: "large"}); status.Code(err) != codes.NotFound {
+		t.Errorf("UnloadModel() twice code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+	if _, err := modelCli.LoadModel(ctx, &modelspb.LoadModelRequest{Name: "small"}); err != nil {
+		t.Fatalf("LoadModel(): unexpected error: %v", err)
+	}
+	_, err = cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", Model: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Chat(missing) code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestChatUnknownModelKeepsResident(t *testing.T) {
+	dir := t.TempDir()
+	// a GGUF header with no metadata is enough for the catalog to list it
+	header := []byte{'G', 'G', 'U', 'F', 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if err := os.WriteFile(filepath.Join(dir, "small.gguf"), header, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg := models.NewRegistry(dir, slog.New(slog.DiscardHandler))
+	if err := reg.Scan(); err != nil {
+		t.Fatalf("Scan(): unexpected error: %v", err)
+	}
+	pool := backend.NewPool(1, func(_ context.Context, model string) (backend.Backend, error) {
+		return &backend.Echo{Reply: "from " + model}, nil
+	})
+	defer pool.Close()
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0,
+		server.WithModelPool(pool, "small"), server.WithModelRegistry(reg))
+	conn := startServer(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := modelspb.NewModelServiceClient(conn).LoadModel(ctx, &modelspb.LoadModelRequest{Name: "small"}); err != nil {
+		t.Fatalf("LoadModel(small): unexpected error: %v", err)
+	}
+	_, err := chatpb.NewChatServiceClient(conn).Chat(ctx, &chatpb.ChatRequest{Text: "hi", Model: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Chat(nope) code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+	_, err = modelspb.NewModelServiceClient(conn).LoadModel(ctx, &modelspb.LoadModelRequest{Name: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("LoadModel(nope) code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+	if rm := pool.Resident(); len(rm) != 1 || rm[0].Name != "small" {
+		t.Errorf("resident models = %v; want small still loaded", rm)
+	}
+}
+
+func TestModelAdmins(t *testing.T) {
+	iss := authtest.NewIssuer(t, "llm-client")
+	pool := backend.NewPool(1, func(_ context.Context, model string) (backend.Backend, error) {
+		return &backend.Echo{Reply: "from " + model}, nil
+	})
+	defer pool.Close()
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0,
+		server.WithModelPool(pool, "small"), server.WithAuth(iss.Provider(t), iss.ClientID), server.WithAdmins("root"))
+	cli := modelspb.NewModelServiceClient(startServer(t, srv))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ada := grpc.PerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, "ada")))
+	root := grpc.PerRPCCredentials(auth.PerRPCCredentials(iss.Token(t, "root")))
+
+	if _, err := cli.LoadModel(ctx, &modelspb.LoadModelRequest{Name: "small"}, ada); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("LoadModel() by a non-admin code = %v; want %v", status.Code(err), codes.PermissionDenied)
+	}
+	if _, err := cli.LoadModel(ctx, &modelspb.LoadModelRequest{Name: "small"}, root); err != nil {
+		t.Fatalf("LoadModel() by an admin: unexpected error: %v", err)
+	}
+	if _, err := cli.UnloadModel(ctx, &modelspb.UnloadModelRequest{Name: "small"}, ada); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("UnloadModel() by a non-admin code = %v; want %v", status.Code(err), codes.PermissionDenied)
+	}
+	if _, err := cli.UnloadModel(ctx, &modelspb.UnloadModelRequest{Name: "small"}, root); err != nil {
+		t.Errorf("UnloadModel() by an admin: unexpected error: %v", err)
+	}
+}
+
+func TestLoadModelFixedBackend(t *testing.T) {
+	conn := startServer(t, server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := chatpb.NewChatServiceClient(conn).Chat(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	if resp.GetModel() != "echo" {
+		t.Errorf("Chat() model = %q; want %q", resp.GetModel(), "echo")
+	}
+	_, err = modelspb.NewModelServiceClient(conn).LoadModel(ctx, &modelspb.LoadModelRequest{Name: "echo"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("LoadModel() code = %v; want %v", status.Code(err), codes.FailedPrecondition)
+	}
+}
+
+// stallBackend emits one token and then blocks until its request is
+// cancelled, closing stopped the first time it sees that.
+type stallBackend struct {
+	backend.Echo
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (b *stallBackend) stop() { b.once.Do(func() { close(b.stopped) }) }
+
+func (b *stallBackend) Generate(ctx context.Context, _ backend.Request) (*backend.Result, error) {
+	<-ctx.Done()
+	b.stop()
+	return nil, ctx.Err()
+}
+
+func (b *stallBackend) Stream(ctx context.Context, _ backend.Request, emit func(string) error) (*backend.Result, error) {
+	if err := emit("partial "); err != nil {
+		return nil, err
+	}
+	<-ctx.Done()
+	b.stop()
+	return nil, ctx.Err()
+}
+
+func TestCancelStream(t *testing.T) {
+	b := &stallBackend{stopped: make(chan struct{})}
+	withAuth, creds := signIn(t, "ada")
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0, server.WithBackend(b), withAuth)
+	conn := startServer(t, srv, creds)
+	cli := chatpb.NewChatServiceClient(conn)
+	sessions := chatpb.NewSessionServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	sess, err := sessions.CreateSession(ctx, &chatpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("CreateSession(): unexpected error: %v", err)
+	}
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "go on forever", SessionId: sess.GetId(), RequestId: "r1"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	if first.GetRequestId() != "r1" {
+		t.Errorf("chunk request id = %q; want r1", first.GetRequestId())
+	}
+
+	if _, err := cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r1"}); err != nil {
+		t.Fatalf("Cancel(): unexpected error: %v", err)
+	}
+	last, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() after Cancel: unexpected error: %v", err)
+	}
+	if last.GetFinishReason() != chatpb.FinishReason_FINISH_REASON_CANCELLED {
+		t.Errorf("finish reason = %v; want %v", last.GetFinishReason(), chatpb.FinishReason_FINISH_REASON_CANCELLED)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv() after final chunk = %v; want EOF", err)
+	}
+	<-b.stopped
+
+	got, err := sessions.GetSession(ctx, &chatpb.GetSessionRequest{Id: sess.GetId()})
+	if err != nil {
+		t.Fatalf("GetSession(): unexpected error: %v", err)
+	}
+	if msgs := got.GetMessages(); len(msgs) != 2 || msgs[1].GetContent() != "partial " {
+		t.Errorf("session messages = %v; want the partial reply kept", msgs)
+	}
+
+	_, err = cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r1"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Cancel() of finished request code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestCancelChat(t *testing.T) {
+	b := &stallBackend{stopped: make(chan struct{})}
+	_, cli := newTestServer(t, server.WithBackend(b))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", RequestId: "r2"})
+		errc <- err
+	}()
+	// the request may not have reached the server yet
+	for {
+		_, err := cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r2"})
+		if err == nil {
+			break
+		}
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("Cancel(): unexpected error: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := <-errc; status.Code(err) != codes.Canceled {
+		t.Errorf("Chat() code = %v; want %v", status.Code(err), codes.Canceled)
+	}
+	<-b.stopped
+}
+
+func TestClientDisconnectStopsGeneration(t *testing.T) {
+	b := &stallBackend{stopped: make(chan struct{})}
+	_, cli := newTestServer(t, server.WithBackend(b))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	cancel()
+	select {
+	case <-b.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend kept generating after the client went away")
+	}
+}
+
+func TestChatQueue(t *testing.T) {
+	b := &stallBackend{stopped: make(chan struct{})}
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "test-host", 0,
+		server.WithBackend(b),
+		server.WithScheduler(scheduler.New(1, 1)),
+	)
+	conn := startServer(t, srv)
+	cli := chatpb.NewChatServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// the first request takes the only slot and stalls
+	running, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "one", RequestId: "r1"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	if _, err := running.Recv(); err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+
+	waiting, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "two"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	chunk, err := waiting.Recv()
+	if err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	if chunk.GetQueuePosition() != 1 || chunk.GetDelta() != "" {
+		t.Errorf("queued chunk = %v; want queue position 1 and no text", chunk)
+	}
+
+	metrics, err := metricspb.NewMetricsServiceClient(conn).GetMetrics(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	if metrics.GetQueueDepth() != 1 || metrics.GetActiveGenerations() != 1 || metrics.GetMaxConcurrentGenerations() != 1 {
+		t.Errorf("metrics queue = %d, active %d/%d; want 1, 1/1",
+			metrics.GetQueueDepth(), metrics.GetActiveGenerations(), metrics.GetMaxConcurrentGenerations())
+	}
+
+	_, err = cli.Chat(ctx, &chatpb.ChatRequest{Text: "three"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Chat() with a full queue code = %v; want %v", status.Code(err), codes.ResourceExhausted)
+	}
+
+	// once the first finishes, the queued one runs
+	if _, err := cli.Cancel(ctx, &chatpb.CancelRequest{RequestId: "r1"}); err != nil {
+		t.Fatalf("Cancel(): unexpected error: %v", err)
+	}
+	chunk, err = waiting.Recv()
+	if err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	if chunk.GetDelta() != "partial " {
+		t.Errorf("chunk after admission = %v; want generated text", chunk)
+	}
+}
+
+func TestGetMetricsHostStats(t *testing.T) {
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "diag", 0, server.WithSampling(10*time.Millisecond, time.Minute))
+	cli := metricspb.NewMetricsServiceClient(startServer(t, srv))
+	m, err := cli.GetMetrics(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	// CPU usage is measured between samples, so the first has none
+	first := m.GetSampledAt().AsTime()
+	for deadline := time.Now().Add(5 * time.Second); m.GetSampledAt().AsTime().Equal(first); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no sample after the first")
+		}
+		if m, err = cli.GetMetrics(context.Background(), &emptypb.Empty{}); err != nil {
+			t.Fatalf("GetMetrics(): unexpected error: %v", err)
+		}
+	}
+	if len(m.GetCpuCorePercent()) == 0 || m.GetUptimeSeconds() == 0 {
+		t.Errorf("GetMetrics() cores %v, uptime %ds; want both", m.GetCpuCorePercent(), m.GetUptimeSeconds())
+	}
+	for i, pct := range append(m.GetCpuCorePercent(), m.GetCpuUsagePercent()) {
+		if pct < 0 || pct > 100 {
+			t.Errorf("CPU figure %d = %.1f%%; want within 0-100", i, pct)
+		}
+	}
+	if runtime.GOOS == "linux" && m.GetLoadAverage() == nil {
+		t.Error("GetMetrics() has no load average")
+	}
+	if m.GetSwapUsedMb() > m.GetSwapTotalMb() {
+		t.Errorf("swap %.1f/%.1f MB; want used within total", m.GetSwapUsedMb(), m.GetSwapTotalMb())
+	}
+	for _, d := range m.GetDisks() {
+		if d.GetMountpoint() == "" || d.GetUsedMb() > d.GetTotalMb() {
+			t.Errorf("disk = %v; want a mount point with used within total", d)
+		}
+	}
+	for _, n := range m.GetNetworks() {
+		if n.GetName() == "lo" || n.GetRxBytesPerSec() < 0 || n.GetTxBytesPerSec() < 0 {
+			t.Errorf("network = %v; want a non-loopback interface with sane rates", n)
+		}
+	}
+}
+
+func TestGetMetricsModelStats(t *testing.T) {
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "stats", 0, server.WithBackend(&backend.Echo{Reply: "one two three"}))
+	conn := startServer(t, srv)
+	cli := chatpb.NewChatServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi"}); err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	stream, err := cli.ChatStream(ctx, &chatpb.ChatRequest{Text: "hi"})
+	if err != nil {
+		t.Fatalf("ChatStream(): unexpected error: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Recv(): unexpected error: %v", err)
+		}
+	}
+	if _, err := cli.Chat(ctx, &chatpb.ChatRequest{Text: "hi", Model: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Chat(missing) code = %v; want %v", status.Code(err), codes.NotFound)
+	}
+
+	m, err := metricspb.NewMetricsServiceClient(conn).GetMetrics(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	stats := m.GetModelStats()
+	if len(stats) != 2 || stats[0].GetModel() != "" || stats[1].GetModel() != "echo" {
+		t.Fatalf("ModelStats = %v; want the unknown model then echo", stats)
+	}
+	unknown, echo := stats[0], stats[1]
+	if unknown.GetRequestsTotal() != 1 || unknown.GetErrorsByCode()["NotFound"] != 1 || unknown.GetLatency().GetCount() != 0 {
+		t.Errorf("unknown model stats = %v; want one NotFound failure and no latency", unknown)
+	}
+	if echo.GetRequestsTotal() != 2 || echo.GetErrorsTotal() != 0 || echo.GetQueued() != 0 || echo.GetActive() != 0 {
+		t.Errorf("echo stats = %v; want two finished requests, none failed or running", echo)
+	}
+	if echo.GetCompletionTokensTotal() != 6 || echo.GetPromptTokensTotal() == 0 {
+		t.Errorf("echo tokens = %d prompt, %d completion; want some prompt and 6 completion",
+			echo.GetPromptTokensTotal(), echo.GetCompletionTokensTotal())
+	}
+	if ttft := echo.GetTimeToFirstToken(); ttft.GetCount() != 1 || len(ttft.GetCounts()) != len(ttft.GetBoundsSeconds())+1 {
+		t.Errorf("time to first token = %v; want the one streamed request", ttft)
+	}
+	if lat := echo.GetLatency(); lat.GetCount() != 2 || lat.GetSumSeconds() <= 0 {
+		t.Errorf("latency = %v; want both requests", lat)
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "scraped", 0, server.WithBackend(&backend.Echo{Reply: "one two"}))
+	cli := chatpb.NewChatServiceClient(startServer(t, srv))
+	if _, err := cli.Chat(context.Background(), &chatpb.ChatRequest{Text: "hi"}); err != nil {
+		t.Fatalf("Chat(): unexpected error: %v", err)
+	}
+	if _, err := cli.Chat(context.Background(), &chatpb.ChatRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Chat(empty) code = %v; want %v", status.Code(err), codes.InvalidArgument)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q; want the Prometheus text format", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`llm_host_info{host="scraped"} 1`,
+		"# TYPE llm_memory_total_bytes gauge",
+		`llm_model_requests_total{model="echo"} 1`,
+		`llm_model_completion_tokens_total{model="echo"} 2`,
+		`llm_model_request_duration_seconds_bucket{model="echo",le="+Inf"} 1`,
+		`llm_model_request_duration_seconds_count{model="echo"} 1`,
+		`grpc_server_started_total{grpc_type="unary",grpc_service="proto.ChatService",grpc_method="Chat"} 2`,
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="proto.ChatService",grpc_method="Chat",grpc_code="InvalidArgument"} 1`,
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="proto.ChatService",grpc_method="Chat",grpc_code="OK"} 1`,
+		`grpc_server_handling_seconds_count{grpc_type="unary",grpc_service="proto.ChatService",grpc_method="Chat"} 2`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("GET /metrics is missing %q; got:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	srv.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /metrics status = %d; want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestMetricsHistory(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "sampled", 0, server.WithSampling(interval, 5*interval))
+	cli := metricspb.NewMetricsServiceClient(startServer(t, srv))
+	ctx := context.Background()
+
+	// readers in quick succession share one sample rather than each
+	// measuring CPU over the gap since the last
+	a, err := cli.GetMetrics(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	b, err := cli.GetMetrics(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("GetMetrics(): unexpected error: %v", err)
+	}
+	if a.GetSampledAt() == nil || (a.GetSampledAt().AsTime().Equal(b.GetSampledAt().AsTime()) && a.GetCpuUsagePercent() != b.GetCpuUsagePercent()) {
+		t.Errorf("GetMetrics() twice = %v, %v; want the same sample's CPU", a, b)
+	}
+
+	time.Sleep(10 * interval)
+	hist, err := cli.GetMetricsHistory(ctx, &metricspb.MetricsHistoryRequest{})
+	if err != nil {
+		t.Fatalf("GetMetricsHistory(): unexpected error: %v", err)
+	}
+	if got := hist.GetInterval().AsDuration(); got != interval {
+		t.Errorf("interval = %v; want %v", got, interval)
+	}
+	samples := hist.GetSamples()
+	if len(samples) != 5 {
+		t.Fatalf("history has %d samples; want the 5 that fit in the window", len(samples))
+	}
+	for i := 1; i < len(samples); i++ {
+		if !samples[i].GetSampledAt().AsTime().After(samples[i-1].GetSampledAt().AsTime()) {
+			t.Errorf("samples %d and %d out of order", i-1, i)
+		}
+	}
+
+	recent, err := cli.GetMetricsHistory(ctx, &metricspb.MetricsHistoryRequest{Window: durationpb.New(3 * interval)})
+	if err != nil {
+		t.Fatalf("GetMetricsHistory(): unexpected error: %v", err)
+	}
+	if n := len(recent.GetSamples()); n == 0 || n >= 5 {
+		t.Errorf("history over %v has %d samples; want only the latest few", 3*interval, n)
+	}
+}
+
+func TestWatchMetrics(t *testing.T) {
+	const floor = 50 * time.Millisecond
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "watched", 0, server.WithWatchInterval(floor))
+	cli := metricspb.NewMetricsServiceClient(startServer(t, srv))
+
+	// asking for less than the floor gets the floor
+	start := time.Now()
+	stream, err := cli.WatchMetrics(context.Background(), &metricspb.WatchMetricsRequest{Interval: durationpb.New(time.Millisecond)})
+	if err != nil {
+		t.Fatalf("WatchMetrics(): unexpected error: %v", err)
+	}
+	for range 3 {
+		snap, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv(): unexpected error: %v", err)
+		}
+		if snap.GetHostId() != "watched" || snap.GetMemoryTotalMb() == 0 {
+			t.Errorf("snapshot = %v; want the host's metrics", snap)
+		}
+	}
+	if got := time.Since(start); got < 2*floor {
+		t.Errorf("3 snapshots took %v; want at least %v between them", got, floor)
+	}
+
+	// stopping the server ends the stream rather than waiting on it
+	stopped := make(chan struct{})
+	go func() {
+		srv.Stop()
+		close(stopped)
+	}()
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Recv() after Stop: got %v; want %v", err, codes.Unavailable)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return while a watch was open")
+	}
+}
+
+func TestClusterStateStandalone(t *testing.T) {
+	srv := server.NewServer(slog.New(slog.DiscardHandler), "solo", 0)
+	state, err := metricspb.NewMetricsServiceClient(startServer(t, srv)).ClusterState(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("ClusterState(): unexpected error: %v", err)
+	}
+	if len(state.GetNodes()) != 1 {
+		t.Fatalf("ClusterState() nodes = %v; want just this host", state.GetNodes())
+	}
+	node := state.GetNodes()[0]
+	if node.GetHostId() != "solo" || node.GetState() != metricspb.NodeState_NODE_STATE_ALIVE {
+		t.Errorf("node = %v; want solo, alive", node)
+	}
+	if node.GetVersion() == "" || node.GetHardware().GetCpuCores() == 0 {
+		t.Errorf("node = %v; want a version and hardware summary", node)
+	}
+	if got := node.GetModels(); len(got) != 1 || got[0] != "echo" {
+		t.Errorf("node models = %v; want [echo]", got)
+	}
+}
+
+func TestClusterState(t *testing.T) {
+	join := func(name string, seeds ...string) *gossip.Node {
+		n, err := gossip.Start(gossip.Config{
+			Name:           name,
+			BindAddr:       "127.0.0.1:0",
+			Seeds:          seeds,
+			ProbeInterval:  100 * time.Millisecond,
+			GossipInterval: 20 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("gossip.Start(): unexpected error: %v", err)
+		}
+		t.Cleanup(func() { n.Shutdown() })
+		return n
+	}
+	a := join("host-a")
+	b := join("host-b", a.Addr())
+
+	logger := slog.New(slog.DiscardHandler)
+	srvA := server.NewServer(logger, "host-a", 0, server.WithCluster(a, "10.0.0.1:50051", "10.0.0.1:50052"))
+	srvB := server.NewServer(logger, "host-b", 0, server.WithCluster(b, "10.0.0.2:50051", "10.0.0.2:50052"))
+	t.Cleanup(srvB.Stop)
+	cli := metricspb.NewMetricsServiceClient(startServer(t, srvA))
+
+	var state *metricspb.ClusterStateResponse
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		var err error
+		state, err = cli.ClusterState(context.Background(), &emptypb.Empty{})
+		if err != nil {
+			t.Fatalf("ClusterState(): unexpected error: %v", err)
+		}
+		if nodes := state.GetNodes(); len(nodes) == 2 && nodes[1].GetChatAddr() != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ClusterState() = %v; want both hosts with metadata", state)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if state.GetHostId() != "host-a" {
+		t.Errorf("answering host = %q; want host-a", state.GetHostId())
+	}
+	peer := state.GetNodes()[1]
+	want := &metricspb.NodeInfo{
+		HostId:      "host-b",
+		ChatAddr:    "10.0.0.2:50051",
+		MetricsAddr: "10.0.0.2:50052",
+		GossipAddr:  b.Addr(),
+		Models:      []string{"echo"},
+		State:       metricspb.NodeState_NODE_STATE_ALIVE,
+	}
+	got := proto.Clone(peer).(*metricspb.NodeInfo)
+	got.Hardware, got.Version, got.Load = nil, "", nil
+	if !proto.Equal(got, want) {
+		t.Errorf("peer = %v; want %v", got, want)
+	}
+	if peer.GetHardware().GetCpuCores() == 0 || peer.GetVersion() == "" || peer.GetLoad().GetMaxConcurrentGenerations() == 0 {
+		t.Errorf("peer = %v; want its hardware, version and load gossiped", peer)
+	}
+	l := peer.GetLoad()
+	routed := &metricspb.MetricsResponse{
+		CpuUsagePercent:          l.GetCpuUsagePercent(),
+		MemoryUsedMb:             l.GetMemoryUsedMb(),
+		MemoryTotalMb:            l.GetMemoryTotalMb(),
+		QueueDepth:               l.GetQueueDepth(),
+		ActiveGenerations:        l.GetActiveGenerations(),
+		MaxConcurrentGenerations: l.GetMaxConcurrentGenerations(),
+	}
+	if !proto.Equal(l, routed) {
+		t.Errorf("peer load = %v; want only the routing figures gossiped", l)
+	}
+	if size := proto.Size(peer); size > gossip.MaxMetaSize {
+		t.Errorf("peer metadata is %d bytes; want at most %d", size, gossip.MaxMetaSize)
+	}
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errSessionNotFound is returned for unknown sessions and for sessions
+// that belong to another user
+var errSessionNotFound = errors.New("session not found")
+
+// session is a stored conversation
+type session struct {
+	ID        string           `json:"id"`
+	Owner     string           `json:"owner"`
+	Title     string           `json:"title"`
+	Messages  []sessionMessage `json:"messages"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// sessionMessage is one stored turn, with the role kept by name so the
+// files stay readable
+type sessionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SessionStore keeps chat sessions in memory and, when backed by a
+// directory, writes each one to <dir>/<id>.json so they survive a restart
+type SessionStore struct {
+	mu       sync.Mutex
+	dir      string
+	sessions map[string]*session
+}
+
+// OpenSessionStore opens a store backed by dir, loading any sessions saved
+// there. An empty dir keeps sessions in memory only.
+func OpenSessionStore(dir string) (*SessionStore, error) {
+	st := &SessionStore{dir: dir, sessions: map[string]*session{}}
+	if dir == "" {
+		return st, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create session dir: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read session %s: %w", f, err)
+		}
+		var sess session
+		if err := json.Unmarshal(b, &sess); err != nil {
+			return nil, fmt.Errorf("decode session %s: %w", f, err)
+		}
+		st.sessions[sess.ID] = &sess
+	}
+	return st, nil
+}
+
+// Create starts an empty session for owner
+func (st *SessionStore) Create(owner, title string) (*session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sess := &session{ID: id, Owner: owner, Title: title, CreatedAt: now, UpdatedAt: now}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err := st.save(sess); err != nil {
+		return nil, err
+	}
+	st.sessions[id] = sess
+	return sess.clone(), nil
+}
+
+// Get returns a copy of owner's session id
+func (st *SessionStore) Get(owner, id string) (*session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sess, err := st.lookup(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return sess.clone(), nil
+}
+
+// List returns copies of owner's sessions, most recently updated first
+func (st *SessionStore) List(owner string) []*session {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var out []*session
+	for _, sess := range st.sessions {
+		if sess.Owner == owner {
+			out = append(out, sess.clone())
+		}
+	}
+	slices.SortFunc(out, func(a, b *session) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	return out
+}
+
+// Rename changes the title of owner's session id
+func (st *SessionStore) Rename(owner, id, title string) (*session, error) {
+	return st.update(owner, id, func(sess *session) { sess.Title = title })
+}
+
+// Append adds turns to owner's session id. An untitled session takes its
+// title from the first user message.
+func (st *SessionStore) Append(owner, id string, msgs ...sessionMessage) (*session, error) {
+	return st.update(owner, id, func(sess *session) {
+		sess.Messages = append(sess.Messages, msgs...)
+		if sess.Title != "" {
+			return
+		}
+		for _, m := range sess.Messages {
+			if m.Role == roleNames[chatpb.Role_ROLE_USER] {
+				sess.Title = truncateTitle(m.Content)
+				return
+			}
+		}
+	})
+}
+
+// Delete removes owner's session id
+func (st *SessionStore) Delete(owner, id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, err := st.lookup(owner, id); err != nil {
+		return err
+	}
+	if st.dir != "" {
+		if err := os.Remove(st.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("delete session: %w", err)
+		}
+	}
+	delete(st.sessions, id)
+	return nil
+}
+
+// update applies fn to a copy of the session and commits it once saved
+func (st *SessionStore) update(owner, id string, fn func(*session)) (*session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	cur, err := st.lookup(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	next := cur.clone()
+	fn(next)
+	next.UpdatedAt = time.Now().UTC()
+	if err := st.save(next); err != nil {
+		return nil, err
+	}
+	st.sessions[id] = next
+	return next.clone(), nil
+}
+
+// lookup finds a session owned by owner; callers hold st.mu
+func (st *SessionStore) lookup(owner, id string) (*session, error) {
+	sess, ok := st.sessions[id]
+	if !ok || sess.Owner != owner {
+		return nil, errSessionNotFound
+	}
+	return sess, nil
+}
+
+// save writes sess to disk atomically; callers hold st.mu
+func (st *SessionStore) save(sess *session) error {
+	if st.dir == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(st.dir, sess.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), st.path(sess.ID)); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+func (st *SessionStore) path(id string) string {
+	return filepath.Join(st.dir, id+".json")
+}
+
+func (sess *session) clone() *session {
+	c := *sess
+	c.Messages = slices.Clone(sess.Messages)
+	return &c
+}
+
+// chatMessages converts the stored turns to protobuf messages
+func (sess *session) chatMessages() []*chatpb.ChatMessage {
+	out := make([]*chatpb.ChatMessage, len(sess.Messages))
+	for i, m := range sess.Messages {
+		out[i] = &chatpb.ChatMessage{Role: roleByName[m.Role], Content: m.Content}
+	}
+	return out
+}
+
+// proto converts a session, with its messages if full is set
+func (sess *session) proto(full bool) *chatpb.Session {
+	p := &chatpb.Session{
+		Id:           sess.ID,
+		Title:        sess.Title,
+		MessageCount: uint32(len(sess.Messages)),
+		CreatedAt:    timestamppb.New(sess.CreatedAt),
+		UpdatedAt:    timestamppb.New(sess.UpdatedAt),
+	}
+	if full {
+		p.Messages = sess.chatMessages()
+	}
+	return p
+}
+
+// storedMessages converts protobuf turns for storage
+func storedMessages(msgs []*chatpb.ChatMessage) []sessionMessage {
+	out := make([]sessionMessage, len(msgs))
+	for i, m := range msgs {
+		out[i] = sessionMessage{Role: roleNames[m.GetRole()], Content: m.GetContent()}
+	}
+	return out
+}
+
+// roleByName is the inverse of roleNames
+var roleByName = map[string]chatpb.Role{
+	"system":    chatpb.Role_ROLE_SYSTEM,
+	"user":      chatpb.Role_ROLE_USER,
+	"assistant": chatpb.Role_ROLE_ASSISTANT,
+}
+
+// truncateTitle shortens a message to a single-line session title
+func truncateTitle(s string) string {
+	const max = 40
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
+
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// caller is the subject of the request's verified token, or "" for an
+// anonymous request
+func caller(ctx context.Context) string {
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		return id.Subject
+	}
+	return ""
+}
+
+// sessionOwner identifies whose sessions a request may touch. Sessions
+// belong to verified callers only; anonymous requests are refused rather
+// than sharing one namespace in which everyone sees everyone's sessions.
+func sessionOwner(ctx context.Context) (string, error) {
+	owner := caller(ctx)
+	if owner == "" {
+		return "", status.Error(codes.Unauthenticated, "sessions need a signed-in caller")
+	}
+	return owner, nil
+}
+
+// sessionError maps store errors onto gRPC statuses
+func sessionError(err error) error {
+	if errors.Is(err, errSessionNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// sessionService implements the SessionServiceServer interface
+// on top of the server's session store
+type sessionService struct {
+	chatpb.UnimplementedSessionServiceServer
+	store *SessionStore
+}
+
+func (s *sessionService) CreateSession(ctx context.Context, req *chatpb.CreateSessionRequest) (*chatpb.Session, error) {
+	owner, err := sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.store.Create(owner, req.GetTitle())
+	if err != nil {
+		return nil, sessionError(err)
+	}
+	return sess.proto(true), nil
+}
+
+func (s *sessionService) ListSessions(ctx context.Context, _ *emptypb.Empty) (*chatpb.ListSessionsResponse, error) {
+	owner, err := sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &chatpb.ListSessionsResponse{}
+	for _, sess := range s.store.List(owner) {
+		resp.Sessions = append(resp.Sessions, sess.proto(false))
+	}
+	return resp, nil
+}
+
+func (s *sessionService) GetSession(ctx context.Context, req *chatpb.GetSessionRequest) (*chatpb.Session, error) {
+	owner, err := sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.store.Get(owner, req.GetId())
+	if err != nil {
+		return nil, sessionError(err)
+	}
+	return sess.proto(true), nil
+}
+
+func (s *sessionService) RenameSession(ctx context.Context, req *chatpb.RenameSessionRequest) (*chatpb.Session, error) {
+	if strings.TrimSpace(req.GetTitle()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "title must not be empty")
+	}
+	owner, err := sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := s.store.Rename(owner, req.GetId(), req.GetTitle())
+	if err != nil {
+		return nil, sessionError(err)
+	}
+	return sess.proto(true), nil
+}
+
+func (s *sessionService) DeleteSession(ctx context.Context, req *chatpb.DeleteSessionRequest) (*emptypb.Empty, error) {
+	owner, err := sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Delete(owner, req.GetId()); err != nil {
+		return nil, sessionError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
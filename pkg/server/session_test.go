@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSessionStorePersists(t *testing.T) {
+	dir := t.TempDir()
+	st, err := OpenSessionStore(dir)
+	if err != nil {
+		t.Fatalf("OpenSessionStore(): unexpected error: %v", err)
+	}
+	sess, err := st.Create("alice", "")
+	if err != nil {
+		t.Fatalf("Create(): unexpected error: %v", err)
+	}
+	_, err = st.Append("alice", sess.ID,
+		sessionMessage{Role: "user", Content: "What is the capital of France?"},
+		sessionMessage{Role: "assistant", Content: "Paris."},
+	)
+	if err != nil {
+		t.Fatalf("Append(): unexpected error: %v", err)
+	}
+
+	reopened, err := OpenSessionStore(dir)
+	if err != nil {
+		t.Fatalf("reopen: unexpected error: %v", err)
+	}
+	got, err := reopened.Get("alice", sess.ID)
+	if err != nil {
+		t.Fatalf("Get(): unexpected error: %v", err)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].Content != "Paris." {
+		t.Errorf("Messages = %+v", got.Messages)
+	}
+	if got.Title != "What is the capital of France?" {
+		t.Errorf("Title = %q; want first user message", got.Title)
+	}
+
+	if err := reopened.Delete("alice", sess.ID); err != nil {
+		t.Fatalf("Delete(): unexpected error: %v", err)
+	}
+	again, err := OpenSessionStore(dir)
+	if err != nil {
+		t.Fatalf("reopen: unexpected error: %v", err)
+	}
+	if n := len(again.List("alice")); n != 0 {
+		t.Errorf("List() after delete has %d sessions; want 0", n)
+	}
+}
+
+func TestSessionStoreOwnerScoped(t *testing.T) {
+	st, _ := OpenSessionStore("")
+	sess, err := st.Create("alice", "mine")
+	if err != nil {
+		t.Fatalf("Create(): unexpected error: %v", err)
+	}
+	if _, err := st.Get("bob", sess.ID); !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Get() by other user error = %v; want errSessionNotFound", err)
+	}
+	if _, err := st.Rename("bob", sess.ID, "stolen"); !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Rename() by other user error = %v; want errSessionNotFound", err)
+	}
+	if err := st.Delete("bob", sess.ID); !errors.Is(err, errSessionNotFound) {
+		t.Errorf("Delete() by other user error = %v; want errSessionNotFound", err)
+	}
+	if n := len(st.List("bob")); n != 0 {
+		t.Errorf("List() for other user has %d sessions; want 0", n)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"google.golang.org/grpc/status"
+)
+
+// latencyBounds are the histogram bucket bounds, in seconds, for both
+// time to first token and request latency
+var latencyBounds = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// inferenceStats records how each model performs on this host
+type inferenceStats struct {
+	mu     sync.Mutex
+	models map[string]*modelStats
+}
+
+type modelStats struct {
+	queued, active    int
+	requests, errors  uint64
+	byCode            map[string]uint64
+	prompt, completed uint64
+	ttft, latency     histogram
+
+	// completion tokens and generation time of the requests finished since
+	// the last tick, and the decode rate they last gave
+	decodeTokens uint64
+	decodeTime   time.Duration
+	tokensPerSec float64
+}
+
+// request tracks one request through queueing and generation
+type request struct {
+	stats   *inferenceStats
+	model   string
+	start   time.Time
+	state   int // 0 queued, 1 active, 2 finished
+	genAt   time.Time
+	firstAt time.Time
+}
+
+// start records a request for model arriving and waiting for a slot
+func (st *inferenceStats) start(model string) *request {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.model(model).queued++
+	return &request{stats: st, model: model, start: time.Now()}
+}
+
+// model returns model's stats, creating them; st.mu must be held
+func (st *inferenceStats) model(name string) *modelStats {
+	if st.models == nil {
+		st.models = map[string]*modelStats{}
+	}
+	m, ok := st.models[name]
+	if !ok {
+		m = &modelStats{byCode: map[string]uint64{}, ttft: newHistogram(), latency: newHistogram()}
+		st.models[name] = m
+	}
+	return m
+}
+
+// admitted moves the request from the queue to generating
+func (r *request) admitted() {
+	r.stats.mu.Lock()
+	defer r.stats.mu.Unlock()
+	if r.state == 0 {
+		m := r.stats.model(r.model)
+		m.queued--
+		m.active++
+		r.state = 1
+	}
+}
+
+// generating notes that the model is ready and generation has begun
+func (r *request) generating() {
+	r.genAt = time.Now()
+}
+
+// firstToken notes when the first token was streamed
+func (r *request) firstToken() {
+	if r.firstAt.IsZero() {
+		r.firstAt = time.Now()
+	}
+}
+
+// finish records the outcome: err is the status the client was sent, and
+// used the tokens, if the request got as far as generating
+func (r *request) finish(used *chatpb.Usage, err error) {
+	r.stats.mu.Lock()
+	defer r.stats.mu.Unlock()
+	m := r.stats.model(r.model)
+	switch r.state {
+	case 0:
+		m.queued--
+	case 1:
+		m.active--
+		if n := uint64(used.GetCompletionTokens()); n > 0 && !r.genAt.IsZero() {
+			from := r.genAt
+			if !r.firstAt.IsZero() {
+				// a stream decodes from its first token on; what came
+				// before was evaluating the prompt
+				from, n = r.firstAt, n-1
+			}
+			if n > 0 {
+				m.decodeTokens += n
+				m.decodeTime += time.Since(from)
+			}
+		}
+	default:
+		return
+	}
+	r.state = 2
+	m.requests++
+	m.prompt += uint64(used.GetPromptTokens())
+	m.completed += uint64(used.GetCompletionTokens())
+	if !r.firstAt.IsZero() {
+		m.ttft.observe(r.firstAt.Sub(r.start))
+	}
+	if err != nil {
+		m.errors++
+		m.byCode[status.Code(err).String()]++
+		return
+	}
+	m.latency.observe(time.Since(r.start))
+}
+
+// tick updates every model's tokens per second to the decode rate of the
+// requests finished since the previous tick: their completion tokens over
+// the time spent generating them, which for streams starts at the first
+// token and otherwise once the model is loaded. A model with none
+// finished keeps its last rate. The sampler calls it on its cadence.
+func (st *inferenceStats) tick() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, m := range st.models {
+		if m.decodeTime > 0 {
+			m.tokensPerSec = float64(m.decodeTokens) / m.decodeTime.Seconds()
+		}
+		m.decodeTokens, m.decodeTime = 0, 0
+	}
+}
+
+// report lists every model's stats, sorted by name
+func (st *inferenceStats) report() []*metricspb.ModelStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	out := make([]*metricspb.ModelStats, 0, len(st.models))
+	for name, m := range st.models {
+		byCode := make(map[string]uint64, len(m.byCode))
+		for code, n := range m.byCode {
+			byCode[code] = n
+		}
+		out = append(out, &metricspb.ModelStats{
+			Model:                 name,
+			Queued:                uint32(m.queued),
+			Active:                uint32(m.active),
+			RequestsTotal:         m.requests,
+			ErrorsTotal:           m.errors,
+			ErrorsByCode:          byCode,
+			PromptTokensTotal:     m.prompt,
+			CompletionTokensTotal: m.completed,
+			TokensPerSecond:       m.tokensPerSec,
+			TimeToFirstToken:      m.ttft.proto(),
+			Latency:               m.latency.proto(),
+		})
+	}
+	slices.SortFunc(out, func(a, b *metricspb.ModelStats) int { return cmp.Compare(a.GetModel(), b.GetModel()) })
+	return out
+}
+
+// histogram counts durations into latencyBounds buckets
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() histogram {
+	return histogram{counts: make([]uint64, len(latencyBounds)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	secs := d.Seconds()
+	// the first bucket whose bound is at least secs; past the last
+	// bound lands in the overflow bucket
+	h.counts[sort.SearchFloat64s(latencyBounds, secs)]++
+	h.count++
+	h.sum += secs
+}
+
+func (h *histogram) proto() *metricspb.Histogram {
+	return &metricspb.Histogram{
+		BoundsSeconds: slices.Clone(latencyBounds),
+		Counts:        slices.Clone(h.counts),
+		Count:         h.count,
+		SumSeconds:    h.sum,
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+)
+
+func TestTokensPerSecond(t *testing.T) {
+	var st inferenceStats
+	rate := func() float64 {
+		st.tick()
+		return st.report()[0].GetTokensPerSecond()
+	}
+
+	// 100 tokens over two seconds of generation, however long it queued
+	r := st.start("m")
+	r.admitted()
+	r.genAt = time.Now().Add(-2 * time.Second)
+	r.finish(&chatpb.Usage{CompletionTokens: 100}, nil)
+	if got := rate(); math.Abs(got-50) > 1 {
+		t.Errorf("tokens per second = %.2f; want about 50", got)
+	}
+	// the rate holds while nothing finishes rather than dropping to zero
+	if got := rate(); math.Abs(got-50) > 1 {
+		t.Errorf("tokens per second with nothing finished = %.2f; want about 50 still", got)
+	}
+
+	// requests finishing together give their combined rate
+	for _, d := range []time.Duration{time.Second, 3 * time.Second} {
+		r := st.start("m")
+		r.admitted()
+		r.genAt = time.Now().Add(-d)
+		r.finish(&chatpb.Usage{CompletionTokens: 40}, nil)
+	}
+	if got := rate(); math.Abs(got-20) > 1 {
+		t.Errorf("tokens per second = %.2f; want about 20", got)
+	}
+
+	// a stream is timed from its first token, not from a slow prefill
+	r = st.start("m")
+	r.admitted()
+	r.genAt = time.Now().Add(-time.Minute)
+	r.firstAt = time.Now().Add(-time.Second)
+	r.finish(&chatpb.Usage{CompletionTokens: 11}, nil)
+	// and a request that never got its model counts for nothing
+	r = st.start("m")
+	r.admitted()
+	r.finish(&chatpb.Usage{}, errors.New("load failed"))
+	if got := rate(); math.Abs(got-10) > 1 {
+		t.Errorf("tokens per second of a stream = %.2f; want about 10", got)
+	}
+}
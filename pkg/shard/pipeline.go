@@ -0,0 +1,220 @@
+package shard
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultMaxTokens caps a reply when the request sets no limit
+const defaultMaxTokens = 256
+
+// Planner lays a model out over the hosts that will run it.
+type Planner func(ctx context.Context) ([]*shardpb.Stage, error)
+
+// Pipeline is a backend that generates with a model split into stages
+// across hosts. The layout is planned afresh for every request, so it
+// follows hosts joining and leaving.
+type Pipeline struct {
+	svc  *Service
+	spec *shardpb.ModelSpec
+	plan Planner
+}
+
+// NewPipeline returns a backend running svc's model over the stages plan
+// lays out.
+func NewPipeline(svc *Service, plan Planner) *Pipeline {
+	return &Pipeline{svc: svc, spec: svc.spec, plan: plan}
+}
+
+// ToySpec is a small toy model with the given number of layers.
+func ToySpec(name string, layers int) *shardpb.ModelSpec {
+	return &shardpb.ModelSpec{Name: name, Layers: uint32(layers), Hidden: 64, Seed: 1}
+}
+
+// LocalPlanner runs every layer on host hostID.
+func LocalPlanner(hostID string, spec *shardpb.ModelSpec) Planner {
+	return func(context.Context) ([]*shardpb.Stage, error) {
+		return []*shardpb.Stage{{HostId: hostID, Start: 0, End: spec.GetLayers()}}, nil
+	}
+}
+
+// ClusterPlanner spreads spec over the live members of n in host order,
+// by the free memory each gossips. layerSizeMB overrides the size of a layer
+// for planning, so the toy model can stand in for a large one; zero uses
+// its real size.
+func ClusterPlanner(n *gossip.Node, spec *shardpb.ModelSpec, layerSizeMB float64) Planner {
+	if layerSizeMB <= 0 {
+		layerSizeMB = layerMB(spec)
+	}
+	return func(context.Context) ([]*shardpb.Stage, error) {
+		var nodes []Node
+		for _, m := range n.Members() {
+			info := &metricspb.NodeInfo{}
+			if m.State != gossip.StateAlive || proto.Unmarshal(m.Meta, info) != nil || info.GetLoad() == nil {
+				continue
+			}
+			nodes = append(nodes, NodeFromMetrics(m.Name, info.GetChatAddr(), info.GetLoad()))
+		}
+		slices.SortFunc(nodes, func(a, b Node) int { return cmp.Compare(a.HostID, b.HostID) })
+		return Plan(int(spec.GetLayers()), layerSizeMB, nodes)
+	}
+}
+
+// Generate implements backend.Backend.
+func (p *Pipeline) Generate(ctx context.Context, req backend.Request) (*backend.Result, error) {
+	return p.Stream(ctx, req, func(string) error { return nil })
+}
+
+// Stream implements backend.Backend, running the whole pipeline once per
+// generated token.
+func (p *Pipeline) Stream(ctx context.Context, req backend.Request, emit func(token string) error) (*backend.Result, error) {
+	stages, err := p.plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := tokenize(req.Prompt)
+	if len(tokens) == 0 {
+		tokens = []uint32{firstChar}
+	}
+	res := &backend.Result{PromptTokens: len(tokens), FinishReason: backend.FinishLength}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	pick := newSampler(req.Sampling)
+
+	// text is held back from emit while it could be the start of a stop
+	// sequence, so a stop sequence is never sent
+	text, sent := "", 0
+	for res.CompletionTokens < maxTokens {
+		resp, err := p.svc.Run(ctx, &shardpb.ForwardRequest{Model: p.spec, Stages: stages, Tokens: tokens})
+		if err != nil {
+			return nil, err
+		}
+		next := pick(resp.GetLogits())
+		if next == tokEOS {
+			res.FinishReason = backend.FinishStop
+			break
+		}
+		tokens = append(tokens, next)
+		text += detokenize(next)
+		res.CompletionTokens++
+		if i := stopIndex(text, req.Stop); i >= 0 {
+			text = text[:i]
+			res.FinishReason = backend.FinishStop
+			break
+		}
+		if safe := len(text) - heldBack(text, req.Stop); safe > sent {
+			if err := emit(text[sent:safe]); err != nil {
+				return nil, err
+			}
+			sent = safe
+		}
+	}
+	if sent < len(text) {
+		if err := emit(text[sent:]); err != nil {
+			return nil, err
+		}
+	}
+	res.Text = text
+	return res, nil
+}
+
+// stopIndex is where the first stop sequence in text begins, or -1
+func stopIndex(text string, stops []string) int {
+	first := -1
+	for _, s := range stops {
+		if i := strings.Index(text, s); s != "" && i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	return first
+}
+
+// heldBack is the length of the longest suffix of text that begins a stop
+// sequence
+func heldBack(text string, stops []string) int {
+	n := 0
+	for _, s := range stops {
+		for k := min(len(s)-1, len(text)); k > n; k-- {
+			if strings.HasSuffix(text, s[:k]) {
+				n = k
+				break
+			}
+		}
+	}
+	return n
+}
+
+// newSampler returns a func picking the next token from logits: greedy
+// at temperature 0, otherwise drawn from the top-k softmax
+func newSampler(s *backend.Sampling) func([]float32) uint32 {
+	if s == nil || s.Temperature <= 0 {
+		return func(logits []float32) uint32 {
+			return uint32(argmax(logits))
+		}
+	}
+	seed := uint64(s.Seed)
+	if s.Seed < 0 {
+		seed = rand.Uint64()
+	}
+	r := rand.New(rand.NewPCG(seed, 0))
+	return func(logits []float32) uint32 {
+		ids := make([]int, len(logits))
+		for i := range ids {
+			ids[i] = i
+		}
+		slices.SortFunc(ids, func(a, b int) int { return cmp.Compare(logits[b], logits[a]) })
+		if s.TopK > 0 && s.TopK < len(ids) {
+			ids = ids[:s.TopK]
+		}
+		weights := make([]float64, len(ids))
+		var sum float64
+		for i, id := range ids {
+			weights[i] = math.Exp(float64(logits[id]-logits[ids[0]]) / s.Temperature)
+			sum += weights[i]
+		}
+		x := r.Float64() * sum
+		for i, w := range weights {
+			if x -= w; x < 0 {
+				return uint32(ids[i])
+			}
+		}
+		return uint32(ids[len(ids)-1])
+	}
+}
+
+func argmax(v []float32) int {
+	best := 0
+	for i, x := range v {
+		if x > v[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Tokenize implements backend.Backend.
+func (p *Pipeline) Tokenize(_ context.Context, text string) ([]int, error) {
+	ids := tokenize(text)
+	out := make([]int, len(ids))
+	for i, id := range ids {
+		out[i] = int(id)
+	}
+	return out, nil
+}
+
+// ListModels implements backend.Backend.
+func (p *Pipeline) ListModels(context.Context) ([]backend.ModelInfo, error) {
+	return []backend.ModelInfo{{ID: p.spec.GetName(), OwnedBy: "pipeline"}}, nil
+}
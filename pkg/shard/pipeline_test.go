@@ -0,0 +1,192 @@
+package shard_test
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/backend"
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+	"github.com/Billy-Davies-2/llm-test/pkg/shard"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startHosts serves shard services running spec for hosts on loopback
+// ports, each allowed to send stages to the others. It returns the
+// services and their addresses by host.
+func startHosts(t *testing.T, spec *shardpb.ModelSpec, hosts ...string) (map[string]*shard.Service, map[string]string) {
+	t.Helper()
+	svcs, addrs := map[string]*shard.Service{}, map[string]string{}
+	peers := shard.WithPeers(func(host, addr string) bool { return addrs[host] == addr })
+	for _, host := range hosts {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		svc := shard.NewService(host, spec, slog.New(slog.DiscardHandler), peers)
+		t.Cleanup(func() { svc.Close() })
+		g := grpc.NewServer()
+		shardpb.RegisterShardServiceServer(g, svc)
+		go g.Serve(lis)
+		t.Cleanup(g.Stop)
+		svcs[host], addrs[host] = svc, lis.Addr().String()
+	}
+	return svcs, addrs
+}
+
+func fixedPlan(stages ...*shardpb.Stage) shard.Planner {
+	return func(context.Context) ([]*shardpb.Stage, error) { return stages, nil }
+}
+
+func TestPipelineMatchesSingleHost(t *testing.T) {
+	spec := shard.ToySpec("toy", 6)
+	svcs, addrs := startHosts(t, spec, "a", "b", "c")
+	a := svcs["a"]
+	req := backend.Request{Prompt: "The quick brown fox", MaxTokens: 24}
+	ctx := context.Background()
+
+	local, err := shard.NewPipeline(a, shard.LocalPlanner("a", spec)).Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate() on one host: unexpected error: %v", err)
+	}
+	if local.CompletionTokens != 24 || local.Text == "" {
+		t.Fatalf("Generate() = %+v; want 24 tokens of text", local)
+	}
+
+	split := shard.NewPipeline(a, fixedPlan(
+		&shardpb.Stage{HostId: "a", Addr: addrs["a"], Start: 0, End: 2},
+		&shardpb.Stage{HostId: "b", Addr: addrs["b"], Start: 2, End: 5},
+		&shardpb.Stage{HostId: "c", Addr: addrs["c"], Start: 5, End: 6},
+	))
+	var streamed string
+	got, err := split.Stream(ctx, req, func(tok string) error {
+		streamed += tok
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream() across hosts: unexpected error: %v", err)
+	}
+	if got.Text != local.Text || streamed != local.Text {
+		t.Errorf("pipelined reply = %q (streamed %q); want %q as on one host", got.Text, streamed, local.Text)
+	}
+}
+
+func TestForwardChainsStages(t *testing.T) {
+	spec := shard.ToySpec("toy", 4)
+	svcs, addrs := startHosts(t, spec, "a", "b", "coordinator")
+
+	// the coordinator runs no layers itself; it only sends to a
+	resp, err := svcs["coordinator"].Run(context.Background(), &shardpb.ForwardRequest{
+		Model: spec,
+		Stages: []*shardpb.Stage{
+			{HostId: "a", Addr: addrs["a"], Start: 0, End: 1},
+			{HostId: "b", Addr: addrs["b"], Start: 1, End: 4},
+		},
+		Tokens: []uint32{5, 6, 7},
+	})
+	if err != nil {
+		t.Fatalf("Run(): unexpected error: %v", err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(resp.GetHosts(), want) {
+		t.Errorf("stage hosts = %v; want %v", resp.GetHosts(), want)
+	}
+	if len(resp.GetLogits()) == 0 {
+		t.Error("Run() returned no logits")
+	}
+}
+
+func TestForwardRejectsBadPlans(t *testing.T) {
+	spec := shard.ToySpec("toy", 4)
+	svcs, addrs := startHosts(t, spec, "a")
+	svc, addr := svcs["a"], addrs["a"]
+	tests := []struct {
+		name   string
+		stages []*shardpb.Stage
+		tokens []uint32
+	}{
+		{"gap", []*shardpb.Stage{{HostId: "a", Addr: addr, Start: 0, End: 1}, {HostId: "a", Addr: addr, Start: 2, End: 4}}, []uint32{1}},
+		{"short", []*shardpb.Stage{{HostId: "a", Addr: addr, Start: 0, End: 3}}, []uint32{1}},
+		{"no tokens", []*shardpb.Stage{{HostId: "a", Addr: addr, Start: 0, End: 4}}, nil},
+		{"no activations", []*shardpb.Stage{{HostId: "a", Addr: addr, Start: 2, End: 4}}, nil},
+	}
+	for _, tt := range tests {
+		_, err := svc.Run(context.Background(), &shardpb.ForwardRequest{Model: spec, Stages: tt.stages, Tokens: tt.tokens})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: Run() code = %v; want %v", tt.name, status.Code(err), codes.InvalidArgument)
+		}
+	}
+}
+
+func TestForwardRefusesStrangers(t *testing.T) {
+	spec := shard.ToySpec("toy", 4)
+	svcs, addrs := startHosts(t, spec, "a", "b")
+	stranger, _ := startHosts(t, spec, "stranger")
+
+	// a model other than the one the host was configured with is refused
+	// rather than built
+	huge := &shardpb.ModelSpec{Name: "huge", Layers: 1024, Hidden: 4096, Seed: 1}
+	_, err := svcs["a"].Forward(context.Background(), &shardpb.ForwardRequest{
+		Model:  huge,
+		Stages: []*shardpb.Stage{{HostId: "a", Addr: addrs["a"], Start: 0, End: 1024}},
+		Tokens: []uint32{1},
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Forward() of another model code = %v; want %v", status.Code(err), codes.FailedPrecondition)
+	}
+
+	// a stage is only sent to a member, at the address it is known by
+	for name, stage := range map[string]*shardpb.Stage{
+		"unknown host":  {HostId: "stranger", Addr: "127.0.0.1:1", Start: 0, End: 4},
+		"wrong address": {HostId: "b", Addr: "127.0.0.1:1", Start: 0, End: 4},
+	} {
+		_, err := svcs["a"].Run(context.Background(), &shardpb.ForwardRequest{Model: spec, Stages: []*shardpb.Stage{stage}, Tokens: []uint32{1}})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: Run() code = %v; want %v", name, status.Code(err), codes.PermissionDenied)
+		}
+	}
+	// nor to a host outside the sender's cluster
+	_, err = stranger["stranger"].Run(context.Background(), &shardpb.ForwardRequest{
+		Model:  spec,
+		Stages: []*shardpb.Stage{{HostId: "a", Addr: addrs["a"], Start: 0, End: 4}},
+		Tokens: []uint32{1},
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Run() from another cluster code = %v; want %v", status.Code(err), codes.PermissionDenied)
+	}
+}
+
+func TestPipelineStopSequence(t *testing.T) {
+	spec := shard.ToySpec("toy", 2)
+	svcs, _ := startHosts(t, spec, "a")
+	p := shard.NewPipeline(svcs["a"], shard.LocalPlanner("a", spec))
+	ctx := context.Background()
+	// a fixed seed keeps the reply the same between the two runs
+	sampling := &backend.Sampling{Temperature: 1, Seed: 7}
+
+	full, err := p.Generate(ctx, backend.Request{Prompt: "hello", MaxTokens: 16, Sampling: sampling})
+	if err != nil {
+		t.Fatalf("Generate(): unexpected error: %v", err)
+	}
+	if len(full.Text) < 6 {
+		t.Fatalf("Generate() = %q; want several characters to stop within", full.Text)
+	}
+	stop := full.Text[3:6]
+
+	var streamed string
+	got, err := p.Stream(ctx, backend.Request{Prompt: "hello", MaxTokens: 16, Stop: []string{stop}, Sampling: sampling}, func(tok string) error {
+		streamed += tok
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream(): unexpected error: %v", err)
+	}
+	want := full.Text[:strings.Index(full.Text, stop)]
+	if got.Text != want || streamed != want || got.FinishReason != backend.FinishStop {
+		t.Errorf("Stream() with stop %q = %q (streamed %q, %v); want %q, stop", stop, got.Text, streamed, got.FinishReason, want)
+	}
+}
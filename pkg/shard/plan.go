@@ -0,0 +1,102 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+)
+
+// ErrInsufficientMemory is returned by Plan when the nodes together cannot
+// hold the model
+var ErrInsufficientMemory = errors.New("not enough memory across the cluster for the model")
+
+// headroom is the share of a node's free memory the planner will fill,
+// leaving the rest for activations and whatever else the host runs
+const headroom = 0.9
+
+// Node is a host the planner can place layers on
+type Node struct {
+	HostID string
+	Addr   string  // ShardService gRPC address
+	FreeMB float64 // memory available for weights
+}
+
+// NodeFromMetrics describes a host by the free memory it last reported.
+func NodeFromMetrics(hostID, addr string, m *metricspb.MetricsResponse) Node {
+	free := max(m.GetMemoryTotalMb()-m.GetMemoryUsedMb(), 0)
+	return Node{HostID: hostID, Addr: addr, FreeMB: free * headroom}
+}
+
+// Plan splits layers, each needing layerMB, into contiguous stages over
+// nodes in the order given. Each node gets a share of the layers in
+// proportion to its free memory, never more than fits; nodes left with no
+// layers are not part of the pipeline.
+func Plan(layers int, layerMB float64, nodes []Node) ([]*shardpb.Stage, error) {
+	if layers <= 0 {
+		return nil, fmt.Errorf("model has %d layers", layers)
+	}
+	capacity := make([]int, len(nodes))
+	total := 0
+	for i, n := range nodes {
+		capacity[i] = layers
+		if layerMB > 0 {
+			capacity[i] = min(int(n.FreeMB/layerMB), layers)
+		}
+		capacity[i] = max(capacity[i], 0)
+		total += capacity[i]
+	}
+	if total < layers {
+		return nil, fmt.Errorf("%w: %d of %d layers fit", ErrInsufficientMemory, total, layers)
+	}
+
+	alloc := make([]int, len(nodes))
+	remaining := layers
+	for remaining > 0 {
+		// share out what is left by free memory among nodes with room
+		var free float64
+		for i, n := range nodes {
+			if alloc[i] < capacity[i] {
+				free += n.FreeMB
+			}
+		}
+		given := 0
+		for i, n := range nodes {
+			if alloc[i] < capacity[i] && free > 0 {
+				give := min(int(float64(remaining)*n.FreeMB/free), capacity[i]-alloc[i])
+				alloc[i] += give
+				given += give
+			}
+		}
+		if given == 0 {
+			// rounding left every share at zero: give one layer to the
+			// node with the most memory per layer it would hold
+			best := -1
+			for i, n := range nodes {
+				if alloc[i] < capacity[i] && (best < 0 || n.FreeMB/float64(alloc[i]+1) > nodes[best].FreeMB/float64(alloc[best]+1)) {
+					best = i
+				}
+			}
+			alloc[best]++
+			given = 1
+		}
+		remaining -= given
+	}
+
+	var stages []*shardpb.Stage
+	start := 0
+	for i, n := range nodes {
+		if alloc[i] == 0 {
+			continue
+		}
+		stages = append(stages, &shardpb.Stage{
+			HostId: n.HostID,
+			Addr:   n.Addr,
+			Start:  uint32(start),
+			End:    uint32(start + alloc[i]),
+		})
+		start += alloc[i]
+	}
+	return stages, nil
+}
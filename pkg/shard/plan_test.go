@@ -0,0 +1,83 @@
+package shard_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/shard"
+)
+
+// ranges flattens a plan into host:[start,end) pairs for comparison
+func ranges(t *testing.T, layers int, layerMB float64, nodes []shard.Node) []string {
+	t.Helper()
+	stages, err := shard.Plan(layers, layerMB, nodes)
+	if err != nil {
+		t.Fatalf("Plan(): unexpected error: %v", err)
+	}
+	var out []string
+	for _, s := range stages {
+		out = append(out, fmt.Sprintf("%s:%02d-%02d", s.GetHostId(), s.GetStart(), s.GetEnd()))
+	}
+	return out
+}
+
+func TestPlanByMemory(t *testing.T) {
+	tests := []struct {
+		name    string
+		layers  int
+		layerMB float64
+		nodes   []shard.Node
+		want    []string
+	}{
+		{
+			name: "proportional", layers: 32, layerMB: 100,
+			nodes: []shard.Node{{HostID: "a", FreeMB: 2400}, {HostID: "b", FreeMB: 800}},
+			want:  []string{"a:00-24", "b:24-32"},
+		},
+		{
+			name: "remainder", layers: 32, layerMB: 100,
+			nodes: []shard.Node{{HostID: "a", FreeMB: 4000}, {HostID: "b", FreeMB: 450}, {HostID: "c", FreeMB: 4000}},
+			want:  []string{"a:00-16", "b:16-17", "c:17-32"},
+		},
+		{
+			// every layer that fits is used
+			name: "tight", layers: 10, layerMB: 100,
+			nodes: []shard.Node{{HostID: "a", FreeMB: 950}, {HostID: "b", FreeMB: 150}},
+			want:  []string{"a:00-09", "b:09-10"},
+		},
+		{
+			name: "one node suffices", layers: 8, layerMB: 1,
+			nodes: []shard.Node{{HostID: "a", FreeMB: 1000}, {HostID: "b", FreeMB: 0}},
+			want:  []string{"a:00-08"},
+		},
+		{
+			name: "rounding", layers: 3, layerMB: 1,
+			nodes: []shard.Node{{HostID: "a", FreeMB: 10}, {HostID: "b", FreeMB: 10}, {HostID: "c", FreeMB: 10}, {HostID: "d", FreeMB: 10}},
+			want:  []string{"a:00-01", "b:01-02", "c:02-03"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranges(t, tt.layers, tt.layerMB, tt.nodes); !slices.Equal(got, tt.want) {
+				t.Errorf("Plan() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanInsufficientMemory(t *testing.T) {
+	nodes := []shard.Node{{HostID: "a", FreeMB: 1000}, {HostID: "b", FreeMB: 1000}}
+	if _, err := shard.Plan(32, 100, nodes); !errors.Is(err, shard.ErrInsufficientMemory) {
+		t.Errorf("Plan() error = %v; want %v", err, shard.ErrInsufficientMemory)
+	}
+}
+
+func TestNodeFromMetrics(t *testing.T) {
+	n := shard.NodeFromMetrics("a", "10.0.0.1:50051", &metricspb.MetricsResponse{MemoryUsedMb: 6000, MemoryTotalMb: 16000})
+	if n.FreeMB != 9000 {
+		t.Errorf("FreeMB = %v; want 9000, 90%% of what is free", n.FreeMB)
+	}
+}
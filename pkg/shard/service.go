@@ -0,0 +1,203 @@
+// Package shard runs a model split across hosts as a pipeline: each host
+// runs a contiguous range of layers and passes its activations on to the
+// next over gRPC, in the manner of exo.
+package shard
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/auth"
+	"github.com/Billy-Davies-2/llm-test/pkg/gossip"
+	metricspb "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Service runs this host's stages of one model and hands activations to
+// the next host. It implements ShardServiceServer.
+type Service struct {
+	shardpb.UnimplementedShardServiceServer
+	hostID string
+	spec   *shardpb.ModelSpec
+	logger *slog.Logger
+
+	// reports whether a stage may be sent to host at addr
+	peer func(host, addr string) bool
+
+	buildOnce sync.Once
+	model     *toyModel
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// ServiceOption customises a Service built by NewService
+type ServiceOption func(*Service)
+
+// WithPeers lets the service send stages on to the hosts allowed
+// approves, such as the members GossipPeers reports. Without it the
+// service runs only its own stages.
+func WithPeers(allowed func(host, addr string) bool) ServiceOption {
+	return func(s *Service) { s.peer = allowed }
+}
+
+// GossipPeers approves the live members of n at the address they gossip,
+// so a request cannot make a host dial anywhere else.
+func GossipPeers(n *gossip.Node) func(host, addr string) bool {
+	return func(host, addr string) bool {
+		for _, m := range n.Members() {
+			if m.Name != host || m.State != gossip.StateAlive {
+				continue
+			}
+			info := &metricspb.NodeInfo{}
+			return proto.Unmarshal(m.Meta, info) == nil && info.GetChatAddr() == addr
+		}
+		return false
+	}
+}
+
+// NewService returns the shard service of host hostID, running spec and
+// no other model.
+func NewService(hostID string, spec *shardpb.ModelSpec, logger *slog.Logger, opts ...ServiceOption) *Service {
+	s := &Service{
+		hostID: hostID,
+		spec:   spec,
+		logger: logger,
+		peer:   func(string, string) bool { return false },
+		conns:  map[string]*grpc.ClientConn{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Forward implements ShardServiceServer.
+func (s *Service) Forward(ctx context.Context, req *shardpb.ForwardRequest) (*shardpb.ForwardResponse, error) {
+	if len(req.GetStages()) == 0 || req.GetStages()[0].GetHostId() != s.hostID {
+		return nil, status.Error(codes.FailedPrecondition, "the first stage is not for this host")
+	}
+	return s.Run(ctx, req)
+}
+
+// Run executes the pipeline req describes from its first stage on,
+// running that stage here if it belongs to this host and sending it to
+// its host otherwise.
+func (s *Service) Run(ctx context.Context, req *shardpb.ForwardRequest) (*shardpb.ForwardResponse, error) {
+	if !proto.Equal(req.GetModel(), s.spec) {
+		return nil, status.Errorf(codes.FailedPrecondition, "this host runs model %q only", s.spec.GetName())
+	}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	stage := req.GetStages()[0]
+	if stage.GetHostId() != s.hostID {
+		if !s.peer(stage.GetHostId(), stage.GetAddr()) {
+			return nil, status.Errorf(codes.PermissionDenied, "stage %s at %s is not a cluster member", stage.GetHostId(), stage.GetAddr())
+		}
+		cli, err := s.client(stage.GetAddr())
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "stage %s: %v", stage.GetHostId(), err)
+		}
+		return cli.Forward(ctx, req)
+	}
+
+	m := s.built()
+	var h []float32
+	if stage.GetStart() == 0 {
+		h = m.embedTokens(req.GetTokens())
+	} else {
+		h = slices.Clone(req.GetActivations().GetData())
+	}
+	m.run(stage.GetStart(), stage.GetEnd(), h)
+
+	rest := req.GetStages()[1:]
+	if len(rest) == 0 {
+		return &shardpb.ForwardResponse{Logits: m.logits(h), Hosts: []string{s.hostID}}, nil
+	}
+	resp, err := s.Run(ctx, &shardpb.ForwardRequest{
+		Model:       req.GetModel(),
+		Stages:      rest,
+		Activations: &shardpb.Tensor{Rows: 1, Cols: uint32(len(h)), Data: h},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Hosts = append([]string{s.hostID}, resp.GetHosts()...)
+	return resp, nil
+}
+
+// validate checks that req's stages cover the model's layers in order and
+// that it carries the input its first stage needs
+func validate(req *shardpb.ForwardRequest) error {
+	spec := req.GetModel()
+	stages := req.GetStages()
+	if len(stages) == 0 {
+		return status.Error(codes.InvalidArgument, "no stages to run")
+	}
+	next := stages[0].GetStart()
+	for _, st := range stages {
+		if st.GetStart() != next || st.GetEnd() <= st.GetStart() {
+			return status.Errorf(codes.InvalidArgument, "stage %s [%d, %d) does not follow layer %d",
+				st.GetHostId(), st.GetStart(), st.GetEnd(), next)
+		}
+		next = st.GetEnd()
+	}
+	if next != spec.GetLayers() {
+		return status.Errorf(codes.InvalidArgument, "stages end at layer %d of %d", next, spec.GetLayers())
+	}
+	if stages[0].GetStart() == 0 {
+		if len(req.GetTokens()) == 0 {
+			return status.Error(codes.InvalidArgument, "the first stage needs tokens")
+		}
+	} else if a := req.GetActivations(); a.GetRows() != 1 || a.GetCols() != spec.GetHidden() || len(a.GetData()) != int(spec.GetHidden()) {
+		return status.Errorf(codes.InvalidArgument, "activations must be 1×%d", spec.GetHidden())
+	}
+	return nil
+}
+
+// built returns the service's model, building it on first use
+func (s *Service) built() *toyModel {
+	s.buildOnce.Do(func() {
+		s.logger.Info("building model shard", "model", s.spec.GetName(), "layers", s.spec.GetLayers(), "hidden", s.spec.GetHidden())
+		s.model = newToyModel(s.spec)
+	})
+	return s.model
+}
+
+// client returns a connection to the ShardService at addr, a peer the
+// service approved
+func (s *Service) client(addr string) (shardpb.ShardServiceClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, ok := s.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(addr, append(append(tracing.DialOptions(), auth.ForwardCredentials()...),
+			grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+		if err != nil {
+			return nil, err
+		}
+		s.conns[addr] = conn
+	}
+	return shardpb.NewShardServiceClient(conn), nil
+}
+
+// Close drops the connections to other hosts.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for addr, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, addr)
+	}
+	return nil
+}
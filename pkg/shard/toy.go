@@ -0,0 +1,176 @@
+package shard
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+
+	shardpb "github.com/Billy-Davies-2/llm-test/pkg/proto/shard"
+)
+
+// The toy model is a reference for testing the pipeline on CPU without
+// model files. Its weights are drawn from a PRNG seeded by the ModelSpec,
+// so every host builds the same model and no weights need to be shipped.
+//
+// Tokens are printable ASCII plus newline and an end-of-text token. Each
+// layer is a residual tanh(Wh+b) block applied to the last position; the
+// embedding stage folds the rest of the context into that position as a
+// decaying running sum. It generates nonsense, but deterministic nonsense
+// that depends on the whole prompt, which is all the tests need.
+
+const (
+	tokEOS     = 0
+	tokNewline = 1
+	firstChar  = 2 // id of ' ', the first printable character
+	vocabSize  = firstChar + '~' - ' ' + 1
+
+	// contextDecay weighs each earlier token in the running sum
+	contextDecay = 0.5
+)
+
+// toyModel is a built ModelSpec
+type toyModel struct {
+	spec  *shardpb.ModelSpec
+	embed [][]float32 // vocabSize × hidden
+	head  [][]float32 // vocabSize × hidden
+
+	mu     sync.Mutex
+	layers map[uint32]*toyLayer // built on first use
+}
+
+type toyLayer struct {
+	w [][]float32 // hidden × hidden
+	b []float32
+}
+
+func newToyModel(spec *shardpb.ModelSpec) *toyModel {
+	h := int(spec.GetHidden())
+	r := rand.New(rand.NewPCG(spec.GetSeed(), math.MaxUint64))
+	embed := make([][]float32, vocabSize)
+	for i := range embed {
+		embed[i] = randVec(r, h, 1)
+	}
+	// the head is not tied to the embedding, which would make the last
+	// token the likeliest next one and the model repeat itself
+	head := make([][]float32, vocabSize)
+	for i := range head {
+		head[i] = randVec(r, h, 1)
+	}
+	return &toyModel{spec: spec, embed: embed, head: head, layers: map[uint32]*toyLayer{}}
+}
+
+// layer builds layer i's weights from its own PRNG stream, so a stage
+// only builds the layers it runs
+func (m *toyModel) layer(i uint32) *toyLayer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.layers[i]; ok {
+		return l
+	}
+	h := int(m.spec.GetHidden())
+	r := rand.New(rand.NewPCG(m.spec.GetSeed(), uint64(i)))
+	scale := 1 / math.Sqrt(float64(h))
+	l := &toyLayer{w: make([][]float32, h), b: randVec(r, h, 0.1)}
+	for j := range l.w {
+		l.w[j] = randVec(r, h, scale)
+	}
+	m.layers[i] = l
+	return l
+}
+
+func randVec(r *rand.Rand, n int, scale float64) []float32 {
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = float32(r.NormFloat64() * scale)
+	}
+	return v
+}
+
+// embedTokens turns a context into the activation of its last position,
+// with a sinusoidal encoding of that position
+func (m *toyModel) embedTokens(tokens []uint32) []float32 {
+	h := make([]float32, m.spec.GetHidden())
+	for _, t := range tokens {
+		e := m.embed[min(int(t), vocabSize-1)]
+		for i := range h {
+			h[i] = contextDecay*h[i] + e[i]
+		}
+	}
+	pos := float64(len(tokens))
+	for i := range h {
+		freq := math.Pow(10000, -float64(i/2*2)/float64(len(h)))
+		if i%2 == 0 {
+			h[i] += float32(math.Sin(pos * freq))
+		} else {
+			h[i] += float32(math.Cos(pos * freq))
+		}
+	}
+	return h
+}
+
+// run applies layers [start, end) to h in place
+func (m *toyModel) run(start, end uint32, h []float32) {
+	out := make([]float32, len(h))
+	for i := start; i < end; i++ {
+		l := m.layer(i)
+		for j, row := range l.w {
+			var sum float32
+			for k, w := range row {
+				sum += w * h[k]
+			}
+			out[j] = float32(math.Tanh(float64(sum + l.b[j])))
+		}
+		for j := range h {
+			h[j] += out[j]
+		}
+	}
+}
+
+// logits scores every token against h through the output head, scaled by
+// 1/√hidden to keep the distribution from collapsing onto one token
+func (m *toyModel) logits(h []float32) []float32 {
+	scale := float32(1 / math.Sqrt(float64(len(h))))
+	out := make([]float32, vocabSize)
+	for t, e := range m.head {
+		var sum float32
+		for i, x := range e {
+			sum += x * h[i]
+		}
+		out[t] = sum * scale
+	}
+	return out
+}
+
+// layerMB is how much memory one layer's weights take
+func layerMB(spec *shardpb.ModelSpec) float64 {
+	h := float64(spec.GetHidden())
+	return (h*h + h) * 4 / (1 << 20)
+}
+
+// tokenize maps text onto the toy vocabulary; characters outside it
+// become spaces
+func tokenize(text string) []uint32 {
+	ids := make([]uint32, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			ids = append(ids, tokNewline)
+		case r >= ' ' && r <= '~':
+			ids = append(ids, uint32(r-' ')+firstChar)
+		default:
+			ids = append(ids, firstChar)
+		}
+	}
+	return ids
+}
+
+// detokenize is the text of one token; end-of-text has none
+func detokenize(id uint32) string {
+	switch {
+	case id == tokNewline:
+		return "\n"
+	case id >= firstChar && id < vocabSize:
+		return string(rune(id-firstChar) + ' ')
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// fileClient is an otlptrace client that appends each batch of spans to w
+// as one line of OTLP/JSON, the ExportTraceServiceRequest a collector's
+// OTLP JSON file receiver reads
+type fileClient struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (c *fileClient) Start(context.Context) error { return nil }
+
+func (c *fileClient) Stop(context.Context) error { return nil }
+
+func (c *fileClient) UploadTraces(_ context.Context, spans []*tracepb.ResourceSpans) error {
+	line, err := otlpJSON(&coltracepb.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(append(line, '\n'))
+	return err
+}
+
+// otlpJSON encodes m as OTLP/JSON, which departs from protobuf's JSON
+// mapping by writing enums as numbers and trace and span IDs in hex
+// rather than base64
+func otlpJSON(m proto.Message) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	hexIDs(doc)
+	return json.Marshal(doc)
+}
+
+// hexIDs re-encodes the base64 trace and span IDs anywhere in v as hex
+func hexIDs(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, field := range v {
+			switch key {
+			case "traceId", "spanId", "parentSpanId":
+				if s, ok := field.(string); ok {
+					if id, err := base64.StdEncoding.DecodeString(s); err == nil {
+						v[key] = hex.EncodeToString(id)
+					}
+				}
+			default:
+				hexIDs(field)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			hexIDs(item)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// record sends the spans of the test to an in-memory exporter
+func record(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	if _, err := tracing.Setup(context.Background(), tracing.Config{}); err != nil {
+		t.Fatalf("Setup(): unexpected error: %v", err)
+	}
+	spans := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return spans
+}
+
+// dialHealth serves the gRPC health service with the tracing
+// interceptors and returns a traced client of it
+func dialHealth(t *testing.T) healthpb.HealthClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(tracing.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(tracing.StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(tracing.DialOptions(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestTraceContextCrossesCalls(t *testing.T) {
+	spans := record(t)
+	cli := dialHealth(t)
+
+	ctx, root := tracing.Start(context.Background(), "caller")
+	if _, err := cli.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check(): unexpected error: %v", err)
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	watch, err := cli.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch(): unexpected error: %v", err)
+	}
+	if _, err := watch.Recv(); err != nil {
+		t.Fatalf("Recv(): unexpected error: %v", err)
+	}
+	// stopping a stream part way ends its spans too, the server's once it
+	// sees the cancel
+	cancel()
+	watch.Recv()
+	root.End()
+	for deadline := time.Now().Add(5 * time.Second); len(spans.GetSpans()) < 5; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d spans; want 5", len(spans.GetSpans()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := map[string][]tracetest.SpanStub{}
+	for _, s := range spans.GetSpans() {
+		got[s.Name] = append(got[s.Name], s)
+	}
+	for _, method := range []string{"grpc.health.v1.Health/Check", "grpc.health.v1.Health/Watch"} {
+		calls := got[method]
+		if len(calls) != 2 {
+			t.Fatalf("%d spans for %s; want the client's and the server's", len(calls), method)
+		}
+		client, server := calls[0], calls[1]
+		if client.SpanKind != trace.SpanKindClient {
+			client, server = server, client
+		}
+		if client.Parent.SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s client span parent = %v; want the caller's span", method, client.Parent.SpanID())
+		}
+		if server.Parent.SpanID() != client.SpanContext.SpanID() || !server.Parent.IsRemote() {
+			t.Errorf("%s server span parent = %v; want the client span, received over the wire", method, server.Parent.SpanID())
+		}
+		if server.SpanContext.TraceID() != root.SpanContext().TraceID() {
+			t.Errorf("%s server span is in trace %v; want the caller's", method, server.SpanContext.TraceID())
+		}
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.json")
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{ServiceName: "test", File: path})
+	if err != nil {
+		t.Fatalf("Setup(): unexpected error: %v", err)
+	}
+	_, span := tracing.Start(context.Background(), "written")
+	tracing.End(span, nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown(): unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(): unexpected error: %v", err)
+	}
+	// one OTLP/JSON export request per line
+	var got struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value struct{ StringValue string }
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID string
+					SpanID  string
+					Name    string
+					Kind    int
+				}
+			}
+		}
+	}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&got); err != nil {
+		t.Fatalf("span file is not JSON: %v\n%s", err, data)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("span file = %s; want one span", data)
+	}
+	written := got.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if written.Name != "written" {
+		t.Errorf("span name = %q; want written", written.Name)
+	}
+	if written.TraceID != span.SpanContext().TraceID().String() || written.SpanID != span.SpanContext().SpanID().String() {
+		t.Errorf("span IDs = %s/%s; want %s/%s in hex", written.TraceID, written.SpanID, span.SpanContext().TraceID(), span.SpanContext().SpanID())
+	}
+	if written.Kind != int(trace.SpanKindInternal) {
+		t.Errorf("span kind = %d; want %d", written.Kind, trace.SpanKindInternal)
+	}
+	service := ""
+	for _, kv := range got.ResourceSpans[0].Resource.Attributes {
+		if kv.Key == "service.name" {
+			service = kv.Value.StringValue
+		}
+	}
+	if service != "test" {
+		t.Errorf("service.name = %q; want test", service)
+	}
+}
@@ -1,6 +1,9 @@
 package tui
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"slices"
@@ -8,9 +11,13 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"github.com/Billy-Davies-2/llm-test/pkg/client"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
 	"github.com/Billy-Davies-2/llm-test/pkg/tui/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // ── Chat-Page Commands ────────────────────────────────────────────────
@@ -27,6 +34,167 @@ func pasteTick() tea.Cmd {
 	})
 }
 
+// ── Streaming replies ────────────────────────────────────────────────
+
+// chatStream delivers the chunks of one streamed reply into Update.
+type chatStream chan tea.Msg
+
+// chatChunkMsg is a single streamed chunk for the tab owning stream.
+type chatChunkMsg struct {
+	stream chatStream
+	chunk  *chatpb.ChatChunk
+}
+
+// chatDoneMsg ends a stream; err is nil if the server finished normally.
+type chatDoneMsg struct {
+	stream chatStream
+	err    error
+}
+
+// chatSessionMsg reports the session created for the tab owning stream.
+type chatSessionMsg struct {
+	stream    chatStream
+	sessionID string
+}
+
+// startChat sends text as the next turn of t's session over c, creating
+// the session first if the tab does not have one yet. A server that keeps
+// no sessions for an anonymous caller is sent the tab's history with each
+// turn instead. It returns the stream the replies arrive on and a func
+// that abandons it.
+func startChat(c *client.Client, t tab, text string) (chatStream, context.CancelFunc) {
+	s := make(chatStream, 16)
+	sessionID, title, sampling := t.sessionID, t.title, t.sampling
+	// the history ends with the turn being sent as text
+	earlier := slices.Clone(t.history[:max(len(t.history)-1, 0)])
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		req := &chatpb.ChatRequest{Text: text, Sampling: sampling, RequestId: t.requestID}
+		if sessionID == "" {
+			sess, err := c.CreateSession(ctx, title)
+			switch {
+			case status.Code(err) == codes.Unauthenticated:
+				req.Messages = earlier
+			case err != nil:
+				s <- chatDoneMsg{stream: s, err: err}
+				return
+			default:
+				sessionID = sess.GetId()
+				s <- chatSessionMsg{stream: s, sessionID: sessionID}
+			}
+		}
+		req.SessionId = sessionID
+		err := c.StreamChat(ctx, req, func(chunk *chatpb.ChatChunk) {
+			s <- chatChunkMsg{stream: s, chunk: chunk}
+		})
+		s <- chatDoneMsg{stream: s, err: err}
+	}()
+	return s, cancel
+}
+
+// cancelChatCmd asks the server to stop the reply streaming into t. The
+// stream is dropped locally instead if the server cannot be told.
+func cancelChatCmd(c *client.Client, t tab) tea.Cmd {
+	id, stop := t.requestID, t.stop
+	return func() tea.Msg {
+		if err := c.Cancel(context.Background(), id); err != nil {
+			log.Printf("cancel request %s: %v", id, err)
+			stop()
+		}
+		return nil
+	}
+}
+
+// newRequestID picks the id a reply can be cancelled by
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// sessionsMsg carries the stored sessions fetched at startup.
+type sessionsMsg struct {
+	sessions []*chatpb.Session
+	err      error
+}
+
+// loadSessionsCmd fetches every stored session with its messages so the
+// tabs from a previous run can be restored.
+func loadSessionsCmd(c *client.Client) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		list, err := c.ListSessions(ctx)
+		if err != nil {
+			return sessionsMsg{err: err}
+		}
+		full := make([]*chatpb.Session, 0, len(list))
+		for i := len(list) - 1; i >= 0; i-- { // oldest first, like tabs
+			sess, err := c.GetSession(ctx, list[i].GetId())
+			if err != nil {
+				return sessionsMsg{err: err}
+			}
+			full = append(full, sess)
+		}
+		return sessionsMsg{sessions: full}
+	}
+}
+
+// deleteSessionCmd removes the session behind a closed tab.
+func deleteSessionCmd(c *client.Client, id string) tea.Cmd {
+	if c == nil || id == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := c.DeleteSession(context.Background(), id); err != nil {
+			log.Printf("delete session %s: %v", id, err)
+		}
+		return nil
+	}
+}
+
+// sessionTab rebuilds a tab from a stored session.
+func sessionTab(sess *chatpb.Session) tab {
+	t := tab{title: sess.GetTitle(), sessionID: sess.GetId(), reply: -1}
+	if t.title == "" {
+		t.title = "Session"
+	}
+	for _, msg := range sess.GetMessages() {
+		prefix := "You: "
+		switch msg.GetRole() {
+		case chatpb.Role_ROLE_ASSISTANT:
+			prefix = "AI: "
+		case chatpb.Role_ROLE_SYSTEM:
+			prefix = "System: "
+		}
+		t.messages = append(t.messages, prefix+msg.GetContent())
+		t.history = append(t.history, msg)
+	}
+	return t
+}
+
+// waitChat blocks until the next message on s.
+func waitChat(s chatStream) tea.Cmd {
+	return func() tea.Msg { return <-s }
+}
+
+// abandon drops the tab's in-flight stream, if any.
+func (t *tab) abandon() {
+	if t.stop != nil {
+		t.stop()
+	}
+	t.stream, t.stop, t.requestID, t.queuePos = nil, nil, "", 0
+}
+
+// tabForStream finds the tab that owns s, or -1 if it has been closed.
+func (m model) tabForStream(s chatStream) int {
+	for i := range m.tabs {
+		if m.tabs[i].stream == s {
+			return i
+		}
+	}
+	return -1
+}
+
 // codeStyle highlights the input area
 var codeStyle = lipgloss.NewStyle().
 	Background(lipgloss.Color("#002b36")).
@@ -113,6 +281,18 @@ func (m model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch s {
 			case "q":
 				return m, tea.Quit
+			case tea.KeyCtrlC.String():
+				// stop the reply being generated in this tab
+				if cur.stream != nil {
+					return m, cancelChatCmd(m.chat, *cur)
+				}
+				if cur.thinking {
+					cur.messages = append(cur.messages, "AI: [cancelled]")
+					cur.history = append(cur.history, &chatpb.ChatMessage{Role: chatpb.Role_ROLE_ASSISTANT})
+					cur.thinking = false
+					cur.dots = 0
+				}
+				return m, nil
 			case "i":
 				m.insertMode = true
 				return m, nil
@@ -150,6 +330,8 @@ func (m model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			case "d":
 				if m.lastKey == "d" && len(m.tabs) > 1 {
+					cur.abandon()
+					cmd := deleteSessionCmd(m.chat, cur.sessionID)
 					m.tabs = slices.Delete(m.tabs, m.currentTab, m.currentTab+1)
 					if len(m.tabs) == 0 {
 						return m, tea.Quit
@@ -157,6 +339,8 @@ func (m model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if m.currentTab >= len(m.tabs) {
 						m.currentTab = len(m.tabs) - 1
 					}
+					m.lastKey = "d"
+					return m, cmd
 				}
 				m.lastKey = "d"
 				return m, nil
@@ -172,11 +356,35 @@ func (m model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		switch s {
 		case "enter":
+			if args, ok := strings.CutPrefix(cur.input, setCommand); ok && (args == "" || args[0] == ' ') {
+				cur.input = ""
+				p, err := applySet(cur.sampling, args)
+				if err != nil {
+					cur.messages = append(cur.messages, "Error: "+err.Error())
+					return m, nil
+				}
+				cur.sampling = p
+				cur.messages = append(cur.messages, "Sampling: "+formatSampling(p))
+				return m, nil
+			}
+			if cur.stream != nil {
+				// one reply at a time per tab
+				return m, nil
+			}
 			cur.messages = append(cur.messages, "You: "+cur.input)
+			text := cur.input
+			cur.history = append(cur.history, &chatpb.ChatMessage{Role: chatpb.Role_ROLE_USER, Content: cur.input})
 			cur.input = ""
 			cur.thinking = true
 			cur.dots = 0
-			return m, thinkCmd()
+			if m.chat == nil {
+				return m, thinkCmd()
+			}
+			cur.requestID = newRequestID()
+			cur.stream, cur.stop = startChat(m.chat, *cur, text)
+			cur.reply = -1
+			cur.partial = ""
+			return m, tea.Batch(thinkCmd(), waitChat(cur.stream))
 		case "backspace":
 			if len(cur.input) > 0 {
 				_, sz := utf8.DecodeLastRuneInString(cur.input)
@@ -203,17 +411,101 @@ func (m model) updateChat(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case thinkMsg:
+		if cur.thinking && cur.stream != nil {
+			// keep animating until the first chunk lands
+			cur.dots = (cur.dots + 1) % 4
+			return m, thinkCmd()
+		}
 		if cur.thinking {
 			if cur.dots < 3 {
 				cur.dots++
 				return m, thinkCmd()
 			}
 			cur.messages = append(cur.messages, "AI: epic response")
+			cur.history = append(cur.history, &chatpb.ChatMessage{Role: chatpb.Role_ROLE_ASSISTANT, Content: "epic response"})
 			cur.thinking = false
 			cur.dots = 0
 		}
 		return m, nil
 
+	case chatChunkMsg:
+		i := m.tabForStream(msg.stream)
+		if i < 0 {
+			// tab was closed; drain the rest of the stream
+			return m, waitChat(msg.stream)
+		}
+		t := &m.tabs[i]
+		t.queuePos = int(msg.chunk.GetQueuePosition())
+		if d := msg.chunk.GetDelta(); d != "" {
+			if t.reply < 0 {
+				t.messages = append(t.messages, "AI: ")
+				t.reply = len(t.messages) - 1
+				t.thinking = false
+			}
+			t.messages[t.reply] += d
+			t.partial += d
+		}
+		if msg.chunk.GetFinishReason() == chatpb.FinishReason_FINISH_REASON_CANCELLED {
+			if t.reply < 0 {
+				t.messages = append(t.messages, "AI: ")
+				t.reply = len(t.messages) - 1
+				t.thinking = false
+			}
+			t.messages[t.reply] += " [cancelled]"
+		}
+		if u := msg.chunk.GetUsage(); u != nil {
+			log.Printf("chat reply finished reason=%v tokens=%d", msg.chunk.GetFinishReason(), u.GetTotalTokens())
+		}
+		return m, waitChat(msg.stream)
+
+	case chatSessionMsg:
+		if i := m.tabForStream(msg.stream); i >= 0 {
+			m.tabs[i].sessionID = msg.sessionID
+		}
+		return m, waitChat(msg.stream)
+
+	case sessionsMsg:
+		if msg.err != nil {
+			log.Printf("loading sessions: %v", msg.err)
+			return m, nil
+		}
+		var restored []tab
+		for _, sess := range msg.sessions {
+			restored = append(restored, sessionTab(sess))
+		}
+		if len(restored) == 0 {
+			return m, nil
+		}
+		// replace the blank starting tab, but keep anything already in use
+		if len(m.tabs) == 1 && m.tabs[0].sessionID == "" && len(m.tabs[0].history) == 0 && m.tabs[0].stream == nil {
+			m.tabs = restored
+			m.currentTab = len(m.tabs) - 1
+		} else {
+			m.tabs = append(m.tabs, restored...)
+		}
+		return m, nil
+
+	case chatDoneMsg:
+		i := m.tabForStream(msg.stream)
+		if i < 0 {
+			return m, nil
+		}
+		t := &m.tabs[i]
+		if msg.err != nil {
+			t.messages = append(t.messages, "AI error: "+msg.err.Error())
+			// forget the unanswered turn so the next message does not repeat it
+			if n := len(t.history); n > 0 && t.history[n-1].GetRole() == chatpb.Role_ROLE_USER {
+				t.history = t.history[:n-1]
+			}
+		} else {
+			t.history = append(t.history, &chatpb.ChatMessage{Role: chatpb.Role_ROLE_ASSISTANT, Content: t.partial})
+		}
+		t.abandon()
+		t.partial = ""
+		t.thinking = false
+		t.dots = 0
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -259,13 +551,16 @@ func (m model) updateChatMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 			line := fmt.Sprintf("> %s [x]", m.tabs[idx].title)
 			closeX := padX + len(line) - 3
 			if msg.X >= closeX {
+				m.tabs[idx].abandon()
+				cmd := deleteSessionCmd(m.chat, m.tabs[idx].sessionID)
 				m.tabs = slices.Delete(m.tabs, idx, idx+1)
 				if len(m.tabs) == 0 {
-					return m, tea.Quit
+					return m, tea.Sequence(cmd, tea.Quit)
 				}
 				if m.currentTab >= len(m.tabs) {
 					m.currentTab = len(m.tabs) - 1
 				}
+				return m, cmd
 			} else {
 				m.dragging = true
 				m.dragIndex = idx
@@ -352,7 +647,11 @@ func (m model) viewChat() string {
 	}
 	if cur.thinking {
 		dots := strings.Repeat(".", cur.dots)
-		chatLines = append(chatLines, style.Render("AI is thinking"+dots))
+		status := "AI is thinking"
+		if cur.queuePos > 0 {
+			status = fmt.Sprintf("Queued (#%d)", cur.queuePos)
+		}
+		chatLines = append(chatLines, style.Render(status+dots))
 	}
 
 	if len(chatLines) > chatH {
@@ -392,7 +691,7 @@ func (m model) viewChat() string {
 		Faint(true).
 		Align(lipgloss.Center).
 		Width(m.width).
-		Render("q:Quit | M:Metrics | i:Insert | dd:Close | p:Paste | yy:Copy | gt/gT:Tabs | z:Sidebar")
+		Render("q:Quit | M:Metrics | i:Insert | ^C:Stop | dd:Close | p:Paste | yy:Copy | gt/gT:Tabs | z:Sidebar")
 
 	return panel + "\n" + footer
 }
@@ -0,0 +1,192 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// send runs one message through Update and returns the resulting model.
+func send(t *testing.T, m model, msg tea.Msg) model {
+	t.Helper()
+	next, _ := m.Update(msg)
+	return next.(model)
+}
+
+func typeText(t *testing.T, m model, text string) model {
+	t.Helper()
+	for _, r := range text {
+		m = send(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func TestChatHistoryAcrossTurns(t *testing.T) {
+	m := InitialModel()
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	for _, text := range []string{"first", "second"} {
+		m = typeText(t, m, text)
+		m = send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		for m.tabs[0].thinking {
+			m = send(t, m, thinkMsg{})
+		}
+	}
+
+	h := m.tabs[0].history
+	want := []chatpb.Role{
+		chatpb.Role_ROLE_USER, chatpb.Role_ROLE_ASSISTANT,
+		chatpb.Role_ROLE_USER, chatpb.Role_ROLE_ASSISTANT,
+	}
+	if len(h) != len(want) {
+		t.Fatalf("history has %d turns; want %d", len(h), len(want))
+	}
+	for i, r := range want {
+		if h[i].GetRole() != r {
+			t.Errorf("history[%d].Role = %v; want %v", i, h[i].GetRole(), r)
+		}
+	}
+	if got := h[2].GetContent(); got != "second" {
+		t.Errorf("history[2].Content = %q; want %q", got, "second")
+	}
+}
+
+func TestChatStreamedReply(t *testing.T) {
+	m := InitialModel()
+	s := make(chatStream)
+	m.tabs[0].stream = s
+	m.tabs[0].reply = -1
+	m.tabs[0].thinking = true
+	m.tabs[0].history = []*chatpb.ChatMessage{{Role: chatpb.Role_ROLE_USER, Content: "hi"}}
+
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{Delta: "Hello "}})
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{Delta: "there"}})
+	m = send(t, m, chatDoneMsg{stream: s})
+
+	cur := m.tabs[0]
+	if got, want := cur.messages[len(cur.messages)-1], "AI: Hello there"; got != want {
+		t.Errorf("last message = %q; want %q", got, want)
+	}
+	if cur.stream != nil || cur.thinking {
+		t.Error("stream still marked in flight after done")
+	}
+	if n := len(cur.history); n != 2 || cur.history[1].GetContent() != "Hello there" {
+		t.Errorf("history = %v; want assistant reply appended", cur.history)
+	}
+}
+
+func TestRestoreSessions(t *testing.T) {
+	m := InitialModel()
+	m = send(t, m, sessionsMsg{sessions: []*chatpb.Session{
+		{Id: "s1", Title: "Old chat", Messages: []*chatpb.ChatMessage{
+			{Role: chatpb.Role_ROLE_USER, Content: "hi"},
+			{Role: chatpb.Role_ROLE_ASSISTANT, Content: "hello"},
+		}},
+		{Id: "s2", Title: "Newer chat"},
+	}})
+
+	if len(m.tabs) != 2 {
+		t.Fatalf("got %d tabs; want 2 restored sessions", len(m.tabs))
+	}
+	first := m.tabs[0]
+	if first.sessionID != "s1" || first.title != "Old chat" {
+		t.Errorf("tab 0 = %q/%q; want s1/Old chat", first.sessionID, first.title)
+	}
+	if len(first.messages) != 2 || first.messages[1] != "AI: hello" {
+		t.Errorf("tab 0 messages = %q", first.messages)
+	}
+	if m.currentTab != 1 {
+		t.Errorf("currentTab = %d; want the newest session", m.currentTab)
+	}
+}
+
+func TestSetSampling(t *testing.T) {
+	m := InitialModel()
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	m = typeText(t, m, ":set temperature=0.2 seed=42 stop=END")
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	cur := m.tabs[0]
+	if cur.thinking || len(cur.history) != 0 {
+		t.Fatal(":set was sent as a chat message")
+	}
+	p := cur.sampling
+	if p.GetTemperature() != 0.2 || p.GetSeed() != 42 || len(p.GetStop()) != 1 || p.TopP != nil {
+		t.Errorf("sampling = %v; want temperature, seed and stop set", p)
+	}
+
+	m = typeText(t, m, ":set temperature= top_k=nope")
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.tabs[0].messages[len(m.tabs[0].messages)-1]; got[:6] != "Error:" {
+		t.Errorf("bad :set reported %q; want an error", got)
+	}
+	if m.tabs[0].sampling.Temperature == nil {
+		t.Error("failed :set still changed the settings")
+	}
+
+	m = typeText(t, m, ":set temperature=")
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.tabs[0].sampling.Temperature != nil {
+		t.Error("temperature= did not restore the default")
+	}
+}
+
+func TestCtrlCStopsReply(t *testing.T) {
+	m := InitialModel()
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	m = typeText(t, m, "tell me everything")
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	m = send(t, m, tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	cur := m.tabs[0]
+	if cur.thinking {
+		t.Error("still thinking after Ctrl-C")
+	}
+	if got := cur.messages[len(cur.messages)-1]; got != "AI: [cancelled]" {
+		t.Errorf("last message = %q; want the reply marked cancelled", got)
+	}
+}
+
+func TestCancelledStream(t *testing.T) {
+	m := InitialModel()
+	s := make(chatStream)
+	stopped := false
+	m.tabs[0].stream = s
+	m.tabs[0].stop = func() { stopped = true }
+	m.tabs[0].reply = -1
+	m.tabs[0].history = []*chatpb.ChatMessage{{Role: chatpb.Role_ROLE_USER, Content: "hi"}}
+
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{Delta: "Hel"}})
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{FinishReason: chatpb.FinishReason_FINISH_REASON_CANCELLED}})
+	m = send(t, m, chatDoneMsg{stream: s})
+
+	cur := m.tabs[0]
+	if got, want := cur.messages[len(cur.messages)-1], "AI: Hel [cancelled]"; got != want {
+		t.Errorf("last message = %q; want %q", got, want)
+	}
+	if n := len(cur.history); n != 2 || cur.history[1].GetContent() != "Hel" {
+		t.Errorf("history = %v; want the partial reply kept", cur.history)
+	}
+	if !stopped || cur.stream != nil {
+		t.Error("finished stream was not released")
+	}
+}
+
+func TestQueuedReply(t *testing.T) {
+	m := InitialModel()
+	s := make(chatStream)
+	m.tabs[0].stream = s
+	m.tabs[0].reply = -1
+	m.tabs[0].thinking = true
+
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{QueuePosition: 2}})
+	if !strings.Contains(m.View(), "Queued (#2)") {
+		t.Error("view does not show the queue position")
+	}
+	m = send(t, m, chatChunkMsg{stream: s, chunk: &chatpb.ChatChunk{Delta: "Hi"}})
+	if m.tabs[0].queuePos != 0 {
+		t.Errorf("queuePos = %d after generation started; want 0", m.tabs[0].queuePos)
+	}
+}
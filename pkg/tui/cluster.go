@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Billy-Davies-2/llm-test/pkg/client"
+	metrics "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"github.com/Billy-Davies-2/llm-test/pkg/tracing"
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// pollTimeout bounds each system page RPC, so a dead node cannot hold up
+// the next refresh
+const pollTimeout = time.Second
+
+// trendLen is how many samples the system page draws each host's CPU
+// trend from
+const trendLen = 24
+
+// defaultWatchInterval is how often each server is asked for metrics
+// unless WithMetricsInterval says otherwise
+const defaultWatchInterval = 2 * time.Second
+
+// clusterMsg carries the backends the chat server knows of
+type clusterMsg struct {
+	nodes []*metrics.NodeInfo
+	err   error
+}
+
+// serverMetricsMsg carries one server's latest metrics, or the history
+// that came before them
+type serverMetricsMsg struct {
+	url     string
+	data    *metrics.MetricsResponse
+	history []*metrics.MetricsResponse
+	err     error
+}
+
+func clusterCmd(c *client.Client) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+		defer cancel()
+		nodes, err := c.ClusterState(ctx)
+		return clusterMsg{nodes: nodes, err: err}
+	}
+}
+
+// pollServers refreshes the cluster view; metrics arrive by themselves
+// from each server's watch
+func (m model) pollServers() tea.Cmd {
+	if m.chat == nil {
+		return nil
+	}
+	return clusterCmd(m.chat)
+}
+
+// waitMetrics blocks until the next snapshot from any watched server.
+func waitMetrics(ch chan serverMetricsMsg) tea.Cmd {
+	return func() tea.Msg { return <-ch }
+}
+
+// watchCmd streams srv's metrics into out until ctx ends, calling again a
+// while after each failure.
+func watchCmd(ctx context.Context, srv ServerMetrics, interval time.Duration, out chan<- serverMetricsMsg) tea.Cmd {
+	url, cli := srv.URL, srv.Client
+	return func() tea.Msg {
+		deliver := func(data *metrics.MetricsResponse, err error) bool {
+			select {
+			case out <- serverMetricsMsg{url: url, data: data, err: err}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			if history := recentHistory(ctx, cli, interval); len(history) > 0 {
+				select {
+				case out <- serverMetricsMsg{url: url, history: history}:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			err := watchMetrics(ctx, cli, interval, func(data *metrics.MetricsResponse) bool {
+				return deliver(data, nil)
+			})
+			if ctx.Err() != nil || !deliver(nil, err) {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// recentHistory fetches enough of a server's history to fill the trend,
+// thinned to one sample per interval to match the watch. Servers without
+// history give none.
+func recentHistory(ctx context.Context, cli metrics.MetricsServiceClient, interval time.Duration) []*metrics.MetricsResponse {
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+	resp, err := cli.GetMetricsHistory(ctx, &metrics.MetricsHistoryRequest{Window: durationpb.New(trendLen * interval)})
+	if err != nil {
+		return nil
+	}
+	var out []*metrics.MetricsResponse
+	var last time.Time
+	for _, s := range resp.GetSamples() {
+		if at := s.GetSampledAt().AsTime(); len(out) == 0 || at.Sub(last) >= interval {
+			out, last = append(out, s), at
+		}
+	}
+	return out
+}
+
+// watchMetrics hands each snapshot from one WatchMetrics call to fn until
+// the stream fails or fn returns false. Servers that predate WatchMetrics
+// are polled instead.
+func watchMetrics(ctx context.Context, cli metrics.MetricsServiceClient, interval time.Duration, fn func(*metrics.MetricsResponse) bool) error {
+	stream, err := cli.WatchMetrics(ctx, &metrics.WatchMetricsRequest{Interval: durationpb.New(interval)})
+	if err != nil {
+		return err
+	}
+	for {
+		data, err := stream.Recv()
+		if status.Code(err) == codes.Unimplemented {
+			return pollMetrics(ctx, cli, interval, fn)
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(data) {
+			return nil
+		}
+	}
+}
+
+func pollMetrics(ctx context.Context, cli metrics.MetricsServiceClient, interval time.Duration, fn func(*metrics.MetricsResponse) bool) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+		data, err := cli.GetMetrics(callCtx, &emptypb.Empty{})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !fn(data) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// watch starts streaming srv's metrics for as long as it stays in the
+// cluster
+func (m model) watch(srv ServerMetrics) (ServerMetrics, tea.Cmd) {
+	if srv.Client == nil {
+		return srv, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	srv.stop = cancel
+	return srv, watchCmd(ctx, srv, m.watchInterval, m.watches)
+}
+
+// dialServer connects to a server's metrics service with opts added; the
+// connection is established lazily on first use
+func dialServer(srv ServerMetrics, opts ...grpc.DialOption) ServerMetrics {
+	conn, err := grpc.NewClient(srv.URL, append(append(tracing.DialOptions(), grpc.WithTransportCredentials(insecure.NewCredentials())), opts...)...)
+	if err != nil {
+		srv.Err = err
+		return srv
+	}
+	srv.conn = conn
+	srv.Client = metrics.NewMetricsServiceClient(conn)
+	return srv
+}
+
+// updateCluster replaces the gossiped servers with the latest cluster
+// state, keeping connections to nodes still present and watching new
+// ones. Servers given by hand stay as they are.
+func (m model) updateCluster(msg clusterMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		// keep showing the last known cluster
+		slog.Warn("cluster state unavailable", "err", msg.err)
+		return m, nil
+	}
+	var cmds []tea.Cmd
+	known := map[string]ServerMetrics{}
+	var servers []ServerMetrics
+	for _, srv := range m.servers {
+		if srv.Node == nil {
+			servers = append(servers, srv)
+		} else {
+			known[srv.Node.GetHostId()] = srv
+		}
+	}
+	for _, node := range msg.nodes {
+		srv, ok := known[node.GetHostId()]
+		if ok && srv.URL == node.GetMetricsAddr() {
+			delete(known, node.GetHostId())
+		} else {
+			srv = ServerMetrics{URL: node.GetMetricsAddr()}
+			if srv.URL != "" {
+				var cmd tea.Cmd
+				srv, cmd = m.watch(dialServer(srv, m.dialOpts...))
+				cmds = append(cmds, cmd)
+			}
+		}
+		srv.Node = node
+		servers = append(servers, srv)
+	}
+	// whatever is left has gone from the cluster
+	for _, srv := range known {
+		if srv.stop != nil {
+			srv.stop()
+		}
+		if srv.conn != nil {
+			srv.conn.Close()
+		}
+	}
+	m.servers = servers
+	return m, tea.Batch(cmds...)
+}
+
+func (m model) updateServerMetrics(msg serverMetricsMsg) model {
+	for i := range m.servers {
+		srv := &m.servers[i]
+		if srv.URL != msg.url {
+			continue
+		}
+		switch {
+		case msg.history != nil:
+			srv.Trend = make([]float64, 0, len(msg.history))
+			for _, s := range msg.history {
+				srv.Trend = append(srv.Trend, s.GetCpuUsagePercent())
+			}
+		case msg.err != nil:
+			srv.Err = msg.err
+		default:
+			srv.Err, srv.Data = nil, msg.data
+			srv.Trend = append(srv.Trend, msg.data.GetCpuUsagePercent())
+		}
+		if n := len(srv.Trend); n > trendLen {
+			srv.Trend = slices.Clone(srv.Trend[n-trendLen:])
+		}
+	}
+	return m
+}
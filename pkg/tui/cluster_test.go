@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestClusterStateServers(t *testing.T) {
+	m := InitialModel()
+	nodes := []*metrics.NodeInfo{
+		{HostId: "gpu-a", MetricsAddr: "10.0.0.1:50051", Models: []string{"llama-3-8b"}},
+		{HostId: "gpu-b", MetricsAddr: "10.0.0.2:50051", State: metrics.NodeState_NODE_STATE_SUSPECT},
+	}
+	m = send(t, m, clusterMsg{nodes: nodes})
+	if len(m.servers) != 2 {
+		t.Fatalf("servers = %d; want 2", len(m.servers))
+	}
+	for i, srv := range m.servers {
+		if srv.URL != nodes[i].GetMetricsAddr() || srv.Client == nil {
+			t.Errorf("servers[%d] = %s (client %v); want %s dialled", i, srv.URL, srv.Client, nodes[i].GetMetricsAddr())
+		}
+	}
+
+	m = send(t, m, serverMetricsMsg{url: "10.0.0.1:50051", history: []*metrics.MetricsResponse{
+		{CpuUsagePercent: 0}, {CpuUsagePercent: 50}, {CpuUsagePercent: 100},
+	}})
+	m = send(t, m, serverMetricsMsg{url: "10.0.0.1:50051", data: &metrics.MetricsResponse{
+		CpuUsagePercent: 12.5,
+		CpuCorePercent:  []float64{3, 96.5},
+		LoadAverage:     &metrics.LoadAverage{Load1: 2, Load5: 1.5, Load15: 0.25},
+		Disks:           []*metrics.DiskUsage{{Mountpoint: "/", UsedMb: 512, TotalMb: 2048}},
+		Networks:        []*metrics.NetworkInterface{{Name: "eth0", RxBytesPerSec: 2048, TxBytesPerSec: 10}},
+		UptimeSeconds:   90061,
+	}})
+	m.page = pageSystem
+	m.width, m.height = 200, 60
+	view := m.View()
+	for _, want := range []string{
+		"gpu-a", "gpu-b", "llama-3-8b", "CPU: 12.5%", "suspect",
+		"Busiest core: 96.5% of 2", "Load: 2.00 1.50 0.25", "Disk /: 25% of 2.0 GB",
+		"Net: ↓2.0 KB/s ↑10.0 B/s", "Up: 1d1h1m", "CPU ▁▅█▂",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("system page missing %q:\n%s", want, view)
+		}
+	}
+
+	// a node leaving drops its box; the rest keep their metrics
+	m = send(t, m, clusterMsg{nodes: nodes[:1]})
+	if len(m.servers) != 1 || m.servers[0].Data.GetCpuUsagePercent() != 12.5 {
+		t.Errorf("servers after gpu-b left = %+v; want gpu-a with its metrics", m.servers)
+	}
+}
+
+// streamingServer answers WatchMetrics with numbered snapshots
+type streamingServer struct {
+	metrics.UnimplementedMetricsServiceServer
+	interval chan time.Duration
+}
+
+func (s *streamingServer) WatchMetrics(req *metrics.WatchMetricsRequest, stream metrics.MetricsService_WatchMetricsServer) error {
+	s.interval <- req.GetInterval().AsDuration()
+	for i := 1; ; i++ {
+		if err := stream.Send(&metrics.MetricsResponse{HostId: "streamer", QueueDepth: uint32(i)}); err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// GetMetricsHistory returns samples 5ms apart, twice as often as the
+// test watches at
+func (s *streamingServer) GetMetricsHistory(context.Context, *metrics.MetricsHistoryRequest) (*metrics.MetricsHistoryResponse, error) {
+	start := time.Now().Add(-time.Second)
+	resp := &metrics.MetricsHistoryResponse{Interval: durationpb.New(5 * time.Millisecond)}
+	for i := range 10 {
+		resp.Samples = append(resp.Samples, &metrics.MetricsResponse{
+			HostId:    "streamer",
+			SampledAt: timestamppb.New(start.Add(time.Duration(i) * 5 * time.Millisecond)),
+		})
+	}
+	return resp, nil
+}
+
+func serveMetrics(t *testing.T, impl metrics.MetricsServiceServer) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	g := grpc.NewServer()
+	metrics.RegisterMetricsServiceServer(g, impl)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	return lis.Addr().String()
+}
+
+func TestWatchServerMetrics(t *testing.T) {
+	streamer := &streamingServer{interval: make(chan time.Duration, 1)}
+	tests := []struct {
+		name    string
+		impl    metrics.MetricsServiceServer
+		host    string
+		history int
+	}{
+		{"stream", streamer, "streamer", 5},
+		// a server without WatchMetrics or history is polled instead
+		{"poll", &metricsServer{hostID: "poller"}, "poller", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := InitialModel().WithMetricsInterval(10 * time.Millisecond)
+			srv, cmd := m.watch(dialServer(ServerMetrics{URL: serveMetrics(t, tt.impl)}))
+			defer srv.conn.Close()
+			done := make(chan struct{})
+			go func() {
+				cmd()
+				close(done)
+			}()
+
+			if tt.history > 0 {
+				if msg := <-m.watches; len(msg.history) != tt.history {
+					t.Errorf("first message has %d history samples; want %d, one per interval", len(msg.history), tt.history)
+				}
+			}
+			for range 3 {
+				select {
+				case msg := <-m.watches:
+					if msg.err != nil || msg.url != srv.URL || msg.data.GetHostId() != tt.host {
+						t.Fatalf("watch sent %+v; want metrics from %s at %s", msg, tt.host, srv.URL)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("no metrics from the watch")
+				}
+			}
+			srv.stop()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("watch kept running after stop")
+			}
+		})
+	}
+	if got := <-streamer.interval; got != 10*time.Millisecond {
+		t.Errorf("WatchMetrics interval = %v; want the 10ms asked for", got)
+	}
+}
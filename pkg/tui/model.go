@@ -1,10 +1,14 @@
 package tui
 
 import (
+	"context"
 	"time"
 
+	"github.com/Billy-Davies-2/llm-test/pkg/client"
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
 	metrics "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
 	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc"
 )
 
 // ── Messages ─────────────────────────────────────────────────────────
@@ -25,6 +29,14 @@ type ServerMetrics struct {
 	Client metrics.MetricsServiceClient
 	Data   *metrics.MetricsResponse
 	Err    error
+	Trend  []float64 // recent CPU usage, oldest first
+
+	// Node is what the server advertises through gossip; nil for servers
+	// given by hand
+	Node *metrics.NodeInfo
+
+	conn *grpc.ClientConn // owned when dialled for a gossiped node
+	stop func()           // ends the metrics watch of a gossiped node
 }
 
 // ── Tab & Model ──────────────────────────────────────────────────────
@@ -34,6 +46,23 @@ type tab struct {
 	input    string
 	thinking bool
 	dots     int
+
+	// server-side session the tab's conversation is stored in
+	sessionID string
+
+	// conversation sent to the backend with every message
+	history []*chatpb.ChatMessage
+
+	// sampling settings changed with :set; nil uses the server defaults
+	sampling *chatpb.SamplingParams
+
+	// in-flight streamed reply, if any
+	stream    chatStream
+	requestID string             // id the server knows the reply by, for Cancel
+	stop      context.CancelFunc // abandons the stream locally
+	queuePos  int                // place in the server's queue while waiting, 0 once running
+	reply     int                // index into messages of the reply being streamed, -1 if none yet
+	partial   string             // reply text received so far
 }
 
 type model struct {
@@ -61,8 +90,20 @@ type model struct {
 	dragging  bool
 	dragIndex int
 
-	// slice of servers to poll
+	// servers to poll, from the chat server's cluster state and any given
+	// by hand
 	servers []ServerMetrics
+
+	// every server's metrics watch delivers snapshots here, asking for
+	// one each watchInterval
+	watches       chan serverMetricsMsg
+	watchInterval time.Duration
+
+	// chat backend; nil means replies are faked locally
+	chat *client.Client
+
+	// added when dialing servers for metrics, e.g. per-RPC credentials
+	dialOpts []grpc.DialOption
 }
 
 // InitialModel constructs the starting model
@@ -76,6 +117,9 @@ func InitialModel() model {
 		height:      24,
 		page:        pageChat,
 		servers:     []ServerMetrics{},
+
+		watches:       make(chan serverMetricsMsg, 16),
+		watchInterval: defaultWatchInterval,
 	}
 }
 
@@ -91,23 +135,56 @@ func sysTickCmd() tea.Cmd {
 
 // ── Tea.Init ────────────────────────────────────────────────────────
 func (m model) Init() tea.Cmd {
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		tea.EnterAltScreen,
 		tea.EnableMouseAllMotion,
 		blinkCmd(),
 		sysTickCmd(),
-	)
+		waitMetrics(m.watches),
+	}
+	for _, srv := range m.servers {
+		if srv.Client != nil {
+			// servers given by hand are watched for as long as the TUI runs
+			cmds = append(cmds, watchCmd(context.Background(), srv, m.watchInterval, m.watches))
+		}
+	}
+	if m.chat != nil {
+		cmds = append(cmds, loadSessionsCmd(m.chat), clusterCmd(m.chat))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m model) NewModel(peers []string) model {
 	// Create a new model with the given peers and logger
 	m.servers = make([]ServerMetrics, len(peers))
 	for i, peer := range peers {
-		m.servers[i] = ServerMetrics{URL: peer}
+		m.servers[i] = dialServer(ServerMetrics{URL: peer}, m.dialOpts...)
 	}
 	return m
 }
 
+// WithMetricsInterval returns a copy of the model that asks servers for
+// metrics every d. Servers may send them less often.
+func (m model) WithMetricsInterval(d time.Duration) model {
+	if d > 0 {
+		m.watchInterval = d
+	}
+	return m
+}
+
+// WithDialOptions returns a copy of the model that adds opts when dialing
+// servers for their metrics.
+func (m model) WithDialOptions(opts ...grpc.DialOption) model {
+	m.dialOpts = opts
+	return m
+}
+
+// WithChat returns a copy of the model that sends chat messages through c.
+func (m model) WithChat(c *client.Client) model {
+	m.chat = c
+	return m
+}
+
 // ── Tea.Update ──────────────────────────────────────────────────────
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -138,12 +215,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case chatChunkMsg, chatDoneMsg, chatSessionMsg, sessionsMsg:
+		// streams keep flowing whichever page is visible
+		return m.updateChat(msg)
+
 	case tickMsg:
 		m.blink = !m.blink
 		return m, blinkCmd()
 
 	case sysTickMsg:
-		return m, sysTickCmd()
+		return m, tea.Batch(sysTickCmd(), m.pollServers())
+
+	case clusterMsg:
+		return m.updateCluster(msg)
+
+	case serverMetricsMsg:
+		return m.updateServerMetrics(msg), waitMetrics(m.watches)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	chatpb "github.com/Billy-Davies-2/llm-test/pkg/proto/chat"
+	"google.golang.org/protobuf/proto"
+)
+
+// setCommand is typed into the input to change the tab's sampling, e.g.
+// ":set temperature=0.2 seed=42". A bare ":set" shows the current values
+// and "name=" goes back to the server default.
+const setCommand = ":set"
+
+// applySet returns p updated by the name=value pairs in args, leaving p
+// itself untouched.
+func applySet(p *chatpb.SamplingParams, args string) (*chatpb.SamplingParams, error) {
+	out := &chatpb.SamplingParams{}
+	if p != nil {
+		out = proto.Clone(p).(*chatpb.SamplingParams)
+	}
+	for _, arg := range strings.Fields(args) {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected name=value, got %q", arg)
+		}
+		if err := setSampling(out, name, value); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// setSampling sets one field of p, or clears it when value is empty
+func setSampling(p *chatpb.SamplingParams, name, value string) error {
+	var err error
+	switch name {
+	case "temperature", "temp":
+		p.Temperature, err = parseFloat(value)
+	case "top_p":
+		p.TopP, err = parseFloat(value)
+	case "top_k":
+		p.TopK, err = parseUint(value)
+	case "max_tokens":
+		p.MaxTokens, err = parseUint(value)
+	case "repetition_penalty", "repeat_penalty":
+		p.RepetitionPenalty, err = parseFloat(value)
+	case "seed":
+		p.Seed, err = parseUint(value)
+	case "stop":
+		// each stop=... adds a sequence; a bare stop= clears them
+		if value == "" {
+			p.Stop = nil
+		} else {
+			p.Stop = append(p.Stop, value)
+		}
+	default:
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func parseFloat(s string) (*float32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Float32(float32(f)), nil
+}
+
+func parseUint(s string) (*uint32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Uint32(uint32(n)), nil
+}
+
+// formatSampling lists the fields set in p
+func formatSampling(p *chatpb.SamplingParams) string {
+	var parts []string
+	if p.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", p.GetTemperature()))
+	}
+	if p.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p=%g", p.GetTopP()))
+	}
+	if p.TopK != nil {
+		parts = append(parts, fmt.Sprintf("top_k=%d", p.GetTopK()))
+	}
+	if p.MaxTokens != nil {
+		parts = append(parts, fmt.Sprintf("max_tokens=%d", p.GetMaxTokens()))
+	}
+	if p.RepetitionPenalty != nil {
+		parts = append(parts, fmt.Sprintf("repetition_penalty=%g", p.GetRepetitionPenalty()))
+	}
+	if p.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", p.GetSeed()))
+	}
+	for _, s := range p.GetStop() {
+		parts = append(parts, fmt.Sprintf("stop=%q", s))
+	}
+	if len(parts) == 0 {
+		return "server defaults"
+	}
+	return strings.Join(parts, " ")
+}
@@ -2,8 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	metrics "github.com/Billy-Davies-2/llm-test/pkg/proto/metrics"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -25,31 +28,52 @@ func (m model) viewSystem() string {
 	var extra []string
 	boxes := make([]string, len(m.servers))
 	for i, srv := range m.servers {
+		name := srv.URL
+		if srv.Node != nil {
+			name = srv.Node.GetHostId()
+		}
 		body := ""
 		if srv.Err != nil {
 			body = "ERROR"
+		} else if d := srv.Data; d == nil {
+			body = "…"
 		} else {
-			d := srv.Data
 			body = fmt.Sprintf("CPU: %.1f%%\nRAM: %.1f/%.1f MB",
-				d.CpuUsagePercent,
-				d.MemoryUsedMb, d.MemoryTotalMb,
+				d.GetCpuUsagePercent(),
+				d.GetMemoryUsedMb(), d.GetMemoryTotalMb(),
+			)
+			if len(srv.Trend) > 1 {
+				body += "\nCPU " + sparkline(srv.Trend)
+			}
+			body += fmt.Sprintf("\nQueue: %d (%d/%d busy)",
+				d.GetQueueDepth(),
+				d.GetActiveGenerations(), d.GetMaxConcurrentGenerations(),
 			)
-			if gpu := d.Gpu; gpu.Name != "" {
-				body += fmt.Sprintf("\nGPU: %s (%.0f°C)", gpu.Name, gpu.TemperatureCelsius)
+			if gpu := d.GetGpu(); gpu.GetName() != "" {
+				body += fmt.Sprintf("\nGPU: %s (%.0f°C)", gpu.GetName(), gpu.GetTemperatureCelsius())
 			} else {
 				body += "\nGPU: n/a"
 			}
+			if diag := hostDiagnostics(d); len(diag) > 0 {
+				body += "\n" + strings.Join(diag, "\n")
+			}
+			if perf := modelPerformance(d); len(perf) > 0 {
+				body += "\n" + strings.Join(perf, "\n")
+			}
 		}
-		box := renderNode(srv.URL, body, srv.Err != nil)
+		if n := srv.Node; n != nil {
+			body += "\n" + nodeSummary(n)
+		}
+		box := renderNode(name, body, srv.Err != nil)
 		if i < 4 {
 			boxes[i] = box
 		} else {
 			// extra after the 4 spokes
-			extra = append(extra, fmt.Sprintf("%s  %s", srv.URL, func() string {
+			extra = append(extra, fmt.Sprintf("%s  %s", name, func() string {
 				if srv.Err != nil {
 					return "[ERROR]"
 				}
-				return fmt.Sprintf("CPU %.1f%%, RAM %.1fMB", srv.Data.CpuUsagePercent, srv.Data.MemoryUsedMb)
+				return fmt.Sprintf("CPU %.1f%%, RAM %.1fMB", srv.Data.GetCpuUsagePercent(), srv.Data.GetMemoryUsedMb())
 			}()))
 		}
 	}
@@ -123,3 +147,110 @@ func (m model) viewSystem() string {
 
 	return graph + extras + "\n\n" + footer
 }
+
+// nodeSummary describes what a gossiped node advertises about itself
+func nodeSummary(n *metrics.NodeInfo) string {
+	models := "none"
+	if len(n.GetModels()) > 0 {
+		models = strings.Join(n.GetModels(), ", ")
+	}
+	lines := []string{"Models: " + models}
+	if hw := n.GetHardware(); hw != nil {
+		lines = append(lines, fmt.Sprintf("%d cores, %.0f MB", hw.GetCpuCores(), hw.GetMemoryTotalMb()))
+	}
+	if n.GetState() == metrics.NodeState_NODE_STATE_SUSPECT {
+		lines = append(lines, "suspect")
+	}
+	if v := n.GetVersion(); v != "" {
+		lines = append(lines, "Version: "+v)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hostDiagnostics summarises what a host reports beyond CPU and memory,
+// for spotting why it is slow
+func hostDiagnostics(d *metrics.MetricsResponse) []string {
+	var lines []string
+	if cores := d.GetCpuCorePercent(); len(cores) > 0 {
+		lines = append(lines, fmt.Sprintf("Busiest core: %.1f%% of %d", slices.Max(cores), len(cores)))
+	}
+	if l := d.GetLoadAverage(); l != nil {
+		lines = append(lines, fmt.Sprintf("Load: %.2f %.2f %.2f", l.GetLoad1(), l.GetLoad5(), l.GetLoad15()))
+	}
+	if d.GetSwapTotalMb() > 0 {
+		lines = append(lines, fmt.Sprintf("Swap: %.1f/%.1f MB", d.GetSwapUsedMb(), d.GetSwapTotalMb()))
+	}
+	for _, disk := range d.GetDisks() {
+		lines = append(lines, fmt.Sprintf("Disk %s: %.0f%% of %.1f GB",
+			disk.GetMountpoint(), 100*disk.GetUsedMb()/disk.GetTotalMb(), disk.GetTotalMb()/1024))
+	}
+	if nets := d.GetNetworks(); len(nets) > 0 {
+		var rx, tx float64
+		for _, n := range nets {
+			rx += n.GetRxBytesPerSec()
+			tx += n.GetTxBytesPerSec()
+		}
+		lines = append(lines, fmt.Sprintf("Net: ↓%s ↑%s", byteRate(rx), byteRate(tx)))
+	}
+	if up := d.GetUptimeSeconds(); up > 0 {
+		lines = append(lines, "Up: "+uptime(time.Duration(up)*time.Second))
+	}
+	return lines
+}
+
+// modelPerformance gives a line per model the host has served: its
+// throughput, mean time to first token and failures
+func modelPerformance(d *metrics.MetricsResponse) []string {
+	var lines []string
+	for _, ms := range d.GetModelStats() {
+		name := ms.GetModel()
+		if name == "" {
+			name = "(unknown)"
+		}
+		line := fmt.Sprintf("%s: %.1f tok/s", name, ms.GetTokensPerSecond())
+		if ttft := ms.GetTimeToFirstToken(); ttft.GetCount() > 0 {
+			line += fmt.Sprintf(", TTFT %.2fs", ttft.GetSumSeconds()/float64(ttft.GetCount()))
+		}
+		if n := ms.GetErrorsTotal(); n > 0 {
+			line += fmt.Sprintf(", %d/%d failed", n, ms.GetRequestsTotal())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// byteRate formats bytes per second in the largest unit under 1024
+func byteRate(b float64) string {
+	units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+	i := 0
+	for ; b >= 1024 && i < len(units)-1; i++ {
+		b /= 1024
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
+// uptime formats d to the minute, in days once it runs past one
+func uptime(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hm := strings.TrimSuffix(d.Truncate(time.Minute).String(), "0s")
+	if hm == "" {
+		hm = "0m"
+	}
+	if days > 0 {
+		return fmt.Sprintf("%dd%s", days, hm)
+	}
+	return hm
+}
+
+// sparkline draws percentages as a row of block characters
+func sparkline(pcts []float64) string {
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	var b strings.Builder
+	for _, p := range pcts {
+		i := int(p / 100 * float64(len(levels)))
+		b.WriteRune(levels[max(min(i, len(levels)-1), 0)])
+	}
+	return b.String()
+}